@@ -4,8 +4,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"seismo/collector"
 	"seismo/collector/db"
 	"seismo/provider"
@@ -17,8 +21,18 @@ func main() {
 	log.Println("main: starting")
 
 	confFileName := flag.String("confFile", "", "config file full name")
+	listProviders := flag.Bool("providers", false, "list registered provider types and exit")
+	backfillId := flag.String("backfill", "", "identifier of a watcher to backfill while watching")
+	backfillFrom := flag.String("from", "", "start of the backfilled range (\"2006-01-02\" or RFC 3339)")
+	backfillTo := flag.String("to", "", "end (exclusive) of the backfilled range, now by default")
+	migrate := flag.Bool("migrate", false, "migrate the database (remove duplicate events) and exit")
 	flag.Parse()
 
+	if *listProviders {
+		printProviders(os.Stdout)
+		return
+	}
+
 	var err error
 	if *confFileName == "" {
 		log.Println("Config file name is not specified, trying to get it from an environment variable...")
@@ -40,12 +54,36 @@ func main() {
 		return
 	}
 
+	if *migrate {
+		n, err := db.Migrate(ctx, conf.Db)
+		log.Printf("main: migration: %d duplicate documents removed\n", n)
+		if err != nil {
+			log.Printf("main: cannot migrate database: %v\n", err)
+		}
+		return
+	}
+
 	watchers, err := collector.CreateWatchers(conf)
 	if err != nil {
 		log.Printf("main: cannot create watchers %v\n", err)
 		return
 	}
 
+	var bw provider.Watcher
+	var from, to time.Time
+	if *backfillId != "" {
+		var ok bool
+		if bw, ok = watchers[*backfillId]; !ok {
+			log.Printf("main: cannot backfill: unknown watcher %q\n", *backfillId)
+			return
+		}
+
+		if from, to, err = parseRange(*backfillFrom, *backfillTo); err != nil {
+			log.Printf("main: cannot backfill: %v\n", err)
+			return
+		}
+	}
+
 	dbAdapter, err := db.NewAdapter(conf.Db)
 	if err != nil {
 		log.Printf("main: cannot create database adaper %v\n", err)
@@ -64,31 +102,66 @@ func main() {
 	msgChan := collector.MergeWatchPipes(watchPipes)
 
 	//maintaining watchers (start and restart)
-	go func() {
-		t := time.NewTicker(time.Duration(conf.MaintainPeriod) * time.Second)
-		for {
-			select {
-			case <-t.C:
-				collector.RestartWatchers(ctx, watchers, dbAdapter, watchPipes)
-			case <-ctx.Done():
+	go collector.MaintainWatchers(ctx, watchers, dbAdapter, watchPipes, time.Duration(conf.MaintainPeriod)*time.Second)
+
+	//backfilling the watcher while it is watching
+	if bw != nil {
+		go func() {
+			log.Printf("main: backfilling %q from %v to %v\n", *backfillId, from, to)
+			if err := collector.Backfill(ctx, bw, from, to, dbAdapter); err != nil {
+				log.Printf("main: %v\n", err)
 				return
 			}
+			log.Printf("main: backfilling %q is complete\n", *backfillId)
+		}()
+	}
+
+	//main loop: getting messages from the merged channel,
+	//validating and saving in database
+	collector.SaveMessages(ctx, msgChan, dbAdapter, collector.WatcherRules(watchers))
+}
+
+// printProviders writes the registered provider types and their
+// configuration schemas into "w".
+func printProviders(w io.Writer) {
+	for _, r := range provider.Registered() {
+		fmt.Fprintf(w, "%s\n\t%s\n\tconn_str: %s\n", r.T, r.Schema.Description, r.Schema.ConnStr)
+		if r.Schema.DefConnStr != "" {
+			fmt.Fprintf(w, "\tdefault conn_str: %s\n", r.Schema.DefConnStr)
 		}
-	}()
-
-	//main loop: getting messages from the merged channel
-	//and saving in database
-	for {
-		select {
-		case m := <-msgChan:
-			err = dbAdapter.SaveMsg(ctx, []provider.Message{m})
-			if err != nil {
-				log.Printf("main: cannot save message in database: error: %v\n", err)
-				return
+		if r.Schema.Options != nil {
+			if b, err := json.Marshal(r.Schema.Options); err == nil {
+				fmt.Fprintf(w, "\toptions: %s\n", b)
 			}
-		case <-ctx.Done():
-			log.Print("main: ended with context")
-			return
 		}
 	}
 }
+
+// parseRange parses the bounds of a backfilled range and returns them and an error.
+// A bound is a date like "2006-01-02" or an RFC 3339 time. The empty "to" bound means now.
+func parseRange(from string, to string) (time.Time, time.Time, error) {
+	parse := func(s string) (time.Time, error) {
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.RFC3339, s)
+	}
+
+	f, err := parse(from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseRange: from: %w", err)
+	}
+
+	t := time.Now().UTC()
+	if to != "" {
+		if t, err = parse(to); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parseRange: to: %w", err)
+		}
+	}
+
+	if err := provider.CheckRange(f, t); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseRange: %w", err)
+	}
+
+	return f, t, nil
+}
@@ -22,6 +22,8 @@ const (
 	listPageMode   = "lp"
 	msgPageMode    = "mp"
 	parseFilesMode = "pf"
+	archiveMode    = "ar"
+	extractMode    = "ex"
 	//Max input file size in bytes
 	maxInputSize = 1024 * 10 //10 KB
 )
@@ -36,7 +38,8 @@ func main() {
 
 	baseAddrFlag := flag.String("baseAddr", "", "base address (url)")
 
-	modeFlagUsage := fmt.Sprintf("%s - get month pages containting list message names, %s - get message pages", listPageMode, msgPageMode)
+	modeFlagUsage := fmt.Sprintf("%s - get month pages containting list message names, %s - get message pages, %s - parse message files, %s - get messages from monthly archives, %s - extract messages showing progress",
+		listPageMode, msgPageMode, parseFilesMode, archiveMode, extractMode)
 	modeFlag := flag.String("mode", listPageMode, modeFlagUsage)
 
 	outFlag := flag.String("out", "", "output folder")
@@ -92,6 +95,16 @@ func main() {
 		if err != nil {
 			fmt.Printf("Parse files error: %v.\n", err)
 		}
+	case archiveMode:
+		err := getArchiveMsgs(*fromFlag, *toFlag, *baseAddrFlag, *outFlag)
+		if err != nil {
+			fmt.Printf("Getting archive messages error: %v.\n", err)
+		}
+	case extractMode:
+		err := extractMsgs(*fromFlag, *toFlag, *baseAddrFlag, *outFlag)
+		if err != nil {
+			fmt.Printf("Extracting messages error: %v.\n", err)
+		}
 	default:
 		fmt.Println("A mode specified incorrectly.")
 		return
@@ -174,6 +187,72 @@ func getMsgPages(from, to provider.MonthYear, baseAddr, saveDir string) error {
 	return nil
 }
 
+// getArchiveMsgs gets messages from the monthly archives and saves
+// the messages of every month into a json file like "2022-April.json".
+func getArchiveMsgs(from, to provider.MonthYear, baseAddr, saveDir string) error {
+	for m := from; !m.After(to); m = m.AddMonth(1) {
+		url, err := url.JoinPath(baseAddr, seishub.ArchiveName(m))
+		if err != nil {
+			return err
+		}
+
+		msgs, err := seishub.GetArchiveMsgs(context.Background(), url, nil)
+		if err != nil {
+			return err
+		}
+
+		js, err := json.MarshalIndent(msgs, "", " ")
+		if err != nil {
+			return err
+		}
+
+		err = saveFile(path.Join(saveDir, seishub.MonthYearPathSeg(m.Month, m.Year)+".json"), string(js))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractMsgs extracts messages with seishub.Hub.ExtractStream printing the progress
+// and the failed links, and saves the messages in the order of their numbers
+// into the "messages.json" file.
+func extractMsgs(from, to provider.MonthYear, baseAddr, saveDir string) error {
+	h, err := seishub.NewHub(provider.WatcherConfig{Id: "seishub-util", ConnStr: baseAddr,
+		Timeout: provider.DefTimeout, CheckPeriod: provider.DefCheckPeriod})
+	if err != nil {
+		return err
+	}
+
+	progress := func(p seishub.ExtractProgress) {
+		fmt.Printf("\rMonths: %d/%d, links: %d/%d", p.MonthsScanned, p.Months, p.LinksDone, p.Links)
+	}
+
+	ch, err := h.ExtractStream(context.Background(), from, to, seishub.ExtractOptions{Ordered: true, Progress: progress})
+	if err != nil {
+		return err
+	}
+
+	msgs := make([]*provider.Message, 0)
+	failed := 0
+	for r := range ch {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("\nFailed %q: %v\n", r.Link, r.Err)
+			continue
+		}
+		msgs = append(msgs, r.Msg)
+	}
+	fmt.Printf("\nExtracted: %d, failed: %d.\n", len(msgs), failed)
+
+	js, err := json.MarshalIndent(msgs, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return saveFile(path.Join(saveDir, "messages.json"), string(js))
+}
+
 func getListPages(from, to provider.MonthYear, baseAddr, saveDir string) error {
 	for my := from.Date(); !my.After(to.Date()); my = my.AddDate(0, 1, 0) {
 		sg := seishub.MonthYearPathSeg(my.Month(), my.Year())
@@ -11,6 +11,7 @@ import (
 	"seismo/collector/db"
 	"seismo/provider"
 	"seismo/provider/crt"
+	"sync"
 	"time"
 )
 
@@ -20,13 +21,17 @@ import (
 // The first return value is a map, keys of which are identifiers of watchers (i.e.
 // their event sources), and values are watchers themselves. The second returned
 // value is an error. If the returned error is not nil, the returned map value is nil.
+//
+// The error of an invalid watcher configuration names the watcher (its key in the config).
+// In particular, it wraps provider.UnknownOptionErr if the options block of the watcher
+// contains a field unknown to the provider.
 func CreateWatchers(conf Config) (map[string]provider.Watcher, error) {
 	watchers := make(map[string]provider.Watcher, len(conf.Watchers))
 
-	for _, c := range conf.Watchers {
+	for key, c := range conf.Watchers {
 		w, err := crt.NewWatcher(c)
 		if err != nil {
-			return nil, fmt.Errorf("createWatchers: %w", err)
+			return nil, fmt.Errorf("createWatchers: watcher %q: %w", key, err)
 		}
 
 		id := w.GetConfig().Id
@@ -41,7 +46,7 @@ func CreateWatchers(conf Config) (map[string]provider.Watcher, error) {
 }
 
 // RestartWatchers permanently checks a current state of every watcher in a passed
-// "watchers" map. If a watcher is stopped, the function tries to start it from the
+// "watchers" map. If a watcher is stopped (or failed), the function tries to start it from the
 // focus time of the last message saved in the database represented by "dbAdapter".
 // If starting th watcher is successful, the function put the returned message channel
 // into the "watchPipes" channel (channel of channels).
@@ -50,7 +55,7 @@ func RestartWatchers(ctx context.Context, watchers map[string]provider.Watcher,
 
 	for id, w := range watchers {
 
-		if w.StateInfo() != provider.Stopped {
+		if w.StateInfo().Active() {
 			continue
 		}
 
@@ -77,6 +82,97 @@ func RestartWatchers(ctx context.Context, watchers map[string]provider.Watcher,
 	}
 }
 
+// lifecycleBuf defines the buffer size of subscriptions to lifecycle events of watchers.
+const lifecycleBuf = 16
+
+// MaintainWatchers starts and restarts watchers of the "watchers" map by RestartWatchers
+// until the context is canceled. Watchers are started at once and checked every "period". Besides, a watcher
+// publishing its lifecycle events (see provider.Observable) is restarted immediately after
+// it has stopped, but not more often than once a "period", so a watcher failing
+// on start is not restarted in a loop. Lifecycle events are logged.
+func MaintainWatchers(ctx context.Context, watchers map[string]provider.Watcher,
+	dbAdapter db.Adapter, watchPipes chan<- (<-chan provider.Message), period time.Duration) {
+
+	events := SubscribeWatchers(ctx, watchers, lifecycleBuf)
+	RestartWatchers(ctx, watchers, dbAdapter, watchPipes)
+
+	t := time.NewTicker(period)
+	defer t.Stop()
+
+	restarted := make(map[string]time.Time, len(watchers))
+	for {
+		select {
+		case <-t.C:
+			RestartWatchers(ctx, watchers, dbAdapter, watchPipes)
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+
+			log.Printf("MaintainWatchers: watcher %q: %s, state: %s, error: %v", e.WatcherId, e.Kind, e.State, e.Err)
+			if e.Kind != provider.LifecycleStopped || ctx.Err() != nil {
+				continue
+			}
+
+			//the ticker restarts the watcher, if it has been restarted recently
+			if time.Since(restarted[e.WatcherId]) < period {
+				continue
+			}
+			restarted[e.WatcherId] = time.Now()
+
+			if w, ok := watchers[e.WatcherId]; ok {
+				RestartWatchers(ctx, map[string]provider.Watcher{e.WatcherId: w}, dbAdapter, watchPipes)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SubscribeWatchers subscribes to lifecycle events of every watcher of the "watchers" map
+// implementing provider.Observable and returns a channel merging the events. Every subscription
+// has a buffer of "buf" events. When the context is canceled, the subscriptions are cancelled
+// and the returned channel is closed.
+func SubscribeWatchers(ctx context.Context, watchers map[string]provider.Watcher, buf int) <-chan provider.LifecycleEvent {
+	out := make(chan provider.LifecycleEvent)
+
+	var wg sync.WaitGroup
+	for _, w := range watchers {
+		ob, ok := w.(provider.Observable)
+		if !ok {
+			continue
+		}
+
+		events, cancel := ob.Subscribe(buf)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+
+			for {
+				select {
+				case e := <-events:
+					select {
+					case out <- e:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
 // MergeWatchPipes provides permanent merging message channels coming from the "watchPipes" channel
 // into a common message channel, returned by the function.
 //
@@ -99,3 +195,122 @@ func MergeWatchPipes(watchPipes <-chan (<-chan provider.Message)) <-chan provide
 
 	return outPipe
 }
+
+// Backfill gets messages of the "w" watcher for the [from, to) range
+// (see provider.Backfiller) and saves them into the database represented by "dbAdapter"
+// like SaveMessages does with the rules of the watcher. The function returns
+// when all messages have been saved or the context is canceled.
+//
+// The watcher can keep watching while it is backfilled, messages about the same events
+// are deduplicated by the database (see db.Adapter.SaveMsg).
+//
+// The function returns an error if the watcher does not implement provider.Backfiller
+// or backfilling cannot be started.
+func Backfill(ctx context.Context, w provider.Watcher, from time.Time, to time.Time, dbAdapter db.Adapter) error {
+	conf := w.GetConfig()
+
+	b, ok := w.(provider.Backfiller)
+	if !ok {
+		return fmt.Errorf("Backfill: watcher %q of type %q does not support backfilling", conf.Id, conf.T)
+	}
+
+	msgs, err := b.Backfill(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("Backfill: watcher %q: %w", conf.Id, err)
+	}
+
+	SaveMessages(ctx, msgs, dbAdapter, map[string]provider.RuleSet{conf.Id: provider.RulesFor(conf.T)})
+
+	return nil
+}
+
+// WatcherRules returns the validation rules of messages of every watcher
+// of the "watchers" map (see provider.RulesFor). The keys of the returned map
+// are identifiers of watchers.
+func WatcherRules(watchers map[string]provider.Watcher) map[string]provider.RuleSet {
+	rules := make(map[string]provider.RuleSet, len(watchers))
+	for id, w := range watchers {
+		rules[id] = provider.RulesFor(w.GetConfig().T)
+	}
+
+	return rules
+}
+
+const (
+	//minSaveDelay and maxSaveDelay define the bounds of the delay
+	//before retrying a failed saving into the database
+	minSaveDelay = 100 * time.Millisecond
+	maxSaveDelay = time.Minute
+)
+
+// SaveMessages saves messages coming from the "msgs" channel into the database
+// represented by "dbAdapter" until the context is canceled or the channel is closed.
+//
+// Every message is validated with the rules of its source taken from the "rules" map
+// by the SourceId of the message, messages of unknown sources are validated with
+// the common rules (provider.DefRules). An invalid message is saved into the rejection
+// store of the database with the reason instead of being saved with valid messages.
+//
+// A failed saving is logged and retried (see retrySave) until it succeeds or the context
+// is canceled, so a temporary failure of the database neither stops the Collector nor
+// loses messages: while the database is unavailable, watchers are not read.
+func SaveMessages(ctx context.Context, msgs <-chan provider.Message, dbAdapter db.Adapter,
+	rules map[string]provider.RuleSet) {
+
+	for {
+		select {
+		case m, ok := <-msgs:
+			if !ok {
+				log.Print("SaveMessages: the message channel has been closed")
+				return
+			}
+
+			rs, ok := rules[m.SourceId]
+			if !ok {
+				rs = provider.DefRules
+			}
+
+			if verr := rs.Validate(m); verr != nil {
+				log.Printf("SaveMessages: rejected: %v\n", verr)
+				if !retrySave(ctx, "rejected message", func() error { return dbAdapter.SaveRejected(ctx, m, verr) }) {
+					log.Print("SaveMessages: ended with context")
+					return
+				}
+				continue
+			}
+
+			if !retrySave(ctx, "message", func() error { return dbAdapter.SaveMsg(ctx, []provider.Message{m}) }) {
+				log.Print("SaveMessages: ended with context")
+				return
+			}
+		case <-ctx.Done():
+			log.Print("SaveMessages: ended with context")
+			return
+		}
+	}
+}
+
+// retrySave calls "save" until it succeeds or the context is canceled. A failed call
+// is logged and retried after a delay growing from minSaveDelay up to maxSaveDelay.
+// The function reports whether "save" has succeeded. The "what" parameter names
+// the saved item in the log.
+func retrySave(ctx context.Context, what string, save func() error) bool {
+	delay := minSaveDelay
+	for {
+		err := save()
+		if err == nil {
+			return true
+		}
+		log.Printf("SaveMessages: cannot save %s in database, retrying in %v: error: %v\n", what, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return false
+		}
+
+		if delay *= 2; delay > maxSaveDelay {
+			delay = maxSaveDelay
+		}
+	}
+}
@@ -2,8 +2,12 @@ package collector
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"seismo/collector/db"
 	"seismo/provider"
+	"seismo/provider/crt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -34,3 +38,158 @@ func Test_RestartWatchers(t *testing.T) {
 		t.Errorf("Restart watcher: want len watch pipes: 2; res len: %d", l)
 	}
 }
+
+func Test_SaveMessages_Validation(t *testing.T) {
+	valid := provider.Message{
+		SourceId:  "seishub",
+		FocusTime: time.Date(2022, 2, 1, 5, 55, 14, 445000000, time.UTC),
+		Latitude:  54.38,
+		Longitude: 86.13,
+		Magnitude: 2.4,
+		EventId:   "asb2022cfjhkl",
+		Link:      "http://seishub.ru/pipermail/seismic-report/2022-February/017538.html",
+	}
+
+	noLink := valid
+	noLink.Link = ""
+
+	badLat := valid
+	badLat.SourceId = "unknown"
+	badLat.Latitude = 91
+
+	rules := map[string]provider.RuleSet{"seishub": provider.RulesFor(provider.Seishub)}
+
+	msgs := make(chan provider.Message, 3)
+	msgs <- valid
+	msgs <- noLink
+	msgs <- badLat
+	close(msgs)
+
+	dbAdapter := &memAdapter{}
+	SaveMessages(context.Background(), msgs, dbAdapter, rules)
+
+	if len(dbAdapter.saved) != 1 || !dbAdapter.saved[0].Equal(valid) {
+		t.Errorf("Test_SaveMessages_Validation: unexpected saved messages: %v", dbAdapter.saved)
+	}
+
+	if len(dbAdapter.invalid) != 2 {
+		t.Fatalf("Test_SaveMessages_Validation: want 2 rejected messages, result: %d", len(dbAdapter.invalid))
+	}
+
+	for i, want := range []string{"link", "latitude"} {
+		var ve provider.ValidationErr
+		if !errors.As(dbAdapter.invalid[i], &ve) || len(ve.Fields) != 1 || ve.Fields[0].Field != want {
+			t.Errorf("Test_SaveMessages_Validation: want violated field %q, result: %v", want, dbAdapter.invalid[i])
+		}
+	}
+}
+
+func Test_CreateWatchers_Options(t *testing.T) {
+	conf, err := ConfigFromFile("testdata/options_conf.json")
+	if err != nil {
+		t.Fatalf("Test_CreateWatchers_Options: %v", err)
+	}
+
+	_, err = CreateWatchers(conf)
+	var ue provider.UnknownOptionErr
+	if !errors.As(err, &ue) || ue.Field != "region" {
+		t.Fatalf("Test_CreateWatchers_Options: want UnknownOptionErr of the \"region\" field, result: %v", err)
+	}
+	if !strings.Contains(err.Error(), `"usgs_1"`) {
+		t.Errorf("Test_CreateWatchers_Options: the error does not name the watcher: %v", err)
+	}
+
+	delete(conf.Watchers, "usgs_1")
+	watchers, err := CreateWatchers(conf)
+	if err != nil || len(watchers) != 1 {
+		t.Errorf("Test_CreateWatchers_Options: unexpected result: %v, %v", watchers, err)
+	}
+}
+
+// watcherOnly implements provider.Watcher but not provider.Backfiller.
+type watcherOnly struct {
+	provider.Watcher
+}
+
+func Test_Backfill(t *testing.T) {
+	wc := provider.DefaultWatcherConfig()
+	w, err := crt.NewWatcher(wc)
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	//the watcher keeps watching while it is backfilled
+	live, err := w.StartWatch(ctx, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+	go func() {
+		for range live {
+		}
+	}()
+
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Minute)
+	dbAdapter := &memAdapter{}
+	if err := Backfill(ctx, w, from, to, dbAdapter); err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	saved, _, invalid := dbAdapter.counts()
+	if saved == 0 || invalid != 0 {
+		t.Errorf("Test_Backfill: want saved messages only, saved: %d, invalid: %d", saved, invalid)
+	}
+	for _, m := range dbAdapter.saved {
+		if m.SourceId != wc.Id || !provider.InRange(m.FocusTime, from, to) {
+			t.Errorf("Test_Backfill: unexpected message: %v", m)
+		}
+	}
+
+	if s := w.StateInfo(); s != provider.Run {
+		t.Errorf("Test_Backfill: want state: %s, res: %s", provider.Run, s)
+	}
+
+	if err := Backfill(ctx, watcherOnly{w}, from, to, dbAdapter); err == nil {
+		t.Errorf("Test_Backfill: a watcher without backfilling is accepted")
+	}
+}
+
+func Test_MaintainWatchers(t *testing.T) {
+	//the watcher stops after generating the first message
+	wc := provider.DefaultWatcherConfig()
+	wc.Options = json.RawMessage(`{"faults": {"close": 1}}`)
+	conf := Config{Watchers: map[string]provider.WatcherConfig{wc.Id: wc}}
+	watchers, err := CreateWatchers(conf)
+	if err != nil {
+		t.Fatalf("Test_MaintainWatchers: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//the period is too long to restart the watcher by the ticker
+	watchPipes := make(chan (<-chan provider.Message), 10)
+	go MaintainWatchers(ctx, watchers, &memAdapter{}, watchPipes, time.Minute)
+
+	//the watcher is started and restarted once immediately after stopping,
+	//the next restart is postponed till the ticker
+	timeout := time.After(3 * time.Second)
+	starts := 0
+	for starts < 3 {
+		select {
+		case p := <-watchPipes:
+			for range p {
+			}
+			starts++
+		case <-timeout:
+			if starts != 2 {
+				t.Errorf("Test_MaintainWatchers: want 2 starts, result: %d", starts)
+			}
+			return
+		}
+	}
+	t.Errorf("Test_MaintainWatchers: the watcher is restarted in a loop")
+}
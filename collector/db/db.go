@@ -45,7 +45,13 @@ type Adapter interface {
 	//Close closes the opened connection.
 	Close(ctx context.Context) error
 	//SaveMsg saves messages in the connected database.
+	//A message about an already saved event (the same SourceId and EventId)
+	//replaces the saved one if it supersedes it (see provider.Message.Supersedes),
+	//retractions included, instead of being stored as an unrelated record.
 	SaveMsg(ctx context.Context, msgs []provider.Message) error
+	//SaveRejected saves a message rejected by validation with the reason of rejection
+	//separately from valid messages.
+	SaveRejected(ctx context.Context, msg provider.Message, reason error) error
 	//GetLastTime returns the focus time of the last saved message for specified "sourceId".
 	GetLastTime(ctx context.Context, sorceId string) (time.Time, error)
 }
@@ -61,3 +67,22 @@ func NewAdapter(conf DbConfig) (Adapter, error) {
 		return nil, fmt.Errorf("NewAdapter: unknown data base type: %q", conf.T)
 	}
 }
+
+// Migrate migrates the database specified in "conf" to the structures of the current version,
+// e.g. removes duplicate documents of events before the unique index of events is created,
+// and returns the number of removed documents and an error. It is run once, explicitly,
+// while the Collector is stopped.
+func Migrate(ctx context.Context, conf DbConfig) (int, error) {
+	switch conf.T {
+	case StubDb:
+		return 0, nil
+	case MongoDb:
+		n, err := mongodb.Migrate(ctx, conf.ConnStr)
+		if err != nil {
+			return n, fmt.Errorf("Migrate: %w", err)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("Migrate: unknown data base type: %q", conf.T)
+	}
+}
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path"
 	"seismo/provider"
@@ -14,9 +15,31 @@ import (
 )
 
 const (
-	msgCollName = "messages"
+	msgCollName      = "messages"
+	rejectedCollName = "rejected"
+
+	//eventIndexName specifies the name of the unique index of saved events
+	eventIndexName = "source_id_event_id_unique"
+
+	//oldEventIndexName specifies the name of the former non-unique index of saved events
+	oldEventIndexName = "source_id_1_event_id_1"
 )
 
+// rejection represents a document of a message rejected by validation.
+type rejection struct {
+	Msg provider.Message `bson:"msg"`
+
+	// Reason specifies the text of the rejection error.
+	Reason string `bson:"reason"`
+
+	// Fields specifies the names of violated fields if the message
+	// was rejected by validation rules.
+	Fields []string `bson:"fields,omitempty"`
+
+	// Time specifies the UTC-time of the rejection.
+	Time time.Time `bson:"time"`
+}
+
 // Adapter provides interaction with a MONGODB database.
 type Adapter struct {
 	//connStr specifies a connection string.
@@ -38,9 +61,181 @@ func (a *Adapter) Connect(ctx context.Context, connStr string) error {
 	a.connStr = connStr
 	a.dbName = path.Base(connStr)
 	a.client = *c
+
+	coll := a.client.Database(a.dbName).Collection(msgCollName)
+	if err := ensureEventIndex(ctx, coll); err != nil {
+		return fmt.Errorf("Connect: error: %w", err)
+	}
+
 	return nil
 }
 
+// ensureEventIndex creates the unique index of saved events (source_id, event_id),
+// which serves lookups by updates and retractions and keeps one document per event.
+// Messages without an event identifier are not indexed.
+//
+// If the collection keeps duplicate documents of events or the former non-unique index
+// (saved before the index was unique), the index cannot be created, and the returned
+// error wraps MigrationNeededErr. Such a database is migrated by Migrate.
+func ensureEventIndex(ctx context.Context, coll *mongo.Collection) error {
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "source_id", Value: 1}, {Key: "event_id", Value: 1}},
+		Options: options.Index().SetName(eventIndexName).SetUnique(true).
+			SetPartialFilterExpression(bson.D{{Key: "event_id", Value: bson.D{{Key: "$gt", Value: ""}}}}),
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) || indexConflict(err) {
+			return fmt.Errorf("ensureEventIndex: %w", MigrationNeededErr{Err: err})
+		}
+		return fmt.Errorf("ensureEventIndex: %w", err)
+	}
+
+	return nil
+}
+
+// MigrationNeededErr indicates that the database keeps events saved before
+// the unique index of events was introduced, so it must be migrated by Migrate.
+type MigrationNeededErr struct {
+	Err error
+}
+
+func (e MigrationNeededErr) Error() string {
+	return fmt.Sprintf("duplicate events or the former event index prevent creating the unique event index, "+
+		"the database must be migrated (collector -migrate): %v", e.Err)
+}
+
+func (e MigrationNeededErr) Unwrap() error {
+	return e.Err
+}
+
+// indexConflict reports whether "err" is a command error of an existing index
+// with the same keys, but different options.
+func indexConflict(err error) bool {
+	var ce mongo.CommandError
+	return errors.As(err, &ce) && (ce.Name == "IndexOptionsConflict" || ce.Name == "IndexKeySpecsConflict")
+}
+
+// Migrate migrates the database specified by the connection string "connStr" to the unique
+// index of events: it removes duplicate documents of every saved event except the one
+// which supersedes the others (see latest), drops the former non-unique index and creates
+// the unique one. It returns the number of removed documents and an error.
+//
+// Migrate scans the whole collection of messages and removes documents irrevocably,
+// so it is run once, explicitly, while the Collector is stopped.
+func Migrate(ctx context.Context, connStr string) (int, error) {
+	c, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	if err != nil {
+		return 0, fmt.Errorf("Migrate: error: %w", err)
+	}
+	defer c.Disconnect(ctx)
+
+	coll := c.Database(path.Base(connStr)).Collection(msgCollName)
+	n, err := removeDuplicates(ctx, coll)
+	if err != nil {
+		return n, fmt.Errorf("Migrate: error: %w", err)
+	}
+
+	if _, err := coll.Indexes().DropOne(ctx, oldEventIndexName); err != nil && !notFound(err) {
+		return n, fmt.Errorf("Migrate: error: %w", err)
+	}
+
+	if err := ensureEventIndex(ctx, coll); err != nil {
+		return n, fmt.Errorf("Migrate: error: %w", err)
+	}
+
+	return n, nil
+}
+
+// notFound reports whether "err" is a command error of a missing index or collection.
+func notFound(err error) bool {
+	var ce mongo.CommandError
+	return errors.As(err, &ce) && (ce.Name == "IndexNotFound" || ce.Name == "NamespaceNotFound")
+}
+
+// removeDuplicates keeps one document of every saved event, the one which supersedes
+// the others (see latest), and removes the other documents of the event.
+// It returns the number of removed documents and an error.
+func removeDuplicates(ctx context.Context, coll *mongo.Collection) (int, error) {
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "event_id", Value: bson.D{{Key: "$gt", Value: ""}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "source_id", Value: "$source_id"}, {Key: "event_id", Value: "$event_id"}}},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "n", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("removeDuplicates: %w", err)
+	}
+
+	var groups []struct {
+		Ids []interface{} `bson:"ids"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return 0, fmt.Errorf("removeDuplicates: %w", err)
+	}
+
+	removed := 0
+	for _, g := range groups {
+		//object ids grow with the time of insertion, so documents are got in the order of saving
+		dc, err := coll.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: g.Ids}}}},
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return removed, fmt.Errorf("removeDuplicates: %w", err)
+		}
+
+		var docs []savedMsg
+		if err := dc.All(ctx, &docs); err != nil {
+			return removed, fmt.Errorf("removeDuplicates: %w", err)
+		}
+
+		msgs := make([]provider.Message, len(docs))
+		for i, d := range docs {
+			msgs[i] = d.Message
+		}
+		keep := latest(msgs)
+
+		drop := make([]interface{}, 0, len(docs)-1)
+		for i, d := range docs {
+			if i != keep {
+				drop = append(drop, d.Id)
+			}
+		}
+		res, err := coll.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: drop}}}})
+		if err != nil {
+			return removed, fmt.Errorf("removeDuplicates: %w", err)
+		}
+		removed += int(res.DeletedCount)
+	}
+
+	return removed, nil
+}
+
+// savedMsg represents a saved message document with its identifier.
+type savedMsg struct {
+	Id               interface{} `bson:"_id"`
+	provider.Message `bson:",inline"`
+}
+
+// latest returns the index of the message of "msgs" (about the same event, in the order
+// of receiving), which would be kept after saving them one by one, i.e. the message
+// superseding the others. It returns -1 if "msgs" is empty.
+func latest(msgs []provider.Message) int {
+	if len(msgs) == 0 {
+		return -1
+	}
+
+	k := 0
+	for i := 1; i < len(msgs); i++ {
+		if msgs[i].Supersedes(msgs[k]) {
+			k = i
+		}
+	}
+
+	return k
+}
+
 // Close closes the opened connection.
 func (a *Adapter) Close(ctx context.Context) error {
 	err := a.client.Disconnect(ctx)
@@ -51,12 +246,31 @@ func (a *Adapter) Close(ctx context.Context) error {
 }
 
 // SaveMsg saves messages in the connected database.
+//
+// A message with an event identifier replaces the saved message about
+// the same event (the same source_id and event_id) if it supersedes
+// the saved one (see provider.Message.Supersedes), otherwise it is dropped.
+// A retraction replaces the saved message as well, so the event is kept
+// as a tombstone with the "retract" action.
+// Messages without an event identifier are always inserted.
 func (a *Adapter) SaveMsg(ctx context.Context, msgs []provider.Message) error {
 	coll := a.client.Database(a.dbName).Collection(msgCollName)
 	mi := make([]interface{}, 0, len(msgs))
 	for _, m := range msgs {
-		mi = append(mi, m)
+		if m.EventId == "" {
+			mi = append(mi, m)
+			continue
+		}
+
+		if err := a.replaceMsg(ctx, coll, m); err != nil {
+			return fmt.Errorf("SaveMsg: error: %w", err)
+		}
 	}
+
+	if len(mi) == 0 {
+		return nil
+	}
+
 	_, err := coll.InsertMany(ctx, mi)
 	if err != nil {
 		return fmt.Errorf("SaveMsg: error: %w", err)
@@ -65,6 +279,95 @@ func (a *Adapter) SaveMsg(ctx context.Context, msgs []provider.Message) error {
 	return nil
 }
 
+// replaceMsg saves "m" in place of the saved message about the same event
+// if "m" supersedes it, or inserts "m" if the event is not saved yet.
+//
+// The check and the replacement are performed by a single conditional update
+// (see supersededFilter), so concurrent saving (e.g. backfilling while watching)
+// cannot replace a newer message with an older one. If the update matches nothing,
+// "m" is inserted, and the unique index rejects it if the event is saved
+// and is not superseded. The update is repeated once if the event has been inserted
+// concurrently after the update.
+func (a *Adapter) replaceMsg(ctx context.Context, coll *mongo.Collection, m provider.Message) error {
+	filter := append(bson.D{{Key: "source_id", Value: m.SourceId}, {Key: "event_id", Value: m.EventId}}, supersededFilter(m)...)
+
+	upd := m
+	if upd.Action == "" {
+		upd.Action = provider.Update
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		res, err := coll.ReplaceOne(ctx, filter, upd)
+		if err != nil {
+			return fmt.Errorf("replaceMsg: %w", err)
+		}
+		if res.MatchedCount > 0 {
+			return nil
+		}
+
+		_, err = coll.InsertOne(ctx, m)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("replaceMsg: %w", err)
+		}
+	}
+
+	//the saved message supersedes "m"
+	return nil
+}
+
+// supersededFilter returns the query conditions matching a saved message about the same event,
+// which "m" supersedes. The conditions follow provider.Message.Supersedes: versions are compared
+// if both messages have them, otherwise update times are compared if both messages have them,
+// otherwise "m" supersedes the saved message.
+func supersededFilter(m provider.Message) bson.D {
+	//update times are not compared or the saved one is not later
+	timeCond := bson.D{}
+	if !m.UpdateTime.IsZero() {
+		timeCond = bson.D{{Key: "update_time", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: m.UpdateTime}}}}}}
+	}
+
+	if m.Version == 0 {
+		return timeCond
+	}
+
+	return bson.D{{Key: "$or", Value: bson.A{
+		//the saved version is less
+		bson.D{{Key: "version", Value: bson.D{{Key: "$lt", Value: m.Version}, {Key: "$ne", Value: 0}}}},
+		//versions are not compared (absent or equal)
+		append(bson.D{{Key: "version", Value: bson.D{{Key: "$in", Value: bson.A{nil, 0, m.Version}}}}}, timeCond...),
+	}}}
+}
+
+// SaveRejected saves a message rejected by validation with the reason of rejection
+// into the separate collection ("rejected").
+func (a *Adapter) SaveRejected(ctx context.Context, msg provider.Message, reason error) error {
+	r := newRejection(msg, reason, time.Now().UTC())
+
+	coll := a.client.Database(a.dbName).Collection(rejectedCollName)
+	if _, err := coll.InsertOne(ctx, r); err != nil {
+		return fmt.Errorf("SaveRejected: error: %w", err)
+	}
+
+	return nil
+}
+
+func newRejection(msg provider.Message, reason error, t time.Time) rejection {
+	r := rejection{Msg: msg, Time: t}
+	if reason != nil {
+		r.Reason = reason.Error()
+	}
+
+	var ve provider.ValidationErr
+	if errors.As(reason, &ve) {
+		r.Fields = ve.FieldNames()
+	}
+
+	return r
+}
+
 // GetLastTime returns the focus time of the last saved message for a specified "sourceId" and error.
 // If there are no messages for the specified source, the method returns zero-value time.
 // If the returned error is not nil, the returned time value is the zero-value.
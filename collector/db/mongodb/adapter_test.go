@@ -0,0 +1,191 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"seismo/provider"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_Message_BSON(t *testing.T) {
+	full := provider.Message{
+		SourceId:  "fdsn_1",
+		FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC),
+		Latitude:  54.71,
+		Longitude: 83.67,
+		Magnitude: 3.3,
+		EventId:   "gfz2023eesfwx",
+		Type:      provider.QuarryBlast,
+		Quality:   provider.Good,
+
+		Depth:                provider.Float(10),
+		MagType:              "mb",
+		TimeUncertainty:      provider.Float(0.12),
+		LatitudeUncertainty:  provider.Float(0.017),
+		LongitudeUncertainty: provider.Float(0.021),
+		DepthUncertainty:     provider.Float(2.3),
+		StationCount:         provider.Int(23),
+		PhaseCount:           provider.Int(41),
+		AzimuthalGap:         provider.Float(74.5),
+		Author:               "scautoloc",
+		Agency:               "GFZ",
+		Extra:                map[string]string{"region": "Southwestern Siberia, Russia"},
+
+		Action:     provider.Update,
+		Version:    2,
+		UpdateTime: time.Date(2023, 3, 1, 5, 41, 12, 0, time.UTC),
+	}
+
+	for _, want := range []provider.Message{full, {SourceId: "pseudo_1", EventId: "1"}} {
+		b, err := bson.Marshal(want)
+		if err != nil {
+			t.Fatalf("Test_Message_BSON: %v", err)
+		}
+
+		var res provider.Message
+		if err := bson.Unmarshal(b, &res); err != nil {
+			t.Fatalf("Test_Message_BSON: %v", err)
+		}
+
+		//BSON keeps time with millisecond precision in UTC
+		res.FocusTime = res.FocusTime.UTC()
+		res.UpdateTime = res.UpdateTime.UTC()
+		if !res.Equal(want) {
+			t.Errorf("Test_Message_BSON: \n\twant: %v\n\tres: %v", want, res)
+		}
+	}
+}
+
+func Test_Message_BSON_Legacy(t *testing.T) {
+	//a document saved before the optional fields were introduced
+	doc := bson.D{
+		{Key: "source_id", Value: "seishub"},
+		{Key: "focus_time", Value: time.Date(2022, 2, 1, 5, 55, 14, 445000000, time.UTC)},
+		{Key: "latitude", Value: 54.38},
+		{Key: "longitude", Value: 86.13},
+		{Key: "magnitude", Value: 2.4},
+		{Key: "event_id", Value: "asb2022cfjhkl"},
+		{Key: "event_type", Value: 1},
+		{Key: "quality", Value: 1},
+		{Key: "link", Value: "http://seishub.ru/pipermail/seismic-report/2022-February/017538.html"},
+	}
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Test_Message_BSON_Legacy: %v", err)
+	}
+
+	var res provider.Message
+	if err := bson.Unmarshal(b, &res); err != nil {
+		t.Fatalf("Test_Message_BSON_Legacy: %v", err)
+	}
+
+	if res.EventId != "asb2022cfjhkl" || res.Depth != nil || res.StationCount != nil || res.Extra != nil {
+		t.Errorf("Test_Message_BSON_Legacy: unexpected result: %v", res)
+	}
+
+	//integer types and qualities are decoded
+	if res.Type != provider.EarthQuake || res.Quality != provider.Preliminary {
+		t.Errorf("Test_Message_BSON_Legacy: want: %q %q, result: %q %q",
+			provider.EarthQuake, provider.Preliminary, res.Type, res.Quality)
+	}
+
+	//optional fields are not stored when they are not specified
+	b, err = bson.Marshal(res)
+	if err != nil {
+		t.Fatalf("Test_Message_BSON_Legacy: %v", err)
+	}
+	for _, k := range []string{"depth", "mag_type", "station_count", "extra", "action", "version", "update_time"} {
+		if _, err := bson.Raw(b).LookupErr(k); err == nil {
+			t.Errorf("Test_Message_BSON_Legacy: %q key is stored", k)
+		}
+	}
+}
+
+func Test_newRejection(t *testing.T) {
+	m := provider.Message{SourceId: "pseudo_1", Latitude: 91}
+	now := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	r := newRejection(m, m.Validate(), now)
+	if r.Reason == "" || !r.Time.Equal(now) || !r.Msg.Equal(m) {
+		t.Errorf("Test_newRejection: unexpected rejection: %v", r)
+	}
+
+	want := []string{"event_id", "latitude", "focus_time"}
+	if len(r.Fields) != len(want) {
+		t.Fatalf("Test_newRejection: want fields: %v, result: %v", want, r.Fields)
+	}
+	for i := range want {
+		if r.Fields[i] != want[i] {
+			t.Errorf("Test_newRejection: want fields: %v, result: %v", want, r.Fields)
+		}
+	}
+
+	if _, err := bson.Marshal(r); err != nil {
+		t.Errorf("Test_newRejection: %v", err)
+	}
+}
+
+func Test_latest(t *testing.T) {
+	t0 := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		msgs []provider.Message
+		want int
+	}{
+		{"empty", nil, -1},
+		{"versions", []provider.Message{{Version: 2}, {Version: 3}, {Version: 1}}, 1},
+		{"update times", []provider.Message{{UpdateTime: t0.Add(time.Hour)}, {UpdateTime: t0}}, 0},
+		{"unordered", []provider.Message{{Version: 5}, {UpdateTime: t0}}, 1},
+	}
+
+	for _, test := range tests {
+		if res := latest(test.msgs); res != test.want {
+			t.Errorf("Test_latest: %s: want: %d res: %d", test.name, test.want, res)
+		}
+	}
+}
+
+func Test_supersededFilter(t *testing.T) {
+	t0 := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	timeCond := bson.D{{Key: "update_time", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: t0}}}}}}
+
+	tests := []struct {
+		name string
+		m    provider.Message
+		want bson.D
+	}{
+		{"unordered", provider.Message{}, bson.D{}},
+		{"update time", provider.Message{UpdateTime: t0}, timeCond},
+		{"version", provider.Message{Version: 3, UpdateTime: t0}, bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "version", Value: bson.D{{Key: "$lt", Value: 3}, {Key: "$ne", Value: 0}}}},
+			append(bson.D{{Key: "version", Value: bson.D{{Key: "$in", Value: bson.A{nil, 0, 3}}}}}, timeCond...),
+		}}}},
+	}
+
+	for _, test := range tests {
+		if res := supersededFilter(test.m); !reflect.DeepEqual(res, test.want) {
+			t.Errorf("Test_supersededFilter: %s: \n\twant: %v\n\tres: %v", test.name, test.want, res)
+		}
+	}
+}
+
+func Test_MigrationNeededErr(t *testing.T) {
+	cause := mongo.CommandError{Code: 85, Name: "IndexOptionsConflict"}
+	if !indexConflict(fmt.Errorf("CreateOne: %w", cause)) || indexConflict(mongo.CommandError{Name: "IndexNotFound"}) {
+		t.Errorf("Test_MigrationNeededErr: unexpected result of indexConflict")
+	}
+
+	err := fmt.Errorf("Connect: %w", MigrationNeededErr{Err: cause})
+	var me MigrationNeededErr
+	var ce mongo.CommandError
+	if !errors.As(err, &me) || !errors.As(err, &ce) || !strings.Contains(err.Error(), "collector -migrate") {
+		t.Errorf("Test_MigrationNeededErr: unexpected error: %v", err)
+	}
+}
@@ -16,6 +16,13 @@ func (a *Adapter) SaveMsg(ctx context.Context, msgs []provider.Message) error {
 	return nil
 }
 
+// SaveRejected writes a rejected message and the reason in stdout
+// and always returns nil as error.
+func (a *Adapter) SaveRejected(ctx context.Context, msg provider.Message, reason error) error {
+	fmt.Println("rejected:", msg, reason)
+	return nil
+}
+
 // GetLastTime always returns current UTC time as focus time in the last message
 // and nil as error.
 func (a *Adapter) GetLastTime(ctx context.Context, sorceId string) (time.Time, error) {
@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"seismo/provider"
+	"seismo/provider/pseudo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memAdapter is a db.Adapter keeping messages in memory. It counts saved messages
+// with malformed values, and its SaveMsg fails while "outage" is positive
+// (every failed call decrements it), like an unavailable database.
+type memAdapter struct {
+	mu        sync.Mutex
+	saved     []provider.Message
+	malformed int
+	outage    int
+
+	//invalid keeps reasons of messages rejected by validation
+	invalid []error
+}
+
+func (a *memAdapter) Connect(ctx context.Context, connStr string) error { return nil }
+
+func (a *memAdapter) Close(ctx context.Context) error { return nil }
+
+func (a *memAdapter) SaveMsg(ctx context.Context, msgs []provider.Message) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.outage > 0 {
+		a.outage--
+		return fmt.Errorf("SaveMsg: the database is unavailable")
+	}
+
+	for _, m := range msgs {
+		if math.IsNaN(m.Latitude) || math.IsNaN(m.Longitude) || math.Abs(m.Latitude) > 90 {
+			a.malformed++
+		}
+		a.saved = append(a.saved, m)
+	}
+
+	return nil
+}
+
+func (a *memAdapter) SaveRejected(ctx context.Context, msg provider.Message, reason error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.invalid = append(a.invalid, reason)
+	return nil
+}
+
+func (a *memAdapter) GetLastTime(ctx context.Context, sourceId string) (time.Time, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var t time.Time
+	for _, m := range a.saved {
+		if m.SourceId == sourceId && m.FocusTime.After(t) {
+			t = m.FocusTime
+		}
+	}
+
+	return t, nil
+}
+
+func (a *memAdapter) counts() (saved int, malformed int, invalid int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.saved), a.malformed, len(a.invalid)
+}
+
+// Test_Faults runs watchers injecting all kinds of faults and checks that
+// RestartWatchers restarts suddenly stopped watchers and SaveMessages keeps saving
+// after an outage of the database and after malformed messages, which are routed
+// to the rejection store by validation and never reach the database.
+func Test_Faults(t *testing.T) {
+	faults := pseudo.FaultConfig{Duplicate: 0.3, OutOfOrder: 0.3, Delay: 0.3, MaxDelay: 0.2,
+		Close: 0.3, Malformed: 0.3, Stall: 0.1, StallPeriod: 0.5, Seed: 1}
+	opts, err := json.Marshal(pseudo.Options{Faults: &faults})
+	if err != nil {
+		t.Fatalf("Test_Faults: %v", err)
+	}
+
+	conf := Config{Watchers: map[string]provider.WatcherConfig{}, MaintainPeriod: 1}
+	for _, id := range []string{"pseudo_1", "pseudo_2"} {
+		conf.Watchers[id] = provider.WatcherConfig{Id: id, T: provider.Pseudo, CheckPeriod: 1, Options: opts}
+	}
+
+	watchers, err := CreateWatchers(conf)
+	if err != nil {
+		t.Fatalf("Test_Faults: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Second)
+	defer cancel()
+
+	dbAdapter := &memAdapter{outage: 3}
+	watchPipes := make(chan (<-chan provider.Message))
+	counted := make(chan (<-chan provider.Message))
+	var starts int
+	var mu sync.Mutex
+	go func() {
+		for p := range watchPipes {
+			mu.Lock()
+			starts++
+			mu.Unlock()
+			counted <- p
+		}
+	}()
+
+	msgChan := MergeWatchPipes(counted)
+
+	go func() {
+		t := time.NewTicker(100 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				RestartWatchers(ctx, watchers, dbAdapter, watchPipes)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SaveMessages(ctx, msgChan, dbAdapter, WatcherRules(watchers))
+	}()
+
+	//save loop keeps working after invalid messages
+	deadline := time.After(5 * time.Second)
+	for {
+		saved, malformed, invalid := dbAdapter.counts()
+		mu.Lock()
+		s := starts
+		mu.Unlock()
+
+		if malformed > 0 {
+			t.Fatalf("Test_Faults: %d malformed messages passed validation", malformed)
+		}
+
+		//the outage is over once messages are saved
+		if invalid > 0 && saved > 10 && s > len(watchers) {
+			break
+		}
+
+		select {
+		case <-deadline:
+			t.Fatalf("Test_Faults: saved: %d, invalid: %d, watcher starts: %d", saved, invalid, s)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Test_Faults: the save loop is not stopped by the context")
+	}
+}
+
+func Test_SaveMessages_Outage(t *testing.T) {
+	msgs := make(chan provider.Message, 3)
+	for i := 1; i <= 3; i++ {
+		msgs <- provider.Message{SourceId: "pseudo_1", EventId: fmt.Sprint(i), FocusTime: time.Now().UTC()}
+	}
+	close(msgs)
+
+	//the failed message is retried, not dropped
+	dbAdapter := &memAdapter{outage: 2}
+	SaveMessages(context.Background(), msgs, dbAdapter, nil)
+	if len(dbAdapter.saved) != 3 || dbAdapter.saved[0].EventId != "1" {
+		t.Errorf("Test_SaveMessages_Outage: unexpected saved messages: %v", dbAdapter.saved)
+	}
+
+	//retrying is stopped by the context
+	msgs = make(chan provider.Message, 1)
+	msgs <- provider.Message{SourceId: "pseudo_1", EventId: "4", FocusTime: time.Now().UTC()}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SaveMessages(ctx, msgs, &memAdapter{outage: math.MaxInt32}, nil)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Test_SaveMessages_Outage: retrying is not stopped by the context")
+	}
+}
@@ -2,31 +2,37 @@
 // new instances implementing abstractions of the seismo/provider package.
 // The package provides an additional layer, that allows to avoid cyclic
 // dependencies between the seismo/provider package and its sub-packages.
+//
+// Watchers are created by factories of the provider registry (see provider.Register).
+// The package links all built-in provider packages, so they are registered
+// in every application importing it. Other providers (e.g. in-house ones shipped
+// as separate modules) register themselves the same way and are linked into
+// an application by a blank import:
+//
+//	import _ "example.com/seismo-inhouse/provider/mysource"
 package crt
 
 import (
 	"fmt"
 	"seismo/provider"
-	"seismo/provider/pseudo"
-	"seismo/provider/seishub"
+
+	_ "seismo/provider/emsc"
+	_ "seismo/provider/fdsn"
+	_ "seismo/provider/pseudo"
+	_ "seismo/provider/replay"
+	_ "seismo/provider/seishub"
+	_ "seismo/provider/smtpd"
+	_ "seismo/provider/usgs"
 )
 
 // NewWatcher creats a new watcher implementation depending on a specified provider type.
+// If the type is not registered, the returned error wraps provider.UnknownTypeErr
+// listing the registered types.
 func NewWatcher(conf provider.WatcherConfig) (provider.Watcher, error) {
-	switch conf.T {
-	case provider.Pseudo:
-		h, err := pseudo.NewHub(conf)
-		if err != nil {
-			return nil, fmt.Errorf("NewWatcher: %w", err)
-		}
-		return h, nil
-	case provider.Seishub:
-		h, err := seishub.NewHub(conf)
-		if err != nil {
-			return nil, fmt.Errorf("NewWatcher: %w", err)
-		}
-		return h, nil
-	default:
-		return nil, fmt.Errorf("unknown watcher type: %q", conf.T)
+	w, err := provider.NewWatcher(conf)
+	if err != nil {
+		return nil, fmt.Errorf("NewWatcher: %w", err)
 	}
+
+	return w, nil
 }
@@ -0,0 +1,38 @@
+package crt
+
+import (
+	"errors"
+	"seismo/provider"
+	"strings"
+	"testing"
+)
+
+func Test_BuiltinProviders(t *testing.T) {
+	types := []provider.ProviderType{provider.Pseudo, provider.Seishub, provider.Fdsn,
+		provider.Usgs, provider.Emsc, provider.Smtp, provider.Replay}
+
+	for _, pt := range types {
+		if _, ok := provider.Lookup(pt); !ok {
+			t.Errorf("Test_BuiltinProviders: %q is not registered", pt)
+		}
+	}
+
+	w, err := NewWatcher(provider.DefaultWatcherConfig())
+	if err != nil {
+		t.Fatalf("Test_BuiltinProviders: NewWatcher: %v", err)
+	}
+	if w.GetConfig().T != provider.DefT {
+		t.Errorf("Test_BuiltinProviders: unexpected watcher type %q", w.GetConfig().T)
+	}
+
+	_, err = NewWatcher(provider.WatcherConfig{Id: "x", T: "unknown"})
+	var ute provider.UnknownTypeErr
+	if !errors.As(err, &ute) {
+		t.Fatalf("Test_BuiltinProviders: UnknownTypeErr is expected, result: %v", err)
+	}
+	for _, pt := range types {
+		if !strings.Contains(err.Error(), string(pt)) {
+			t.Errorf("Test_BuiltinProviders: the error does not list %q: %v", pt, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package seismo/provider/emsc provides tools for getting information about
+// seismic activity from push services implementing the protocol of
+// the EMSC SeismicPortal WebSocket service
+// ("wss://www.seismicportal.eu/standing_order/websocket").
+//
+// A service pushes a JSON text message for every created or updated event:
+//
+//	{"action": "create", "data": {"type": "Feature", "id": "20230301_0000042",
+//		"geometry": {...}, "properties": {"unid": "20230301_0000042", "time": "...",
+//		"lat": 54.71, "lon": 83.67, "mag": 3.3, "evtype": "ke", ...}}}
+//
+// The service does not send events occurred before a connection is established.
+package emsc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"seismo/provider"
+	"strings"
+	"time"
+)
+
+const (
+	//DefConnStr defines the default address of the push service
+	DefConnStr = "wss://www.seismicportal.eu/standing_order/websocket"
+
+	//DefHistoryURL defines the default query address of the FDSN event service
+	//of SeismicPortal used for backfilling
+	DefHistoryURL = "https://www.seismicportal.eu/fdsnws/event/1/query?format=text"
+
+	//detailsLink defines the address of event pages
+	detailsLink = "https://www.seismicportal.eu/eventdetails.html"
+
+	//actions of push messages
+
+	createAction = "create"
+	updateAction = "update"
+)
+
+// PushMsg represents a message pushed by the service.
+type PushMsg struct {
+	// Action specifies the action with the event: "create" or "update".
+	Action string `json:"action"`
+
+	// Data specifies the event as a GeoJSON feature.
+	Data struct {
+		Id         string     `json:"id"`
+		Properties Properties `json:"properties"`
+	} `json:"data"`
+}
+
+// Properties represents the properties of a pushed event.
+type Properties struct {
+	// Unid specifies the unique identifier of the event.
+	Unid string `json:"unid"`
+
+	// Time specifies the origin time of the event.
+	Time time.Time `json:"time"`
+
+	// LastUpdate specifies the time the event was updated.
+	LastUpdate time.Time `json:"lastupdate"`
+
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+
+	// Depth specifies the depth of the event (km), nil if it is absent.
+	Depth *float64 `json:"depth"`
+
+	Mag     float64 `json:"mag"`
+	MagType string  `json:"magtype"`
+
+	// EvType specifies the type of the event, e.g. "ke" (known earthquake),
+	// "se" (suspected earthquake), "km" (known mine explosion), "kn" (known
+	// nuclear explosion), "ls" (landslide).
+	EvType string `json:"evtype"`
+
+	// Auth specifies the authoring agency of the event.
+	Auth string `json:"auth"`
+
+	// FlynnRegion specifies the name of the region of the event.
+	FlynnRegion string `json:"flynn_region"`
+}
+
+// ParsePushMsg returns a message pushed by the service decoded from "data" and an error.
+// If the returned error is not nil, the returned message is nil.
+func ParsePushMsg(data []byte) (*PushMsg, error) {
+	var pm PushMsg
+	if err := json.Unmarshal(data, &pm); err != nil {
+		return nil, fmt.Errorf("ParsePushMsg: %w", err)
+	}
+
+	if pm.Action != createAction && pm.Action != updateAction {
+		return nil, fmt.Errorf("ParsePushMsg: unknown action %q", pm.Action)
+	}
+
+	return &pm, nil
+}
+
+// ParseMsg returns a seismic event message extracted from a message pushed
+// by the service and an error. If the returned error is not nil, the returned message pointer is nil.
+func ParseMsg(data []byte) (*provider.Message, error) {
+	pm, err := ParsePushMsg(data)
+	if err != nil {
+		return nil, fmt.Errorf("ParseMsg: %w", err)
+	}
+
+	p := pm.Data.Properties
+	if p.Unid == "" {
+		p.Unid = pm.Data.Id
+	}
+	if p.Unid == "" {
+		return nil, fmt.Errorf("ParseMsg: empty event id")
+	}
+
+	if p.Time.IsZero() {
+		return nil, fmt.Errorf("ParseMsg: event %q: empty time", p.Unid)
+	}
+
+	m := provider.Message{
+		EventId:   p.Unid,
+		FocusTime: p.Time.UTC(),
+		Latitude:  p.Lat,
+		Longitude: p.Lon,
+		Magnitude: p.Mag,
+		Type:      defineEventType(p.EvType),
+		Link:      eventLink(p.Unid),
+		Depth:     p.Depth,
+		MagType:   p.MagType,
+		Agency:    p.Auth,
+		Action:    provider.Create,
+	}
+
+	if pm.Action == updateAction {
+		m.Action = provider.Update
+	}
+
+	if !p.LastUpdate.IsZero() {
+		m.UpdateTime = p.LastUpdate.UTC()
+	}
+
+	if p.FlynnRegion != "" {
+		m.Extra = map[string]string{"region": p.FlynnRegion}
+	}
+
+	return &m, nil
+}
+
+// eventLink returns the address of the page of the event "unid".
+func eventLink(unid string) string {
+	return detailsLink + "?unid=" + url.QueryEscape(unid)
+}
+
+// defineEventType converts a passed EMSC event type code to the corresponding EventType value.
+func defineEventType(s string) provider.EventType {
+	switch strings.ToLower(s) {
+	case "ke", "se":
+		return provider.EarthQuake
+	case "km", "sm":
+		return provider.MiningExplosion
+	case "kr", "sr":
+		return provider.RockBurst
+	case "ki", "si":
+		return provider.InducedOrTriggered
+	case "kx", "sx":
+		return provider.ExperimentalExplosion
+	case "kn", "sn":
+		return provider.NuclearExplosion
+	case "ls":
+		return provider.Landslide
+	default:
+		return provider.UnknownType
+	}
+}
@@ -0,0 +1,105 @@
+package emsc
+
+import (
+	"os"
+	"seismo/provider"
+	"testing"
+	"time"
+)
+
+func Test_ParseMsg(t *testing.T) {
+	tests := []struct {
+		name string
+		want provider.Message
+	}{
+		{
+			"testdata/create.json",
+			provider.Message{
+				EventId:   "20230301_0000042",
+				FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC),
+				Latitude:  54.71,
+				Longitude: 83.67,
+				Magnitude: 3.3,
+				Type:      provider.MiningExplosion,
+				Link:      "https://www.seismicportal.eu/eventdetails.html?unid=20230301_0000042",
+				Depth:     provider.Float(10),
+				MagType:   "ml",
+				Agency:    "ASRS",
+				Extra:     map[string]string{"region": "SOUTHWESTERN SIBERIA, RUSSIA"},
+				Action:    provider.Create,
+
+				UpdateTime: time.Date(2023, 3, 1, 5, 20, 1, 0, time.UTC),
+			},
+		},
+		{
+			"testdata/update.json",
+			provider.Message{
+				EventId:   "20230301_0000042",
+				FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 500000000, time.UTC),
+				Latitude:  54.72,
+				Longitude: 83.69,
+				Magnitude: 3.4,
+				Type:      provider.MiningExplosion,
+				Link:      "https://www.seismicportal.eu/eventdetails.html?unid=20230301_0000042",
+				Depth:     provider.Float(8),
+				MagType:   "ml",
+				Agency:    "ASRS",
+				Extra:     map[string]string{"region": "SOUTHWESTERN SIBERIA, RUSSIA"},
+				Action:    provider.Update,
+
+				UpdateTime: time.Date(2023, 3, 1, 5, 41, 12, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		b, err := os.ReadFile(test.name)
+		if err != nil {
+			t.Fatalf("Test_ParseMsg: %v", err)
+		}
+
+		res, err := ParseMsg(b)
+		if err != nil {
+			t.Fatalf("Test_ParseMsg: name: %s error: %v", test.name, err)
+		}
+
+		if !res.Equal(test.want) {
+			t.Errorf("Test_ParseMsg: name: %s\n\twant: %v\n\tres: %v", test.name, test.want, *res)
+		}
+	}
+}
+
+func Test_defineEventType(t *testing.T) {
+	tests := []struct {
+		code string
+		want provider.EventType
+	}{
+		{"ke", provider.EarthQuake},
+		{"KM", provider.MiningExplosion},
+		{"sm", provider.MiningExplosion},
+		{"sr", provider.RockBurst},
+		{"ls", provider.Landslide},
+		{"xx", provider.UnknownType},
+	}
+
+	for _, test := range tests {
+		if res := defineEventType(test.code); res != test.want {
+			t.Errorf("Test_defineEventType: code: %q want: %q res: %q", test.code, test.want, res)
+		}
+	}
+}
+
+func Test_ParseMsg_Errors(t *testing.T) {
+	tests := []string{
+		``,
+		`{"action":"delete","data":{"properties":{"unid":"1","time":"2023-03-01T05:13:16.5Z"}}}`,
+		`{"action":"create","data":{"properties":{"time":"2023-03-01T05:13:16.5Z"}}}`,
+		`{"action":"create","data":{"properties":{"unid":"1"}}}`,
+	}
+
+	for _, test := range tests {
+		if _, err := ParseMsg([]byte(test)); err == nil {
+			t.Errorf("Test_ParseMsg_Errors: an error is expected for %q", test)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package emsc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"seismo/provider"
+	"seismo/provider/fdsn"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	//minBackoff and maxBackoff define the bounds of the delay
+	//between reconnection attempts
+	minBackoff = time.Second
+	maxBackoff = 2 * time.Minute
+
+	//heartbeatPeriods defines the number of ping periods without any
+	//frame from the service, after which the connection is considered dead
+	heartbeatPeriods = 3
+)
+
+// hubState is implemented to provide a specific behavior within THE STATE PATTERN.
+type hubState interface {
+	startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error)
+	stateInfo() provider.WatcherStateInfo
+}
+
+// stoppedState implements a stopped Hub's behavior within THE STATE PATTERN.
+type stoppedState struct {
+	hub *Hub
+}
+
+func newStoppedState(h *Hub) *stoppedState {
+	return &stoppedState{hub: h}
+}
+
+// startWatch implements the behaivor of Hub.StartWatch in the "stopped" state,
+// i.e. starts receiving pushed messages and returns a channel for fetching messages.
+// If the returned error is not nil, the returned channel is nil.
+func (s *stoppedState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("cannot start with canceled context")
+	}
+
+	h := s.hub
+	h.setState(newRunState(h))
+	o := make(chan provider.Message)
+	go h.watch(ctx, o, from.UTC())
+
+	return o, nil
+}
+
+func (s *stoppedState) stateInfo() provider.WatcherStateInfo {
+	return provider.Stopped
+}
+
+// runState implements a running Hub's behavior within THE STATE PATTERN.
+type runState struct {
+	hub *Hub
+}
+
+func newRunState(h *Hub) *runState {
+	return &runState{hub: h}
+}
+
+func (r *runState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	return nil, provider.AlreadyRunErr{}
+}
+
+func (r *runState) stateInfo() provider.WatcherStateInfo {
+	return provider.Run
+}
+
+// Hub implements the provider.Watcher interface for a push service
+// addressed by the connection string of its configuration.
+//
+// Instead of polling, Hub holds a long-lived WebSocket connection to the service.
+// The connection is pinged every CheckPeriod, and it is considered dead if no frame
+// (a message or a pong) is received for several periods. A dead or broken connection
+// is reestablished with an exponential backoff.
+type Hub struct {
+	config provider.WatcherConfig
+
+	//state implements THE STATE PATTERN
+	state hubState
+
+	//mu guards the state, since the receiving go-routine stops the Hub
+	//concurrently with StateInfo and StartWatch calls.
+	mu sync.Mutex
+
+	//lifecycle publishes lifecycle events of the Hub
+	lifecycle provider.Lifecycle
+
+	//stats keeps statistics of the Hub
+	stats provider.StatsRecorder
+
+	dialer websocket.Dialer
+
+	//client requests the history service
+	client http.Client
+
+	//options are decoded from the configuration options block
+	options Options
+
+	//pingPeriod specifies the period of pinging the service.
+	pingPeriod time.Duration
+
+	//heartbeat specifies the time without frames, after which
+	//the connection is considered dead.
+	heartbeat time.Duration
+
+	//minBackoff specifies the initial delay between reconnection attempts.
+	minBackoff time.Duration
+}
+
+// Options contains options of an emsc watcher (see provider.WatcherConfig.Options).
+// The HTTP options set headers of the websocket handshake request.
+// Pushed events are filtered by the filter options.
+type Options struct {
+	provider.HTTPOptions
+	provider.FilterOptions
+
+	// HistoryURL specifies the query address of an FDSN event service
+	// providing past events for backfilling (see Hub.Backfill).
+	// The default value is DefHistoryURL.
+	HistoryURL string `json:"history_url"`
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		T: provider.Emsc,
+		Factory: func(conf provider.WatcherConfig) (provider.Watcher, error) {
+			h, err := NewHub(conf)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		Schema: provider.ConfigSchema{
+			Description: "Receives events pushed by the EMSC websocket service.",
+			ConnStr:     "The websocket address of the push service.",
+			DefConnStr:  DefConnStr,
+			Options:     Options{HistoryURL: DefHistoryURL},
+		},
+		Rules: provider.RuleSet{provider.RequiredLink},
+	})
+}
+
+// NewHub returns a pointer to a new emsc.Hub in the stopped state
+// configured by "conf" values and an error.
+//
+// If the returned error is not nil, the returned pointer is nil.
+func NewHub(conf provider.WatcherConfig) (*Hub, error) {
+	if conf.CheckPeriod < 1 {
+		return nil, fmt.Errorf("NewHub: checkperiod cannot be less than 1 (second)")
+	}
+
+	if conf.Timeout < 1 {
+		return nil, fmt.Errorf("NewHub: timeout cannot be less than 1 (second)")
+	}
+
+	if conf.ConnStr == "" {
+		conf.ConnStr = DefConnStr
+	}
+
+	opts := Options{HistoryURL: DefHistoryURL}
+	if err := provider.DecodeOptions(conf.Options, &opts); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	pp := time.Duration(conf.CheckPeriod) * time.Second
+	h := &Hub{
+		config:     conf,
+		options:    opts,
+		client:     http.Client{Timeout: time.Duration(conf.Timeout) * time.Second, Transport: opts.Transport(nil)},
+		dialer:     websocket.Dialer{HandshakeTimeout: time.Duration(conf.Timeout) * time.Second},
+		pingPeriod: pp,
+		heartbeat:  heartbeatPeriods * pp,
+		minBackoff: minBackoff,
+	}
+
+	h.setState(newStoppedState(h))
+
+	return h, nil
+}
+
+// GetConfig returns configuration of the Hub.
+func (h *Hub) GetConfig() provider.WatcherConfig {
+	return h.config
+}
+
+// setState sets the state of the Hub. The caller must hold h.mu,
+// except while the Hub is being created.
+func (h *Hub) setState(s hubState) {
+	prev := h.state
+	h.state = s
+	h.stats.Transited()
+
+	if prev != nil {
+		h.lifecycle.Transit(h.config.Id, prev.stateInfo(), s.stateInfo(), nil)
+	}
+}
+
+// Subscribe implements the provider.Observable interface.
+func (h *Hub) Subscribe(buf int) (<-chan provider.LifecycleEvent, func()) {
+	return h.lifecycle.Subscribe(buf)
+}
+
+// StateInfo reports a current state of the Hub.
+func (h *Hub) StateInfo() provider.WatcherStateInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.state.stateInfo()
+}
+
+// Stats returns the state and statistics of the Hub. A broken connection is counted
+// as a fetch error and reconnecting as a retry, every connection and received frame
+// is counted as a poll.
+func (h *Hub) Stats() provider.WatcherStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.Stats(h.state.stateInfo())
+}
+
+// StartWatch connects to the push service and starts receiving messages.
+//
+// The method returns a channel for fetching messages. If the returned error is not nil, the returned
+// channel is nil. Connection errors do not stop watching, the hub reconnects until
+// watching is canceled through the context.
+//
+// The service pushes only new events and updates, so "from" does not request
+// past events; messages with FocusTime before "from" are skipped.
+func (h *Hub) StartWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	o, err := h.state.startWatch(ctx, from)
+	return o, err
+}
+
+// watch keeps a connection to the service and sends received messages
+// into the "o" channel. Broken connections are reestablished with an exponential
+// backoff, which is reset after every connection that has received a frame.
+func (h *Hub) watch(ctx context.Context, o chan<- provider.Message, from time.Time) {
+	defer func() {
+		h.mu.Lock()
+		h.setState(newStoppedState(h))
+		h.mu.Unlock()
+		close(o)
+	}()
+
+	backoff := h.minBackoff
+	for {
+		alive, err := h.receive(ctx, o, from)
+		if ctx.Err() != nil {
+			log.Println("watch: Canceled")
+			return
+		}
+		log.Printf("watch: %v", err)
+		h.stats.FetchFailed(err)
+		h.lifecycle.Failed(h.config.Id, provider.Run, err)
+
+		if alive {
+			backoff = h.minBackoff
+		}
+
+		//the full jitter avoids simultaneous reconnections of many clients
+		d := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			log.Println("watch: Canceled")
+			return
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		h.stats.Retried()
+	}
+}
+
+// Backfill implements the provider.Backfiller interface. Since the push service
+// does not send past events, they are requested from the FDSN event service
+// addressed by the HistoryURL option (see fdsn.GetEvents). Event identifiers
+// of SeismicPortal services are the same, so backfilled messages match pushed ones.
+//
+// Backfilling can be performed while the Hub is watching.
+func (h *Hub) Backfill(ctx context.Context, from time.Time, to time.Time) (<-chan provider.Message, error) {
+	if err := provider.CheckRange(from, to); err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	l, err := fdsn.RangeURL(h.options.HistoryURL, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	o := make(chan provider.Message)
+	go func() {
+		defer close(o)
+
+		events, err := fdsn.GetEvents(ctx, l, &h.client)
+		if err != nil {
+			log.Printf("Backfill: %v", err)
+			return
+		}
+
+		msgs := make([]provider.Message, 0, len(events))
+		for _, m := range events {
+			if !provider.InRange(m.FocusTime, from, to) || !h.options.Match(*m) {
+				continue
+			}
+
+			m.SourceId = h.config.Id
+			m.Link = eventLink(m.EventId)
+			msgs = append(msgs, *m)
+		}
+
+		provider.SendAll(ctx, o, msgs)
+	}()
+
+	return o, nil
+}
+
+// receive connects to the service and sends received messages into the "o" channel
+// until the connection is broken or watching is canceled.
+//
+// The method returns whether any frame has been received from the service and the error
+// terminated the connection.
+func (h *Hub) receive(ctx context.Context, o chan<- provider.Message, from time.Time) (alive bool, err error) {
+	conn, _, err := h.dialer.DialContext(ctx, h.config.ConnStr, h.options.Header())
+	if err != nil {
+		return false, fmt.Errorf("receive: dial %q: %w", h.config.ConnStr, err)
+	}
+	defer conn.Close()
+	h.stats.Polled()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	//closing the connection on cancellation breaks the read loop below
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	beat := func() error {
+		return conn.SetReadDeadline(time.Now().Add(h.heartbeat))
+	}
+	beat()
+	conn.SetPongHandler(func(string) error {
+		alive = true
+		return beat()
+	})
+
+	go func() {
+		t := time.NewTicker(h.pingPeriod)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.pingPeriod)); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return alive, fmt.Errorf("receive: %w", err)
+		}
+		alive = true
+		beat()
+		h.stats.Polled()
+
+		m, err := ParseMsg(data)
+		if err != nil {
+			log.Printf("receive: %v", err)
+			h.stats.SetErr(fmt.Errorf("receive: %w", err))
+			continue
+		}
+
+		if m.FocusTime.Before(from) || !h.options.Match(*m) {
+			continue
+		}
+		m.SourceId = h.config.Id
+
+		select {
+		case o <- *m:
+			h.stats.Emitted()
+		case <-ctx.Done():
+			return alive, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package emsc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"seismo/provider"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer returns a stand-in of a push service. Every connection
+// is served by the "serve" function with the number of the connection.
+func newTestServer(t *testing.T, serve func(n int, conn *websocket.Conn)) *httptest.Server {
+	var up websocket.Upgrader
+	var cnt int32
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("newTestServer: %v", err)
+			return
+		}
+		defer conn.Close()
+		serve(int(atomic.AddInt32(&cnt, 1)), conn)
+	}))
+}
+
+func newTestHub(t *testing.T, srv *httptest.Server) *Hub {
+	conf := provider.WatcherConfig{Id: "emsc", T: provider.Emsc, Timeout: 5, CheckPeriod: 1,
+		ConnStr: "ws" + strings.TrimPrefix(srv.URL, "http")}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("newTestHub: %v", err)
+	}
+	h.minBackoff = 10 * time.Millisecond
+	h.pingPeriod = 100 * time.Millisecond
+	h.heartbeat = 300 * time.Millisecond
+
+	return h
+}
+
+func readFile(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	return b
+}
+
+func Test_StartWatch_Reconnect(t *testing.T) {
+	create := readFile(t, "testdata/create.json")
+	update := readFile(t, "testdata/update.json")
+
+	srv := newTestServer(t, func(n int, conn *websocket.Conn) {
+		switch n {
+		case 1: //the connection is broken after 2 messages
+			conn.WriteMessage(websocket.TextMessage, create)
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"action":"unknown"}`))
+			conn.WriteMessage(websocket.TextMessage, update)
+		default:
+			conn.WriteMessage(websocket.TextMessage, update)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}
+	})
+	defer srv.Close()
+
+	h := newTestHub(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Reconnect: %v", err)
+	}
+
+	want := []float64{3.3, 3.4, 3.4}
+	for i, w := range want {
+		select {
+		case m := <-ch:
+			if m.Magnitude != w || m.SourceId != "emsc" {
+				t.Errorf("Test_StartWatch_Reconnect: message %d: want magnitude: %v res: %v", i, w, m)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Test_StartWatch_Reconnect: timeout waiting for message %d", i)
+		}
+	}
+
+	cancel()
+	for range ch {
+	}
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_StartWatch_Reconnect: want state: %s, res: %s", provider.Stopped, s)
+	}
+
+	//the broken connection is a fetch error, the unknown action is the last error
+	if st := h.Stats(); st.Emitted != 3 || st.FetchErrors < 1 || st.Retries < 1 || st.LastErr == nil {
+		t.Errorf("Test_StartWatch_Reconnect: unexpected stats: %+v", st)
+	}
+}
+
+func Test_StartWatch_Heartbeat(t *testing.T) {
+	create := readFile(t, "testdata/create.json")
+
+	srv := newTestServer(t, func(n int, conn *websocket.Conn) {
+		if n == 1 { //a stalled connection: no reading, no pongs
+			time.Sleep(3 * time.Second)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, create)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer srv.Close()
+
+	h := newTestHub(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Heartbeat: %v", err)
+	}
+
+	select {
+	case m := <-ch:
+		if m.EventId != "20230301_0000042" {
+			t.Errorf("Test_StartWatch_Heartbeat: unexpected message %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Test_StartWatch_Heartbeat: the stalled connection has not been detected")
+	}
+}
+
+func Test_StartWatch_From(t *testing.T) {
+	create := readFile(t, "testdata/create.json")
+	update := readFile(t, "testdata/update.json")
+
+	srv := newTestServer(t, func(n int, conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, create)
+		conn.WriteMessage(websocket.TextMessage, update)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer srv.Close()
+
+	h := newTestHub(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	//the "create" message is before "from"
+	ch, err := h.StartWatch(ctx, time.Date(2023, 3, 1, 5, 13, 16, 450000000, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_From: %v", err)
+	}
+
+	select {
+	case m := <-ch:
+		if m.Magnitude != 3.4 {
+			t.Errorf("Test_StartWatch_From: unexpected message %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Test_StartWatch_From: timeout")
+	}
+}
@@ -0,0 +1,291 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// EventType represents the type of a sesmic event. Values are the event types
+// of the QuakeML vocabulary, e.g. "earthquake", "quarry blast", "landslide".
+// The empty value means an unknown type.
+//
+// EventType is marshalled as a string in JSON and BSON. Integers, which were used
+// to store the types earlier (0 - unknown, 1 - earthquake, 2 - quarry blast),
+// are still accepted by decoding.
+type EventType string
+
+const (
+	UnknownType EventType = ""
+
+	NotExisting           EventType = "not existing"
+	NotReported           EventType = "not reported"
+	EarthQuake            EventType = "earthquake"
+	AnthropogenicEvent    EventType = "anthropogenic event"
+	Collapse              EventType = "collapse"
+	CavityCollapse        EventType = "cavity collapse"
+	MineCollapse          EventType = "mine collapse"
+	BuildingCollapse      EventType = "building collapse"
+	Explosion             EventType = "explosion"
+	AccidentalExplosion   EventType = "accidental explosion"
+	ChemicalExplosion     EventType = "chemical explosion"
+	ControlledExplosion   EventType = "controlled explosion"
+	ExperimentalExplosion EventType = "experimental explosion"
+	IndustrialExplosion   EventType = "industrial explosion"
+	MiningExplosion       EventType = "mining explosion"
+	QuarryBlast           EventType = "quarry blast"
+	RoadCut               EventType = "road cut"
+	BlastingLevee         EventType = "blasting levee"
+	NuclearExplosion      EventType = "nuclear explosion"
+	InducedOrTriggered    EventType = "induced or triggered event"
+	RockBurst             EventType = "rock burst"
+	ReservoirLoading      EventType = "reservoir loading"
+	FluidInjection        EventType = "fluid injection"
+	FluidExtraction       EventType = "fluid extraction"
+	Crash                 EventType = "crash"
+	PlaneCrash            EventType = "plane crash"
+	TrainCrash            EventType = "train crash"
+	BoatCrash             EventType = "boat crash"
+	OtherEvent            EventType = "other event"
+	AtmosphericEvent      EventType = "atmospheric event"
+	SonicBoom             EventType = "sonic boom"
+	SonicBlast            EventType = "sonic blast"
+	AcousticNoise         EventType = "acoustic noise"
+	Thunder               EventType = "thunder"
+	Avalanche             EventType = "avalanche"
+	SnowAvalanche         EventType = "snow avalanche"
+	DebrisAvalanche       EventType = "debris avalanche"
+	HydroacousticEvent    EventType = "hydroacoustic event"
+	IceQuake              EventType = "ice quake"
+	Slide                 EventType = "slide"
+	Landslide             EventType = "landslide"
+	Rockslide             EventType = "rockslide"
+	Meteorite             EventType = "meteorite"
+	VolcanicEruption      EventType = "volcanic eruption"
+)
+
+// eventTypes lists the QuakeML event type vocabulary.
+var eventTypes = []EventType{
+	NotExisting, NotReported, EarthQuake, AnthropogenicEvent, Collapse, CavityCollapse,
+	MineCollapse, BuildingCollapse, Explosion, AccidentalExplosion, ChemicalExplosion,
+	ControlledExplosion, ExperimentalExplosion, IndustrialExplosion, MiningExplosion,
+	QuarryBlast, RoadCut, BlastingLevee, NuclearExplosion, InducedOrTriggered, RockBurst,
+	ReservoirLoading, FluidInjection, FluidExtraction, Crash, PlaneCrash, TrainCrash,
+	BoatCrash, OtherEvent, AtmosphericEvent, SonicBoom, SonicBlast, AcousticNoise, Thunder,
+	Avalanche, SnowAvalanche, DebrisAvalanche, HydroacousticEvent, IceQuake, Slide,
+	Landslide, Rockslide, Meteorite, VolcanicEruption,
+}
+
+// legacyEventTypes maps integer values of event types stored earlier to the types.
+var legacyEventTypes = [...]EventType{UnknownType, EarthQuake, QuarryBlast}
+
+// EventTypes returns the QuakeML event type vocabulary.
+func EventTypes() []EventType {
+	return append([]EventType(nil), eventTypes...)
+}
+
+// ParseEventType returns the event type named "s". The name is case insensitive
+// and extra spaces are ignored. Names out of the QuakeML vocabulary (e.g. typos
+// or local labels) are mapped to UnknownType.
+func ParseEventType(s string) EventType {
+	t := EventType(strings.Join(strings.Fields(strings.ToLower(s)), " "))
+	if !t.Known() {
+		return UnknownType
+	}
+
+	return t
+}
+
+// Known reports whether the type is unknown or belongs to the QuakeML vocabulary.
+func (t EventType) Known() bool {
+	if t == UnknownType {
+		return true
+	}
+
+	for _, v := range eventTypes {
+		if t == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnmarshalJSON decodes a type from a JSON string or a legacy integer.
+func (t *EventType) UnmarshalJSON(data []byte) error {
+	s, n, err := unmarshalJSONEnum(data)
+	if err != nil {
+		return fmt.Errorf("UnmarshalJSON: event type: %w", err)
+	}
+
+	if s == nil {
+		v, err := legacyEventType(n)
+		if err != nil {
+			return fmt.Errorf("UnmarshalJSON: %w", err)
+		}
+		*t = v
+		return nil
+	}
+
+	*t = EventType(*s)
+	return nil
+}
+
+// UnmarshalBSONValue decodes a type from a BSON string or a legacy integer.
+func (t *EventType) UnmarshalBSONValue(bt bsontype.Type, data []byte) error {
+	s, n, err := unmarshalBSONEnum(bt, data)
+	if err != nil {
+		return fmt.Errorf("UnmarshalBSONValue: event type: %w", err)
+	}
+
+	if s == nil {
+		v, err := legacyEventType(n)
+		if err != nil {
+			return fmt.Errorf("UnmarshalBSONValue: %w", err)
+		}
+		*t = v
+		return nil
+	}
+
+	*t = EventType(*s)
+	return nil
+}
+
+func legacyEventType(n int64) (EventType, error) {
+	if n < 0 || n >= int64(len(legacyEventTypes)) {
+		return UnknownType, fmt.Errorf("legacyEventType: unexpected value %d", n)
+	}
+
+	return legacyEventTypes[n], nil
+}
+
+// RandEventType creates a random value of the EventType type
+// (unknown, earthquake or quarry blast).
+func RandEventType() EventType {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return legacyEventTypes[r.Intn(len(legacyEventTypes))]
+}
+
+// EventQuality represents quality of a seismic event assessment.
+// The empty value means an unknown quality.
+//
+// EventQuality is marshalled as a string in JSON and BSON. Integers, which were used
+// to store the quality earlier (0 - unknown, 1 - preliminary, 2 - good, 3 - excellent),
+// are still accepted by decoding.
+type EventQuality string
+
+const (
+	UnknownQuality EventQuality = ""
+	Preliminary    EventQuality = "preliminary"
+	Good           EventQuality = "good"
+	Excellent      EventQuality = "excellent"
+)
+
+// legacyQualities maps integer values of qualities stored earlier to the qualities.
+var legacyQualities = [...]EventQuality{UnknownQuality, Preliminary, Good, Excellent}
+
+// UnmarshalJSON decodes a quality from a JSON string or a legacy integer.
+func (q *EventQuality) UnmarshalJSON(data []byte) error {
+	s, n, err := unmarshalJSONEnum(data)
+	if err != nil {
+		return fmt.Errorf("UnmarshalJSON: event quality: %w", err)
+	}
+
+	if s == nil {
+		v, err := legacyQuality(n)
+		if err != nil {
+			return fmt.Errorf("UnmarshalJSON: %w", err)
+		}
+		*q = v
+		return nil
+	}
+
+	*q = EventQuality(*s)
+	return nil
+}
+
+// UnmarshalBSONValue decodes a quality from a BSON string or a legacy integer.
+func (q *EventQuality) UnmarshalBSONValue(bt bsontype.Type, data []byte) error {
+	s, n, err := unmarshalBSONEnum(bt, data)
+	if err != nil {
+		return fmt.Errorf("UnmarshalBSONValue: event quality: %w", err)
+	}
+
+	if s == nil {
+		v, err := legacyQuality(n)
+		if err != nil {
+			return fmt.Errorf("UnmarshalBSONValue: %w", err)
+		}
+		*q = v
+		return nil
+	}
+
+	*q = EventQuality(*s)
+	return nil
+}
+
+func legacyQuality(n int64) (EventQuality, error) {
+	if n < 0 || n >= int64(len(legacyQualities)) {
+		return UnknownQuality, fmt.Errorf("legacyQuality: unexpected value %d", n)
+	}
+
+	return legacyQualities[n], nil
+}
+
+// RandEventQuality creates a random value of the EventQuality type.
+func RandEventQuality() EventQuality {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return legacyQualities[r.Intn(len(legacyQualities))]
+}
+
+// unmarshalJSONEnum decodes a JSON string or integer. If the value is a string,
+// the returned string pointer is not nil, otherwise the returned integer is the value.
+// JSON null is decoded as an empty string.
+func unmarshalJSONEnum(data []byte) (*string, int64, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		s := ""
+		return &s, 0, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return &s, 0, nil
+	}
+
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, 0, fmt.Errorf("unmarshalJSONEnum: a string or an integer is expected: %s", data)
+	}
+
+	return nil, n, nil
+}
+
+// unmarshalBSONEnum decodes a BSON string or number like unmarshalJSONEnum.
+// BSON null is decoded as an empty string.
+func unmarshalBSONEnum(bt bsontype.Type, data []byte) (*string, int64, error) {
+	v := bsoncore.Value{Type: bt, Data: data}
+
+	switch bt {
+	case bsontype.String:
+		s, ok := v.StringValueOK()
+		if !ok {
+			return nil, 0, fmt.Errorf("unmarshalBSONEnum: malformed string")
+		}
+		return &s, 0, nil
+	case bsontype.Null, bsontype.Undefined:
+		s := ""
+		return &s, 0, nil
+	}
+
+	if n, ok := v.AsInt64OK(); ok {
+		return nil, n, nil
+	}
+
+	return nil, 0, fmt.Errorf("unmarshalBSONEnum: a string or an integer is expected, BSON type: %s", bt)
+}
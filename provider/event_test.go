@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_EventType_JSON(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantType    EventType
+		wantQuality EventQuality
+	}{
+		{`{"event_type":"quarry blast","quality":"excellent"}`, QuarryBlast, Excellent},
+		{`{"event_type":"nuclear explosion","quality":"good"}`, NuclearExplosion, Good},
+		{`{"event_type":"","quality":""}`, UnknownType, UnknownQuality},
+		{`{"event_type":null,"quality":null}`, UnknownType, UnknownQuality},
+		{`{}`, UnknownType, UnknownQuality},
+
+		//legacy integers
+		{`{"event_type":0,"quality":0}`, UnknownType, UnknownQuality},
+		{`{"event_type":1,"quality":1}`, EarthQuake, Preliminary},
+		{`{"event_type":2,"quality":3}`, QuarryBlast, Excellent},
+	}
+
+	for _, test := range tests {
+		var m Message
+		if err := json.Unmarshal([]byte(test.input), &m); err != nil {
+			t.Errorf("Test_EventType_JSON: input: %s error: %v", test.input, err)
+			continue
+		}
+
+		if m.Type != test.wantType || m.Quality != test.wantQuality {
+			t.Errorf("Test_EventType_JSON: input: %s want: %q %q result: %q %q",
+				test.input, test.wantType, test.wantQuality, m.Type, m.Quality)
+		}
+	}
+
+	for _, input := range []string{`{"event_type":3}`, `{"quality":-1}`, `{"event_type":true}`} {
+		var m Message
+		if err := json.Unmarshal([]byte(input), &m); err == nil {
+			t.Errorf("Test_EventType_JSON: input: %s an error is expected", input)
+		}
+	}
+
+	b, err := json.Marshal(Message{Type: Landslide, Quality: Good})
+	if err != nil {
+		t.Fatalf("Test_EventType_JSON: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatalf("Test_EventType_JSON: %v", err)
+	}
+	if raw["event_type"] != "landslide" || raw["quality"] != "good" {
+		t.Errorf("Test_EventType_JSON: strings are expected: %s", b)
+	}
+}
+
+func Test_EventType_BSON(t *testing.T) {
+	tests := []struct {
+		doc         bson.D
+		wantType    EventType
+		wantQuality EventQuality
+	}{
+		{bson.D{{Key: "event_type", Value: "induced or triggered event"}, {Key: "quality", Value: "preliminary"}}, InducedOrTriggered, Preliminary},
+		{bson.D{{Key: "event_type", Value: int32(2)}, {Key: "quality", Value: int32(2)}}, QuarryBlast, Good},
+		{bson.D{{Key: "event_type", Value: int64(1)}, {Key: "quality", Value: int64(3)}}, EarthQuake, Excellent},
+		{bson.D{{Key: "event_type", Value: 0.0}, {Key: "quality", Value: nil}}, UnknownType, UnknownQuality},
+	}
+
+	for _, test := range tests {
+		b, err := bson.Marshal(test.doc)
+		if err != nil {
+			t.Fatalf("Test_EventType_BSON: %v", err)
+		}
+
+		var m Message
+		if err := bson.Unmarshal(b, &m); err != nil {
+			t.Errorf("Test_EventType_BSON: doc: %v error: %v", test.doc, err)
+			continue
+		}
+
+		if m.Type != test.wantType || m.Quality != test.wantQuality {
+			t.Errorf("Test_EventType_BSON: doc: %v want: %q %q result: %q %q",
+				test.doc, test.wantType, test.wantQuality, m.Type, m.Quality)
+		}
+	}
+
+	b, err := bson.Marshal(Message{Type: QuarryBlast, Quality: Excellent})
+	if err != nil {
+		t.Fatalf("Test_EventType_BSON: %v", err)
+	}
+	if s, ok := bson.Raw(b).Lookup("event_type").StringValueOK(); !ok || s != "quarry blast" {
+		t.Errorf("Test_EventType_BSON: a string is expected: %v", bson.Raw(b))
+	}
+}
+
+func Test_ParseEventType(t *testing.T) {
+	tests := []struct {
+		input string
+		want  EventType
+	}{
+		{"Quarry  Blast", QuarryBlast},
+		{" earthquake ", EarthQuake},
+		{"", UnknownType},
+		{"sonic boom", SonicBoom},
+		{"glacial quake", UnknownType},
+		{"землетрясение", UnknownType},
+	}
+
+	for _, test := range tests {
+		if res := ParseEventType(test.input); res != test.want {
+			t.Errorf("Test_ParseEventType: input: %q want: %q result: %q", test.input, test.want, res)
+		}
+	}
+
+	if EventType("glacial quake").Known() || !UnknownType.Known() || !SonicBoom.Known() {
+		t.Errorf("Test_ParseEventType: unexpected result of Known")
+	}
+
+	if n := len(EventTypes()); n != 44 {
+		t.Errorf("Test_ParseEventType: want vocabulary size: 44, result: %d", n)
+	}
+}
+
+func Test_RandEventType(t *testing.T) {
+	types := map[EventType]bool{}
+	qualities := map[EventQuality]bool{}
+	for i := 0; i < 1000 && (len(types) < 3 || len(qualities) < 4); i++ {
+		types[RandEventType()] = true
+		qualities[RandEventQuality()] = true
+	}
+
+	for _, v := range []EventType{UnknownType, EarthQuake, QuarryBlast} {
+		if !types[v] {
+			t.Errorf("Test_RandEventType: %q is never generated", v)
+		}
+	}
+
+	for _, v := range []EventQuality{UnknownQuality, Preliminary, Good, Excellent} {
+		if !qualities[v] {
+			t.Errorf("Test_RandEventQuality: %q is never generated", v)
+		}
+	}
+}
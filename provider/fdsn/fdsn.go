@@ -0,0 +1,372 @@
+// Package seismo/provider/fdsn provides tools for getting information about
+// seismic activity from any source implementing the FDSN event web service
+// specification (fdsnws-event), e.g. GEOFON, EMSC, ISC, USGS.
+//
+// A query address of a source (the connection string of a watcher) is
+// an "fdsnws/event/1/query" url, which can contain any additional query
+// parameters supported by the source, e.g.
+// "https://geofon.gfz-potsdam.de/fdsnws/event/1/query?format=text&minmagnitude=4".
+// Time window parameters (starttime, updatedafter, orderby) are managed
+// by the package and should not be specified in the query address.
+//
+// Both response formats defined by the specification are supported:
+// "text" (pipe separated values) and "xml" (QuakeML). The format is
+// selected by the "format" parameter of the query address, if the parameter
+// is absent the specification default (QuakeML) is expected.
+package fdsn
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"seismo/provider"
+	"seismo/provider/fetch"
+	"seismo/provider/quakeml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//DefConnStr defines the default query address (GEOFON)
+	DefConnStr = "https://geofon.gfz-potsdam.de/fdsnws/event/1/query"
+
+	//formats of responses
+	textFormat = "text"
+	xmlFormat  = "xml"
+
+	//timeLayout defines the time format of query parameters
+	timeLayout = "2006-01-02T15:04:05"
+)
+
+// defClient is a package-level default http client, that can be
+// used by package functions, having no specified client(s).
+// The Timeout value is 60 sec, failed requests are retried by the shared fetch layer.
+var defClient = http.Client{Timeout: 60 * time.Second, Transport: &fetch.Transport{Retries: fetch.DefRetries}}
+
+// textTimeLayouts lists the time formats found in the "Time" column
+// of the text format responses of various services.
+var textTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// QueryURL returns a query address built from the "base" query address
+// with the time window parameters and an error. If the returned error is not nil,
+// the returned string is empty.
+//
+// The "start" parameter sets the "starttime" parameter. If "updatedAfter"
+// is not the zero time, it sets the "updatedafter" parameter.
+// Events are requested in ascending order of their origin time.
+func QueryURL(base string, start time.Time, updatedAfter time.Time) (string, error) {
+	u, err := queryBase(base)
+	if err != nil {
+		return "", fmt.Errorf("QueryURL: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("starttime", start.UTC().Format(timeLayout))
+	if !updatedAfter.IsZero() {
+		q.Set("updatedafter", updatedAfter.UTC().Format(timeLayout))
+	}
+	q.Set("orderby", "time-asc")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// RangeURL returns a query address built from the "base" query address
+// requesting events with the origin time from "start" to "end" and an error.
+// If the returned error is not nil, the returned string is empty.
+// Events are requested in ascending order of their origin time.
+func RangeURL(base string, start time.Time, end time.Time) (string, error) {
+	u, err := queryBase(base)
+	if err != nil {
+		return "", fmt.Errorf("RangeURL: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("starttime", start.UTC().Format(timeLayout))
+	q.Set("endtime", end.UTC().Format(timeLayout))
+	q.Set("orderby", "time-asc")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// EventURL returns an address of a single event, the identifier
+// of which is "eventId", built from the "base" query address and an error.
+// If the returned error is not nil, the returned string is empty.
+func EventURL(base string, eventId string) (string, error) {
+	u, err := queryBase(base)
+	if err != nil {
+		return "", fmt.Errorf("EventURL: %w", err)
+	}
+
+	q := u.Query()
+	for k := range q {
+		if k != "format" {
+			q.Del(k)
+		}
+	}
+	q.Set("eventid", eventId)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// filterQuery returns the "base" query address with the query parameters
+// set by the filter options "f" and an error. If the returned error is not nil,
+// the returned string is empty.
+func filterQuery(base string, f provider.FilterOptions) (string, error) {
+	u, err := queryBase(base)
+	if err != nil {
+		return "", fmt.Errorf("filterQuery: %w", err)
+	}
+
+	q := u.Query()
+	for _, p := range []struct {
+		name string
+		v    *float64
+	}{
+		{"minmagnitude", f.MinMagnitude},
+		{"minlatitude", f.MinLatitude},
+		{"maxlatitude", f.MaxLatitude},
+		{"minlongitude", f.MinLongitude},
+		{"maxlongitude", f.MaxLongitude},
+	} {
+		if p.v != nil {
+			q.Set(p.name, strconv.FormatFloat(*p.v, 'f', -1, 64))
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// queryBase parses the "base" query address and appends the "query"
+// method to its path if the method is not specified.
+func queryBase(base string) (*url.URL, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("queryBase: %w", err)
+	}
+
+	if !strings.HasSuffix(u.Path, "/query") {
+		u = u.JoinPath("query")
+	}
+
+	return u, nil
+}
+
+// Format returns the response format ("text" or "xml") requested
+// by the "link" query address.
+func Format(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return xmlFormat
+	}
+
+	if f := strings.ToLower(u.Query().Get("format")); f == textFormat {
+		return textFormat
+	}
+
+	return xmlFormat
+}
+
+// GetEvents requests events addressed by the "link" query address and
+// returns a slice of messages built from the response and an error.
+// If the returned error is not nil, the returned slice is nil.
+//
+// If there are no events matching the query (the 204 or 404 response status),
+// the returned slice is empty.
+//
+// If the "cl" parameter is nil, the function uses the default package-level http client.
+func GetEvents(ctx context.Context, link string, cl *http.Client) ([]*provider.Message, error) {
+	if cl == nil {
+		cl = &defClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetEvents: link: %q error: %w", link, err)
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GetEvents: link: %q error: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound:
+		return []*provider.Message{}, nil
+	default:
+		return nil, fmt.Errorf("GetEvents: link: %q unexpected status: %s", link, resp.Status)
+	}
+
+	var msgs []*provider.Message
+	if Format(link) == textFormat {
+		msgs, err = ParseText(resp.Body)
+	} else {
+		msgs, err = ParseQuakeML(resp.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetEvents: link: %q error: %w", link, err)
+	}
+
+	return msgs, nil
+}
+
+// ParseText returns a slice of messages extracted from a text format
+// response read from "r" and an error.
+// If the returned error is not nil, the returned slice is nil.
+//
+// Columns are located by the header line, so their order and number
+// (e.g. the optional "EventType" column) may vary between services.
+func ParseText(r io.Reader) ([]*provider.Message, error) {
+	msgs := make([]*provider.Message, 0)
+	cols := map[string]int{}
+
+	sc := bufio.NewScanner(r)
+	for ln := 1; sc.Scan(); ln++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "|")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields[0] = strings.TrimSpace(strings.TrimPrefix(fields[0], "#"))
+			for i, f := range fields {
+				cols[strings.ToLower(f)] = i
+			}
+			continue
+		}
+
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("ParseText: line %d: no header line", ln)
+		}
+
+		m, err := parseTextLine(fields, cols)
+		if err != nil {
+			return nil, fmt.Errorf("ParseText: line %d: %w", ln, err)
+		}
+		msgs = append(msgs, m)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ParseText: %w", err)
+	}
+
+	return msgs, nil
+}
+
+// parseTextLine builds a message from the "fields" of a text format line.
+// The "cols" parameter maps lower case column names to field indexes.
+func parseTextLine(fields []string, cols map[string]int) (*provider.Message, error) {
+	field := func(name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return fields[i]
+	}
+
+	var m provider.Message
+	var err error
+
+	if m.EventId = field("eventid"); m.EventId == "" {
+		return nil, fmt.Errorf("parseTextLine: empty EventID")
+	}
+
+	if m.FocusTime, err = parseTime(field("time")); err != nil {
+		return nil, fmt.Errorf("parseTextLine: parse Time: %w", err)
+	}
+
+	if m.Latitude, err = strconv.ParseFloat(field("latitude"), 64); err != nil {
+		return nil, fmt.Errorf("parseTextLine: parse Latitude: %w", err)
+	}
+
+	if m.Longitude, err = strconv.ParseFloat(field("longitude"), 64); err != nil {
+		return nil, fmt.Errorf("parseTextLine: parse Longitude: %w", err)
+	}
+
+	if s := field("magnitude"); s != "" {
+		if m.Magnitude, err = strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("parseTextLine: parse Magnitude: %w", err)
+		}
+	}
+
+	if s := field("depth/km"); s != "" {
+		d, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parseTextLine: parse Depth: %w", err)
+		}
+		m.Depth = &d
+	}
+
+	m.Type = quakeml.EventType(field("eventtype"))
+	m.MagType = field("magtype")
+	m.Author = field("author")
+	m.Agency = field("contributor")
+
+	extra := map[string]string{
+		"catalog":        field("catalog"),
+		"contributor_id": field("contributorid"),
+		"mag_author":     field("magauthor"),
+		"region":         field("eventlocationname"),
+	}
+	for k, v := range extra {
+		if v == "" {
+			delete(extra, k)
+		}
+	}
+	if len(extra) > 0 {
+		m.Extra = extra
+	}
+
+	return &m, nil
+}
+
+// parseTime parses a time value of a text format response as UTC time.
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, l := range textTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(l, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// ParseQuakeML returns a slice of messages extracted from a QuakeML
+// document read from "r" and an error.
+// If the returned error is not nil, the returned slice is nil.
+//
+// The preferred origin and magnitude of every event are used. If they are not
+// specified, the first ones are used.
+func ParseQuakeML(r io.Reader) ([]*provider.Message, error) {
+	events, err := quakeml.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("ParseQuakeML: %w", err)
+	}
+
+	msgs := make([]*provider.Message, 0, len(events))
+	for i := range events {
+		msgs = append(msgs, &events[i])
+	}
+
+	return msgs, nil
+}
@@ -0,0 +1,182 @@
+package fdsn
+
+import (
+	"net/url"
+	"os"
+	"seismo/provider"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ParseText(t *testing.T) {
+	f, err := os.Open("testdata/geofon.txt")
+	if err != nil {
+		t.Fatalf("Test_ParseText: %v", err)
+	}
+	defer f.Close()
+
+	res, err := ParseText(f)
+	if err != nil {
+		t.Fatalf("Test_ParseText: error: %v", err)
+	}
+
+	if len(res) != 4 {
+		t.Fatalf("Test_ParseText: want len: 4, res len: %d", len(res))
+	}
+
+	want := provider.Message{
+		EventId:   "gfz2023eesfwx",
+		FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC),
+		Latitude:  54.71,
+		Longitude: 83.67,
+		Magnitude: 3.3,
+		Type:      provider.QuarryBlast,
+		Depth:     provider.Float(10),
+		MagType:   "mb",
+		Agency:    "GFZ",
+		Extra: map[string]string{
+			"contributor_id": "gfz2023eesfwx",
+			"region":         "Southwestern Siberia, Russia",
+		},
+	}
+	if !res[1].Equal(want) {
+		t.Errorf("Test_ParseText: \n\twant: %v\n\tres: %v", want, *res[1])
+	}
+
+	if res[3].Type != provider.UnknownType {
+		t.Errorf("Test_ParseText: empty event type: want: %v, res: %v", provider.UnknownType, res[3].Type)
+	}
+}
+
+func Test_ParseText_NoHeader(t *testing.T) {
+	input := "gfz2023eesfwx|2023-03-01T05:13:16.43|54.71|83.67|10.0|||GFZ|gfz2023eesfwx|mb|3.3||Southwestern Siberia, Russia|quarry blast\n"
+
+	if _, err := ParseText(strings.NewReader(input)); err == nil {
+		t.Errorf("Test_ParseText_NoHeader: an error is expected for a response without a header")
+	}
+}
+
+func Test_ParseQuakeML(t *testing.T) {
+	f, err := os.Open("testdata/usgs.xml")
+	if err != nil {
+		t.Fatalf("Test_ParseQuakeML: %v", err)
+	}
+	defer f.Close()
+
+	res, err := ParseQuakeML(f)
+	if err != nil {
+		t.Fatalf("Test_ParseQuakeML: error: %v", err)
+	}
+
+	want := []provider.Message{
+		{
+			EventId:          "us7000jk3l",
+			FocusTime:        time.Date(2023, 3, 1, 7, 21, 19, 458000000, time.UTC),
+			Latitude:         2.4506,
+			Longitude:        127.3547,
+			Magnitude:        4.7,
+			Type:             provider.EarthQuake,
+			Quality:          provider.Excellent,
+			Depth:            provider.Float(35),
+			MagType:          "mb",
+			DepthUncertainty: provider.Float(1.9),
+			StationCount:     provider.Int(41),
+			PhaseCount:       provider.Int(41),
+			AzimuthalGap:     provider.Float(74),
+			Agency:           "us",
+
+			UpdateTime: time.Date(2023, 3, 1, 7, 40, 10, 40000000, time.UTC),
+		},
+		{
+			EventId:   "gfz2023eesfwx",
+			FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC),
+			Latitude:  54.71,
+			Longitude: 83.67,
+			Magnitude: 3.3,
+			Type:      provider.QuarryBlast,
+			Quality:   provider.Preliminary,
+			MagType:   "mb",
+		},
+	}
+
+	if len(res) != len(want) {
+		t.Fatalf("Test_ParseQuakeML: want len: %d, res len: %d", len(want), len(res))
+	}
+
+	for i := range want {
+		if !res[i].Equal(want[i]) {
+			t.Errorf("Test_ParseQuakeML: \n\twant: %v\n\tres: %v", want[i], *res[i])
+		}
+	}
+}
+
+func Test_QueryURL(t *testing.T) {
+	start := time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC)
+	upd := time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		base         string
+		updatedAfter time.Time
+		want         url.Values
+		wantPath     string
+	}{
+		{
+			"https://geofon.gfz-potsdam.de/fdsnws/event/1/query?format=text",
+			time.Time{},
+			url.Values{"format": {"text"}, "starttime": {"2023-03-01T05:13:16"}, "orderby": {"time-asc"}},
+			"/fdsnws/event/1/query",
+		},
+		{
+			"https://www.seismicportal.eu/fdsnws/event/1/?minmag=4",
+			upd,
+			url.Values{"minmag": {"4"}, "starttime": {"2023-03-01T05:13:16"},
+				"updatedafter": {"2023-03-02T00:00:00"}, "orderby": {"time-asc"}},
+			"/fdsnws/event/1/query",
+		},
+	}
+
+	for _, test := range tests {
+		res, err := QueryURL(test.base, start, test.updatedAfter)
+		if err != nil {
+			t.Fatalf("Test_QueryURL: base: %s error: %v", test.base, err)
+		}
+
+		u, _ := url.Parse(res)
+		if u.Path != test.wantPath {
+			t.Errorf("Test_QueryURL: base: %s want path: %s res path: %s", test.base, test.wantPath, u.Path)
+		}
+
+		q := u.Query()
+		if len(q) != len(test.want) {
+			t.Errorf("Test_QueryURL: base: %s want: %v res: %v", test.base, test.want, q)
+		}
+		for k := range test.want {
+			if q.Get(k) != test.want.Get(k) {
+				t.Errorf("Test_QueryURL: base: %s param %q want: %q res: %q", test.base, k, test.want.Get(k), q.Get(k))
+			}
+		}
+	}
+}
+
+func Test_NewHub_Options(t *testing.T) {
+	conf := provider.WatcherConfig{Id: "geofon", T: provider.Fdsn, Timeout: 10, CheckPeriod: 60,
+		ConnStr: "https://geofon.gfz-potsdam.de/fdsnws/event/1/query?format=text",
+		Options: []byte(`{"min_magnitude": 4.5, "min_latitude": 40, "max_latitude": 70}`)}
+
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("Test_NewHub_Options: %v", err)
+	}
+
+	u, _ := url.Parse(h.query)
+	want := url.Values{"format": {"text"}, "minmagnitude": {"4.5"}, "minlatitude": {"40"}, "maxlatitude": {"70"}}
+	if q := u.Query(); q.Encode() != want.Encode() {
+		t.Errorf("Test_NewHub_Options: want query: %v res: %v", want, q)
+	}
+
+	conf.Options = []byte(`{"max_latitude": 95}`)
+	if _, err := NewHub(conf); err == nil {
+		t.Errorf("Test_NewHub_Options: an out of range option is accepted")
+	}
+}
@@ -0,0 +1,386 @@
+package fdsn
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"seismo/provider"
+	"sync"
+	"time"
+)
+
+const (
+	//defLookback defines how long events stay in the query window.
+	//Revisions of older events are not tracked by watching.
+	defLookback = 7 * 24 * time.Hour
+)
+
+// hubState is implemented to provide a specific behavior within THE STATE PATTERN.
+type hubState interface {
+	startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error)
+	stateInfo() provider.WatcherStateInfo
+}
+
+// stoppedState implements a stopped Hub's behavior within THE STATE PATTERN.
+type stoppedState struct {
+	hub *Hub
+}
+
+func newStoppedState(h *Hub) *stoppedState {
+	return &stoppedState{hub: h}
+}
+
+// startWatch implements the behaivor of Hub.StartWatch in the "stopped" state,
+// i.e. starts polling the source for events and returns a channel for fetching messages.
+// If the returned error is not nil, the returned channel is nil.
+func (s *stoppedState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("cannot start with canceled context")
+	}
+
+	from = from.UTC()
+	if from.After(time.Now().UTC()) {
+		return nil, fmt.Errorf(`watching cannot be started in the future (the "from" arg cannot be after the start time)`)
+	}
+
+	h := s.hub
+	h.setState(newRunState(h))
+	o := make(chan provider.Message)
+	go h.watch(ctx, o, from, time.Duration(h.config.CheckPeriod)*time.Second)
+
+	return o, nil
+}
+
+func (s *stoppedState) stateInfo() provider.WatcherStateInfo {
+	return provider.Stopped
+}
+
+// runState implements a running Hub's behavior within THE STATE PATTERN.
+type runState struct {
+	hub *Hub
+}
+
+func newRunState(h *Hub) *runState {
+	return &runState{hub: h}
+}
+
+func (r *runState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	return nil, provider.AlreadyRunErr{}
+}
+
+func (r *runState) stateInfo() provider.WatcherStateInfo {
+	return provider.Run
+}
+
+// Hub implements the provider.Watcher interface for an FDSN event web service
+// addressed by the connection string of its configuration.
+//
+// Hub embeds an http.Client.
+type Hub struct {
+	config provider.WatcherConfig
+	http.Client
+
+	//options are decoded from the configuration options block
+	options Options
+
+	//query is the query address of the configuration
+	//with the parameters of the options
+	query string
+
+	//state implements THE STATE PATTERN
+	state hubState
+
+	//mu guards the state, since the polling go-routine stops the Hub
+	//concurrently with StateInfo and StartWatch calls.
+	mu sync.Mutex
+
+	//lifecycle publishes lifecycle events of the Hub
+	lifecycle provider.Lifecycle
+
+	//stats keeps statistics of the Hub
+	stats provider.StatsRecorder
+}
+
+// Options contains options of an fdsn watcher (see provider.WatcherConfig.Options).
+// The filter options are passed to the service as query parameters
+// ("minmagnitude", "minlatitude" etc).
+type Options struct {
+	provider.HTTPOptions
+	provider.FilterOptions
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		T: provider.Fdsn,
+		Factory: func(conf provider.WatcherConfig) (provider.Watcher, error) {
+			h, err := NewHub(conf)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		Schema: provider.ConfigSchema{
+			Description: "Polls an FDSN event web service (fdsnws-event), e.g. GEOFON, EMSC, ISC, USGS.",
+			ConnStr:     "A \"fdsnws/event/1/query\" url with optional query parameters, e.g. \"format=text\".",
+			DefConnStr:  DefConnStr,
+			Options:     Options{},
+		},
+		Rules: provider.RuleSet{provider.RequiredLink},
+	})
+}
+
+// NewHub returns a pointer to a new fdsn.Hub in the stopped state
+// configured by "conf" values and an error.
+//
+// If the returned error is not nil, the returned pointer is nil.
+func NewHub(conf provider.WatcherConfig) (*Hub, error) {
+	if conf.CheckPeriod < 1 {
+		return nil, fmt.Errorf("NewHub: checkperiod cannot be less than 1 (second)")
+	}
+
+	if conf.Timeout < 1 {
+		return nil, fmt.Errorf("NewHub: timeout cannot be less than 1 (second)")
+	}
+
+	if conf.ConnStr == "" {
+		conf.ConnStr = DefConnStr
+	}
+
+	var opts Options
+	if err := provider.DecodeOptions(conf.Options, &opts); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	query, err := filterQuery(conf.ConnStr, opts.FilterOptions)
+	if err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	h := &Hub{config: conf, options: opts, query: query,
+		Client: http.Client{Timeout: time.Duration(conf.Timeout) * time.Second, Transport: opts.Transport(nil)}}
+
+	h.setState(newStoppedState(h))
+
+	return h, nil
+}
+
+// GetConfig returns configuration of the Hub.
+func (h *Hub) GetConfig() provider.WatcherConfig {
+	return h.config
+}
+
+// setState sets the state of the Hub. The caller must hold h.mu,
+// except while the Hub is being created.
+func (h *Hub) setState(s hubState) {
+	prev := h.state
+	h.state = s
+	h.stats.Transited()
+
+	if prev != nil {
+		h.lifecycle.Transit(h.config.Id, prev.stateInfo(), s.stateInfo(), nil)
+	}
+}
+
+// Subscribe implements the provider.Observable interface.
+func (h *Hub) Subscribe(buf int) (<-chan provider.LifecycleEvent, func()) {
+	return h.lifecycle.Subscribe(buf)
+}
+
+// StateInfo reports a current state of the Hub.
+func (h *Hub) StateInfo() provider.WatcherStateInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.state.stateInfo()
+}
+
+// Stats returns the state and statistics of the Hub. Failed requests to the FDSN service
+// are counted as fetch errors, a request following a failed one is counted as a retry.
+func (h *Hub) Stats() provider.WatcherStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.Stats(h.state.stateInfo())
+}
+
+// StartWatch starts polling the FDSN event service every CheckPeriod
+// for events with an origin time after (or equal to) "from".
+//
+// The method returns a channel for fetching messages. If the returned error is not nil, the returned
+// channel is nil.
+//
+// The first request fetches all events since "from". Every next request fetches
+// only events created or updated after the previous request, so a revised event
+// is sent again with the update action. Watching can't be started in the future. Returns an error in such case.
+func (h *Hub) StartWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	o, err := h.state.startWatch(ctx, from)
+	return o, err
+}
+
+// window keeps the query window of watching and the events sent within it.
+type window struct {
+	//start specifies the start of the window (starttime).
+	start time.Time
+
+	//updatedAfter specifies the time of the previous request (updatedafter).
+	updatedAfter time.Time
+
+	//sent maps identifiers of sent events to their last sent messages.
+	sent map[string]provider.Message
+}
+
+// watch polls the service with a frequency of "checkPeriod" and sends
+// new and revised messages into the "o" channel.
+func (h *Hub) watch(ctx context.Context, o chan<- provider.Message, from time.Time, checkPeriod time.Duration) {
+	defer func() {
+		h.mu.Lock()
+		h.setState(newStoppedState(h))
+		h.mu.Unlock()
+		close(o)
+	}()
+
+	w := window{start: from, sent: make(map[string]provider.Message)}
+	wt := time.NewTicker(checkPeriod)
+	defer wt.Stop()
+
+	failed := false
+	for {
+		if failed {
+			h.stats.Retried()
+		}
+
+		msgs, err := h.poll(ctx, &w)
+		if err != nil {
+			log.Printf("watch: %v", err)
+			h.stats.FetchFailed(err)
+			h.lifecycle.Failed(h.config.Id, provider.Run, err)
+		} else {
+			h.stats.Polled()
+		}
+		failed = err != nil
+
+		for _, m := range msgs {
+			select {
+			case o <- *m:
+				h.stats.Emitted()
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-wt.C:
+		case <-ctx.Done():
+			log.Println("watch: Canceled")
+			return
+		}
+	}
+}
+
+// Backfill implements the provider.Backfiller interface. It requests events
+// of the [from, to) range from the service with a single query.
+//
+// Backfilling can be performed while the Hub is watching.
+func (h *Hub) Backfill(ctx context.Context, from time.Time, to time.Time) (<-chan provider.Message, error) {
+	if err := provider.CheckRange(from, to); err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	l, err := RangeURL(h.query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	o := make(chan provider.Message)
+	go func() {
+		defer close(o)
+
+		events, err := GetEvents(ctx, l, &h.Client)
+		if err != nil {
+			log.Printf("Backfill: %v", err)
+			return
+		}
+
+		msgs := make([]provider.Message, 0, len(events))
+		for _, m := range events {
+			if !provider.InRange(m.FocusTime, from, to) || !h.options.Match(*m) {
+				continue
+			}
+
+			m.SourceId = h.config.Id
+			if m.Link, err = EventURL(h.config.ConnStr, m.EventId); err != nil {
+				log.Printf("Backfill: %v", err)
+				continue
+			}
+			msgs = append(msgs, *m)
+		}
+
+		provider.SendAll(ctx, o, msgs)
+	}()
+
+	return o, nil
+}
+
+// poll requests events of the "w" window and returns new and changed
+// messages and an error. If the returned error is not nil, the returned slice is nil.
+// The window is moved if the request is successful.
+func (h *Hub) poll(ctx context.Context, w *window) ([]*provider.Message, error) {
+	reqTime := time.Now().UTC()
+
+	l, err := QueryURL(h.query, w.start, w.updatedAfter)
+	if err != nil {
+		return nil, fmt.Errorf("poll: %w", err)
+	}
+
+	events, err := GetEvents(ctx, l, &h.Client)
+	if err != nil {
+		return nil, fmt.Errorf("poll: %w", err)
+	}
+
+	msgs := make([]*provider.Message, 0, len(events))
+	for _, m := range events {
+		if m.FocusTime.Before(w.start) || !h.options.Match(*m) {
+			continue
+		}
+
+		m.SourceId = h.config.Id
+		if m.Link, err = EventURL(h.config.ConnStr, m.EventId); err != nil {
+			return nil, fmt.Errorf("poll: %w", err)
+		}
+
+		prev, sent := w.sent[m.EventId]
+		if sent && prev.Equal(*m) {
+			continue
+		}
+		w.sent[m.EventId] = *m
+
+		if m.Action == "" {
+			m.Action = provider.Create
+			if sent {
+				m.Action = provider.Update
+			}
+		}
+		msgs = append(msgs, m)
+	}
+
+	//the previous request time minus one check period overlaps
+	//possible delays of updating the service data
+	w.updatedAfter = reqTime.Add(-time.Duration(h.config.CheckPeriod) * time.Second)
+	if s := reqTime.Add(-defLookback); s.After(w.start) {
+		w.start = s
+	}
+	for id, m := range w.sent {
+		if m.FocusTime.Before(w.start) {
+			delete(w.sent, id)
+		}
+	}
+
+	return msgs, nil
+}
@@ -0,0 +1,141 @@
+package fdsn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"seismo/provider"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a stand-in of an FDSN event service serving the
+// recorded "testdata/geofon.txt" response for the first request and
+// "testdata/geofon_update.txt" for requests with the "updatedafter" parameter.
+// The date of the recorded events is replaced with the "day" date.
+func newTestServer(t *testing.T, day time.Time) *httptest.Server {
+	read := func(name string) string {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("newTestServer: %v", err)
+		}
+		return strings.ReplaceAll(string(b), "2023-03-01", day.Format("2006-01-02"))
+	}
+	first := read("testdata/geofon.txt")
+	update := read("testdata/geofon_update.txt")
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/fdsnws/event/1/query" || q.Get("starttime") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if q.Get("updatedafter") != "" {
+			w.Write([]byte(update))
+			return
+		}
+		w.Write([]byte(first))
+	}))
+}
+
+func Test_StartWatch(t *testing.T) {
+	day := time.Now().UTC().AddDate(0, 0, -1).Truncate(24 * time.Hour)
+	srv := newTestServer(t, day)
+	defer srv.Close()
+
+	conf := provider.WatcherConfig{Id: "geofon", T: provider.Fdsn, Timeout: 10, CheckPeriod: 1,
+		ConnStr: srv.URL + "/fdsnws/event/1/?format=text"}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("Test_StartWatch: NewHub: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, day.Add(6*time.Hour))
+	if err != nil {
+		t.Fatalf("Test_StartWatch: %v", err)
+	}
+
+	if _, err = h.StartWatch(ctx, day); err == nil {
+		t.Errorf("Test_StartWatch: an AlreadyRunErr error is expected for a running hub")
+	}
+
+	//the first request sends 2 events after 6:00, the second one sends
+	//1 revised and 1 new event and skips 1 unchanged event
+	want := []struct {
+		id     string
+		mag    float64
+		action provider.Action
+	}{
+		{"gfz2023eevmbq", 4.1, provider.Create},
+		{"gfz2023efbvla", 4.8, provider.Create},
+		{"gfz2023efbvla", 5.0, provider.Update},
+		{"gfz2023efhqzt", 4.4, provider.Create},
+	}
+
+	for i, w := range want {
+		select {
+		case m := <-ch:
+			if m.EventId != w.id || m.Magnitude != w.mag || m.Action != w.action || m.SourceId != conf.Id {
+				t.Errorf("Test_StartWatch: message %d: want: %s %v %s, res: %v", i, w.id, w.mag, w.action, m)
+			}
+			if !strings.Contains(m.Link, "eventid="+w.id) {
+				t.Errorf("Test_StartWatch: message %d: unexpected link %q", i, m.Link)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Test_StartWatch: timeout waiting for message %d", i)
+		}
+	}
+
+	cancel()
+	for range ch {
+	}
+
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_StartWatch: want state: %s, res: %s", provider.Stopped, s)
+	}
+
+	if st := h.Stats(); st.State != provider.Stopped || st.Emitted != 4 || st.LastPoll.IsZero() || st.FetchErrors != 0 {
+		t.Errorf("Test_StartWatch: unexpected stats: %+v", st)
+	}
+}
+
+func Test_Backfill(t *testing.T) {
+	day := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, day)
+	defer srv.Close()
+
+	conf := provider.WatcherConfig{Id: "geofon", T: provider.Fdsn, Timeout: 5, CheckPeriod: 60,
+		ConnStr: srv.URL + "/fdsnws/event/1/query?format=text"}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	if _, err := h.Backfill(context.Background(), day, day); err == nil {
+		t.Errorf("Test_Backfill: an empty range is accepted")
+	}
+
+	//the range excludes the first and the last events of the response
+	ch, err := h.Backfill(context.Background(), day.Add(3*time.Hour), day.Add(9*time.Hour))
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	var ids []string
+	for m := range ch {
+		if m.SourceId != "geofon" || m.Link == "" {
+			t.Errorf("Test_Backfill: unexpected message: %v", m)
+		}
+		ids = append(ids, m.EventId)
+	}
+
+	if want := "gfz2023eesfwx gfz2023eevmbq"; strings.Join(ids, " ") != want {
+		t.Errorf("Test_Backfill: want events: %s, result: %v", want, ids)
+	}
+}
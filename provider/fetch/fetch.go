@@ -0,0 +1,314 @@
+// Package seismo/provider/fetch provides the shared HTTP fetch layer of providers:
+// a round tripper retrying requests failed because of transient errors with
+// exponential backoff and limiting the rate of requests to every host.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefRetries defines the default number of retries of a failed request.
+	DefRetries = 3
+
+	// DefMinDelay defines the default delay before the first retry.
+	DefMinDelay = 500 * time.Millisecond
+
+	// DefMaxDelay defines the default max delay before a retry.
+	DefMaxDelay = 30 * time.Second
+
+	// DefMaxRetryAfter defines the default max delay requested by a server with
+	// the Retry-After header, which is waited for. If a server requests a longer delay,
+	// its response is returned without retrying.
+	DefMaxRetryAfter = 2 * time.Minute
+
+	//maxDrain constant defines max number of bytes read from the body
+	//of a response before retrying to reuse the connection
+	maxDrain = 64 * 1024
+)
+
+// StatusErr indicates that a server has responded with an unexpected status.
+type StatusErr struct {
+	URL        string
+	StatusCode int
+}
+
+func (e StatusErr) Error() string {
+	return fmt.Sprintf("Unexpected status %d %s of %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+}
+
+// Temporary reports whether the status indicates a transient error,
+// i.e. the request can succeed if it is repeated later.
+func (e StatusErr) Temporary() bool {
+	return Transient(e.StatusCode)
+}
+
+// IsTransient reports whether the error "err" is a StatusErr with a transient status.
+func IsTransient(err error) bool {
+	var se StatusErr
+	return errors.As(err, &se) && se.Temporary()
+}
+
+// Transient reports whether the status code "c" indicates a transient error of a server
+// (408 Request Timeout, 429 Too Many Requests or 5xx except 501 Not Implemented).
+func Transient(c int) bool {
+	switch {
+	case c == http.StatusRequestTimeout, c == http.StatusTooManyRequests:
+		return true
+	case c >= 500 && c != http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// Transport is an http.RoundTripper retrying GET and HEAD requests failed because
+// of network errors (e.g. timeouts) or transient statuses (see Transient)
+// with exponential backoff and jitter, and limiting the rate of requests to every host.
+//
+// The delay requested by a server with the Retry-After header is waited for instead
+// of the backoff delay. The retries are stopped by cancellation of the context
+// of the request (e.g. the timeout of the http.Client).
+//
+// The zero value sends requests with http.DefaultTransport without retries and limits.
+type Transport struct {
+	// Base specifies the round tripper sending requests.
+	// If Base is nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Retries specifies max number of retries of a failed request.
+	Retries int
+
+	// MinDelay specifies the backoff delay before the first retry, it is doubled for every
+	// next retry up to MaxDelay. If they are 0, DefMinDelay and DefMaxDelay are used.
+	MinDelay time.Duration
+	MaxDelay time.Duration
+
+	// MaxRetryAfter specifies max delay requested with the Retry-After header,
+	// which is waited for. If it is 0, DefMaxRetryAfter is used.
+	MaxRetryAfter time.Duration
+
+	// Rate specifies max number of requests per second to a host, Burst specifies
+	// the number of requests which can be sent at once. If Rate is 0, the rate is not limited.
+	Rate  float64
+	Burst int
+
+	// Limiter keeps the token buckets of hosts. If Limiter is nil, the Shared limiter
+	// is used, i.e. the rate of requests to a host is limited for all watchers together.
+	Limiter *Limiter
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) limiter() *Limiter {
+	if t.Limiter == nil {
+		return Shared
+	}
+	return t.Limiter
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	for attempt := 0; ; attempt++ {
+		if t.Rate > 0 {
+			if err := t.limiter().Wait(ctx, req.URL.Host, t.Rate, t.Burst); err != nil {
+				return nil, fmt.Errorf("RoundTrip: %w", err)
+			}
+		}
+
+		resp, err := t.base().RoundTrip(req)
+		if !retryable || attempt >= t.Retries {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		case Transient(resp.StatusCode):
+			if d, ok := RetryAfter(resp.Header, time.Now()); ok {
+				if d > t.maxRetryAfter() {
+					return resp, nil
+				}
+				delay = d
+			}
+			io.CopyN(io.Discard, resp.Body, maxDrain)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		wt := time.NewTimer(delay)
+		select {
+		case <-wt.C:
+		case <-ctx.Done():
+			wt.Stop()
+			return nil, fmt.Errorf("RoundTrip: waiting for retry: %w", ctx.Err())
+		}
+	}
+}
+
+func (t *Transport) maxRetryAfter() time.Duration {
+	if t.MaxRetryAfter <= 0 {
+		return DefMaxRetryAfter
+	}
+	return t.MaxRetryAfter
+}
+
+// backoff returns the delay before the retry after the failed attempt "attempt" (counted from 0):
+// the half of the exponential delay plus a random jitter up to the other half.
+func (t *Transport) backoff(attempt int) time.Duration {
+	lo, hi := t.MinDelay, t.MaxDelay
+	if lo <= 0 {
+		lo = DefMinDelay
+	}
+	if hi <= 0 {
+		hi = DefMaxDelay
+	}
+
+	d := lo
+	for i := 0; i < attempt && d < hi; i++ {
+		d *= 2
+	}
+	if d > hi {
+		d = hi
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// RetryAfter returns the delay specified by the Retry-After header of "h" (in seconds
+// or as an HTTP date relative to "now") and reports whether the header is valid.
+func RetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// Limiter keeps token buckets limiting the rate of requests to hosts.
+// It is safe for concurrent use. The zero value is ready to use.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// Shared is the limiter shared by all Transports without their own Limiter.
+var Shared = &Limiter{}
+
+// Wait waits until a request to the host can be sent at the rate "rate" (requests per second)
+// with the burst "burst" (at least 1) or the context is done. If the host is limited with
+// a different rate by other Transports, the least rate and burst are used.
+func (l *Limiter) Wait(ctx context.Context, host string, rate float64, burst int) error {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+		l.buckets[host] = b
+	}
+	l.mu.Unlock()
+
+	d := b.reserve(time.Now(), rate, float64(burst))
+	if d <= 0 {
+		return nil
+	}
+
+	wt := time.NewTimer(d)
+	defer wt.Stop()
+	select {
+	case <-wt.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return fmt.Errorf("Wait: host %s: %w", host, ctx.Err())
+	}
+}
+
+// bucket implements a token bucket. A reservation takes a token even if the bucket
+// is empty, so waiting reservations are served in order.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token at the time "now" and returns the delay before the token is available.
+// The rate and burst of the bucket are lowered to "rate" and "burst" if they are less.
+func (b *bucket) reserve(now time.Time, rate float64, burst float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if rate < b.rate {
+		b.rate = rate
+	}
+	if burst < b.burst {
+		b.burst = burst
+	}
+
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		b.last = now
+	}
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by a reservation which has not been used.
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+}
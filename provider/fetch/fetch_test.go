@@ -0,0 +1,193 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFailingServer returns a server responding with the "status" status (and the "retryAfter"
+// Retry-After header, if it is not empty) to the first "failures" requests and with 200 OK
+// to the others. If "status" is 0, connections of the failed requests are broken.
+func newFailingServer(requests *int32, failures int32, status int, retryAfter string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) > failures {
+			w.Write([]byte("ok"))
+			return
+		}
+
+		if status == 0 {
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+			return
+		}
+
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+}
+
+func Test_Transport_Retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int32
+		status     int
+		retryAfter string
+		wantStatus int
+		wantReqs   int32
+		minTime    time.Duration
+	}{
+		{"bad gateway", 2, http.StatusBadGateway, "", http.StatusOK, 3, 0},
+		{"broken connection", 1, 0, "", http.StatusOK, 2, 0},
+		{"not found", 1, http.StatusNotFound, "", http.StatusNotFound, 1, 0},
+		{"exhausted retries", 5, http.StatusServiceUnavailable, "", http.StatusServiceUnavailable, 4, 0},
+		{"retry after", 1, http.StatusTooManyRequests, "1", http.StatusOK, 2, time.Second},
+		{"too long retry after", 1, http.StatusServiceUnavailable, "3600", http.StatusServiceUnavailable, 1, 0},
+	}
+
+	for _, test := range tests {
+		var reqs int32
+		srv := newFailingServer(&reqs, test.failures, test.status, test.retryAfter)
+
+		cl := http.Client{Timeout: 10 * time.Second,
+			Transport: &Transport{Retries: 3, MinDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}}
+		start := time.Now()
+		resp, err := cl.Get(srv.URL)
+		d := time.Since(start)
+		srv.Close()
+
+		if err != nil {
+			t.Errorf("Test_Transport_Retry: %s: %v", test.name, err)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != test.wantStatus || reqs != test.wantReqs {
+			t.Errorf("Test_Transport_Retry: %s: want status: %d requests: %d, res: %d %d",
+				test.name, test.wantStatus, test.wantReqs, resp.StatusCode, reqs)
+		}
+		if d < test.minTime {
+			t.Errorf("Test_Transport_Retry: %s: the Retry-After delay has not been waited for: %v", test.name, d)
+		}
+	}
+}
+
+func Test_Transport_Cancel(t *testing.T) {
+	var reqs int32
+	srv := newFailingServer(&reqs, 100, http.StatusServiceUnavailable, "")
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	cl := http.Client{Transport: &Transport{Retries: 10, MinDelay: time.Minute}}
+	start := time.Now()
+	if _, err := cl.Do(req); err == nil {
+		t.Errorf("Test_Transport_Cancel: want an error")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Test_Transport_Cancel: retrying has not been canceled promptly: %v", d)
+	}
+}
+
+func Test_Limiter(t *testing.T) {
+	var reqs int32
+	srv := newFailingServer(&reqs, 0, 0, "")
+	defer srv.Close()
+
+	//two transports share the limiter, the least rate is applied
+	l := &Limiter{}
+	fast := http.Client{Transport: &Transport{Rate: 1000, Burst: 10, Limiter: l}}
+	slow := http.Client{Transport: &Transport{Rate: 20, Burst: 1, Limiter: l}}
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		cl := fast
+		if i == 0 {
+			cl = slow
+		}
+		resp, err := cl.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Test_Limiter: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	//5 intervals at 20 requests per second
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("Test_Limiter: the requests have not been limited: %v", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Wait(context.Background(), "other", 1, 1) //the token is taken
+	if err := l.Wait(ctx, "other", 1, 1); err == nil {
+		t.Errorf("Test_Limiter: want an error of the canceled context")
+	}
+}
+
+func Test_RetryAfter(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		v    string
+		want time.Duration
+		ok   bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		h := http.Header{}
+		if test.v != "" {
+			h.Set("Retry-After", test.v)
+		}
+		if res, ok := RetryAfter(h, now); res != test.want || ok != test.ok {
+			t.Errorf("Test_RetryAfter: %q: want: %v %v res: %v %v", test.v, test.want, test.ok, res, ok)
+		}
+	}
+}
+
+func Test_backoff(t *testing.T) {
+	tr := Transport{MinDelay: time.Second, MaxDelay: 8 * time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, time.Second},
+		{2, 4 * time.Second},
+		{10, 8 * time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 10; i++ {
+			if d := tr.backoff(test.attempt); d < test.max/2 || d > test.max {
+				t.Errorf("Test_backoff: attempt: %d want: [%v, %v] res: %v", test.attempt, test.max/2, test.max, d)
+			}
+		}
+	}
+}
+
+func Test_IsTransient(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusBadGateway: true, http.StatusTooManyRequests: true, http.StatusNotImplemented: false,
+		http.StatusNotFound: false, http.StatusForbidden: false,
+	} {
+		if res := IsTransient(StatusErr{URL: "http://x", StatusCode: code}); res != want {
+			t.Errorf("Test_IsTransient: %d: want: %v res: %v", code, want, res)
+		}
+	}
+}
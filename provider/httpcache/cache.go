@@ -0,0 +1,335 @@
+// Package seismo/provider/httpcache provides a round tripper caching responses
+// of GET requests on disk and revalidating them with conditional requests,
+// so the resources which have not changed are not transferred again.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FromCacheHeader is the header set into responses got from the cache.
+	FromCacheHeader = "X-From-Cache"
+
+	//pruneInterval constant defines the min interval between automatic pruning
+	pruneInterval = time.Hour
+
+	metaExt = ".json"
+	bodyExt = ".body"
+)
+
+// Transport is an http.RoundTripper caching successful responses of GET requests in the Dir
+// directory. Every entry of the cache consists of the body of a response and its metadata
+// (the ETag and Last-Modified validators). A cached resource is revalidated with a conditional
+// request (If-None-Match, If-Modified-Since), and the cached body is used if the resource
+// has not been modified. Immutable resources are got from the cache without requests.
+//
+// Transport is safe for concurrent use.
+type Transport struct {
+	// Dir specifies the cache directory. It is created on storing if it does not exist.
+	Dir string
+
+	// Base specifies the round tripper sending requests.
+	// If Base is nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Immutable reports whether the resource addressed by a URL never changes,
+	// so its cached response is used without revalidation.
+	// If Immutable is nil, all cached resources are revalidated.
+	Immutable func(u *url.URL) bool
+
+	// MaxAge specifies the time after the last use of an entry when it is removed by pruning.
+	// If MaxAge is 0, entries are not removed by age.
+	MaxAge time.Duration
+
+	// MaxSize specifies max total size of cached bodies in bytes. Pruning removes
+	// least recently used entries exceeding the size. If MaxSize is 0, the size is not limited.
+	MaxSize int64
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// entry represents metadata of a cached response.
+type entry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+	Size         int64  `json:"size"`
+}
+
+// response creates a response to "req" with the cached body.
+func (e *entry) response(req *http.Request, body []byte) *http.Response {
+	h := make(http.Header)
+	if e.ContentType != "" {
+		h.Set("Content-Type", e.ContentType)
+	}
+	h.Set(FromCacheHeader, "1")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+// Errors of the cache are logged, and the request is sent as without the cache.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base().RoundTrip(req)
+	}
+
+	key := cacheKey(req.URL)
+	e, body, err := t.load(key)
+	if err != nil {
+		log.Printf("RoundTrip: %v", err)
+	}
+
+	if e != nil && t.Immutable != nil && t.Immutable(req.URL) {
+		t.touch(key)
+		return e.response(req, body), nil
+	}
+
+	r := req
+	if e != nil && (e.ETag != "" || e.LastModified != "") {
+		//a round tripper should not modify the request
+		r = req.Clone(req.Context())
+		if e.ETag != "" {
+			r.Header.Set("If-None-Match", e.ETag)
+		}
+		if e.LastModified != "" {
+			r.Header.Set("If-Modified-Since", e.LastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && r != req {
+		resp.Body.Close()
+		t.touch(key)
+		return e.response(req, body), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return resp, nil
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("RoundTrip: read body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	ne := entry{URL: req.URL.String(), ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"),
+		ContentType: resp.Header.Get("Content-Type"), Size: int64(len(b))}
+	if err := t.store(key, ne, b); err != nil {
+		log.Printf("RoundTrip: %v", err)
+	}
+	t.autoPrune()
+
+	return resp, nil
+}
+
+// cacheKey returns the name of the files of the cache entry of the URL.
+func cacheKey(u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// load returns the cache entry "key" and its body. If there is no such entry, the entry is nil.
+func (t *Transport) load(key string) (*entry, []byte, error) {
+	m, err := os.ReadFile(filepath.Join(t.Dir, key+metaExt))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("load: %w", err)
+	}
+
+	var e entry
+	if err := json.Unmarshal(m, &e); err != nil {
+		return nil, nil, fmt.Errorf("load: entry %s: %w", key, err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(t.Dir, key+bodyExt))
+	if err != nil {
+		return nil, nil, fmt.Errorf("load: %w", err)
+	}
+	if int64(len(body)) != e.Size { //the body has been replaced, but the metadata has not been yet
+		return nil, nil, fmt.Errorf("load: entry %s: the size of the body is %d instead of %d", key, len(body), e.Size)
+	}
+
+	return &e, body, nil
+}
+
+// store saves the body and then the metadata of the cache entry "key".
+func (t *Transport) store(key string, e entry, body []byte) error {
+	m, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+
+	if err := writeFile(t.Dir, key+bodyExt, body); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	if err := writeFile(t.Dir, key+metaExt, m); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+
+	return nil
+}
+
+// writeFile writes "b" into a temporary file and renames it into "name",
+// so a file of the cache is never left partially written.
+func writeFile(dir string, name string, b []byte) error {
+	f, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(dir, name))
+}
+
+// touch records the use of the cache entry "key" as the modification time of its metadata.
+func (t *Transport) touch(key string) {
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(t.Dir, key+metaExt), now, now); err != nil {
+		log.Printf("touch: %v", err)
+	}
+}
+
+// autoPrune prunes the cache if MaxAge or MaxSize is set, not more often than once per pruneInterval.
+func (t *Transport) autoPrune() {
+	if t.MaxAge == 0 && t.MaxSize == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	if time.Since(t.lastPrune) < pruneInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.lastPrune = time.Now()
+	t.mu.Unlock()
+
+	if err := t.Prune(); err != nil {
+		log.Printf("autoPrune: %v", err)
+	}
+}
+
+// Prune removes the entries, which have not been used for MaxAge, and then least recently
+// used entries while the total size of cached bodies exceeds MaxSize.
+func (t *Transport) Prune() error {
+	files, err := os.ReadDir(t.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Prune: %w", err)
+	}
+
+	type use struct {
+		key  string
+		time time.Time
+		size int64
+	}
+
+	now := time.Now()
+	var uses []use
+	var total int64
+	for _, f := range files {
+		key := strings.TrimSuffix(f.Name(), metaExt)
+		if f.IsDir() || key == f.Name() {
+			continue
+		}
+
+		mi, err := f.Info()
+		if err != nil {
+			continue
+		}
+
+		if t.MaxAge > 0 && now.Sub(mi.ModTime()) > t.MaxAge {
+			t.remove(key)
+			continue
+		}
+
+		var size int64
+		if bi, err := os.Stat(filepath.Join(t.Dir, key+bodyExt)); err == nil {
+			size = bi.Size()
+		}
+		uses = append(uses, use{key: key, time: mi.ModTime(), size: size})
+		total += size
+	}
+
+	if t.MaxSize <= 0 {
+		return nil
+	}
+
+	sort.Slice(uses, func(i, j int) bool { return uses[i].time.Before(uses[j].time) })
+	for _, u := range uses {
+		if total <= t.MaxSize {
+			break
+		}
+		t.remove(u.key)
+		total -= u.size
+	}
+
+	return nil
+}
+
+// remove removes the metadata and then the body of the cache entry "key".
+func (t *Transport) remove(key string) {
+	for _, ext := range []string{metaExt, bodyExt} {
+		if err := os.Remove(filepath.Join(t.Dir, key+ext)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("remove: %v", err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a server of the "/etag" resource validated by an ETag
+// and the "/modified" resource validated by the Last-Modified header.
+// It counts all requests and the requests answered with 304.
+func newTestServer(requests *int32, notModified *int32) *httptest.Server {
+	modified := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		switch r.URL.Path {
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/modified":
+			if r.Header.Get("If-Modified-Since") == modified {
+				atomic.AddInt32(notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", modified)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("body of " + r.URL.Path))
+	}))
+}
+
+func Test_Transport(t *testing.T) {
+	var requests, notModified int32
+	srv := newTestServer(&requests, &notModified)
+	defer srv.Close()
+
+	tr := &Transport{Dir: filepath.Join(t.TempDir(), "cache"),
+		Immutable: func(u *url.URL) bool { return u.Query().Get("immutable") != "" }}
+	cl := http.Client{Transport: tr}
+
+	get := func(p string, wantCached bool) {
+		t.Helper()
+
+		resp, err := cl.Get(srv.URL + p)
+		if err != nil {
+			t.Fatalf("Test_Transport: %s: %v", p, err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Test_Transport: %s: %v", p, err)
+		}
+
+		u, _ := url.Parse(p)
+		if resp.StatusCode != http.StatusOK || string(b) != "body of "+u.Path || resp.Header.Get("Content-Type") != "text/html" {
+			t.Errorf("Test_Transport: %s: unexpected response: %d %q %v", p, resp.StatusCode, b, resp.Header)
+		}
+		if cached := resp.Header.Get(FromCacheHeader) != ""; cached != wantCached {
+			t.Errorf("Test_Transport: %s: want from cache: %v res: %v", p, wantCached, cached)
+		}
+	}
+
+	tests := []struct {
+		path        string
+		cached      bool
+		requests    int32
+		notModified int32
+	}{
+		{"/etag", false, 1, 0},
+		{"/etag", true, 2, 1},
+		{"/modified", false, 3, 1},
+		{"/modified", true, 4, 2},
+		{"/modified?immutable=1", false, 5, 2},
+		{"/modified?immutable=1", true, 5, 2}, //no request
+	}
+
+	for _, test := range tests {
+		get(test.path, test.cached)
+		if r, n := atomic.LoadInt32(&requests), atomic.LoadInt32(&notModified); r != test.requests || n != test.notModified {
+			t.Errorf("Test_Transport: %s: want requests: %d (not modified: %d) res: %d (%d)",
+				test.path, test.requests, test.notModified, r, n)
+		}
+	}
+
+	//not found resources are not cached
+	for i := 0; i < 2; i++ {
+		resp, err := cl.Get(srv.URL + "/missing")
+		if err != nil {
+			t.Fatalf("Test_Transport: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Test_Transport: want status: %d res: %d", http.StatusNotFound, resp.StatusCode)
+		}
+	}
+}
+
+func Test_Transport_Prune(t *testing.T) {
+	var requests, notModified int32
+	srv := newTestServer(&requests, &notModified)
+	defer srv.Close()
+
+	tr := &Transport{Dir: t.TempDir()}
+	cl := http.Client{Transport: tr}
+	for _, p := range []string{"/etag", "/modified", "/etag?n=1"} {
+		resp, err := cl.Get(srv.URL + p)
+		if err != nil {
+			t.Fatalf("Test_Transport_Prune: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	//"/modified" is the oldest entry, "/etag?n=1" is not used for an hour
+	u, _ := url.Parse(srv.URL + "/etag?n=1")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(tr.Dir, cacheKey(u)+metaExt), old, old); err != nil {
+		t.Fatalf("Test_Transport_Prune: %v", err)
+	}
+	u, _ = url.Parse(srv.URL + "/modified")
+	older := time.Now().Add(-30 * time.Minute)
+	if err := os.Chtimes(filepath.Join(tr.Dir, cacheKey(u)+metaExt), older, older); err != nil {
+		t.Fatalf("Test_Transport_Prune: %v", err)
+	}
+
+	//entries: count of entries, size: total size of bodies
+	count := func() (entries int, size int64) {
+		files, _ := os.ReadDir(tr.Dir)
+		for _, f := range files {
+			if filepath.Ext(f.Name()) == bodyExt {
+				entries++
+				if fi, err := f.Info(); err == nil {
+					size += fi.Size()
+				}
+			}
+		}
+		return entries, size
+	}
+
+	tr.MaxAge = 45 * time.Minute
+	if err := tr.Prune(); err != nil {
+		t.Fatalf("Test_Transport_Prune: %v", err)
+	}
+	if n, _ := count(); n != 2 {
+		t.Errorf("Test_Transport_Prune: want entries after pruning by age: 2 res: %d", n)
+	}
+
+	tr.MaxSize = int64(len("body of /etag"))
+	if err := tr.Prune(); err != nil {
+		t.Fatalf("Test_Transport_Prune: %v", err)
+	}
+	if n, size := count(); n != 1 || size > tr.MaxSize {
+		t.Errorf("Test_Transport_Prune: want entries after pruning by size: 1 res: %d (%d bytes)", n, size)
+	}
+}
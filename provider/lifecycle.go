@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+// LifecycleKind represents kinds of lifecycle events of watchers.
+type LifecycleKind string
+
+const (
+	// LifecycleStarted means that a watcher has been started.
+	LifecycleStarted LifecycleKind = "started"
+
+	// LifecycleCaughtUp means that a watcher has got the messages appeared
+	// before the current moment and watches new ones.
+	LifecycleCaughtUp LifecycleKind = "caught_up"
+
+	// LifecycleStalled means that a watcher cannot get messages for a while,
+	// e.g. it waits before retrying a failed request.
+	LifecycleStalled LifecycleKind = "stalled"
+
+	// LifecycleStopped means that a watcher has stopped, normally or because of an error
+	// (the state of the event is Stopped or Failed respectively).
+	LifecycleStopped LifecycleKind = "stopped"
+
+	// LifecycleError means that an error has happened, the watcher can keep watching.
+	LifecycleError LifecycleKind = "error"
+)
+
+// LifecycleEvent describes a change of the lifecycle of a watcher.
+type LifecycleEvent struct {
+	// WatcherId specifies the identifier of the watcher (its message source).
+	WatcherId string
+
+	Kind LifecycleKind
+
+	// State specifies the state of the watcher after the event.
+	State WatcherStateInfo
+
+	// Err specifies the error of the event, if any.
+	Err error
+
+	Time time.Time
+}
+
+// Observable represents a watcher publishing its lifecycle events.
+//
+// The interface is optional, use a type assertion to check whether a watcher implements it.
+type Observable interface {
+	// Subscribe returns a new channel receiving lifecycle events of the watcher
+	// and a function cancelling the subscription, which closes the channel.
+	// The channel has a buffer of "buf" events. If the buffer is full, the event
+	// is dropped for the subscriber, so a slow subscriber cannot stop the watcher.
+	Subscribe(buf int) (<-chan LifecycleEvent, func())
+}
+
+// Lifecycle keeps subscriptions to lifecycle events of a watcher and publishes
+// events to subscribers. It is safe for concurrent use. The zero value is ready to use.
+//
+// Watchers implement the Observable interface by means of Lifecycle.
+type Lifecycle struct {
+	mu   sync.Mutex
+	subs map[chan LifecycleEvent]struct{}
+}
+
+// Subscribe implements Observable.Subscribe.
+func (l *Lifecycle) Subscribe(buf int) (<-chan LifecycleEvent, func()) {
+	if buf < 0 {
+		buf = 0
+	}
+	ch := make(chan LifecycleEvent, buf)
+
+	l.mu.Lock()
+	if l.subs == nil {
+		l.subs = make(map[chan LifecycleEvent]struct{})
+	}
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			l.mu.Lock()
+			delete(l.subs, ch)
+			l.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// Publish sends the event "e" to all subscribers without blocking.
+// If the Time of the event is zero, it is set to the current time.
+func (l *Lifecycle) Publish(e LifecycleEvent) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ch := range l.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Transit publishes the events corresponding to the transition of the watcher "id"
+// from the state "from" to the state "to". The error "err" is attached to the events
+// of stopping and stalling. Transitions between the same states publish nothing.
+//
+// A transition into an active state from an inactive one is LifecycleStarted,
+// from Starting or CatchingUp into Run is LifecycleCaughtUp, into Backoff is LifecycleStalled,
+// into Stopped or Failed is LifecycleStopped.
+func (l *Lifecycle) Transit(id string, from WatcherStateInfo, to WatcherStateInfo, err error) {
+	if from == to {
+		return
+	}
+
+	e := LifecycleEvent{WatcherId: id, State: to}
+	switch {
+	case !to.Active():
+		e.Kind, e.Err = LifecycleStopped, err
+	case !from.Active():
+		e.Kind = LifecycleStarted
+	case to == Backoff:
+		e.Kind, e.Err = LifecycleStalled, err
+	case to == Run && (from == Starting || from == CatchingUp):
+		e.Kind = LifecycleCaughtUp
+	default:
+		return
+	}
+
+	l.Publish(e)
+}
+
+// Failed publishes a LifecycleError event of the watcher "id" in the state "s".
+func (l *Lifecycle) Failed(id string, s WatcherStateInfo, err error) {
+	l.Publish(LifecycleEvent{WatcherId: id, Kind: LifecycleError, State: s, Err: err})
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Lifecycle_Transit(t *testing.T) {
+	errFetch := errors.New("fetch")
+
+	tests := []struct {
+		from, to WatcherStateInfo
+		want     LifecycleKind
+		wantErr  error
+	}{
+		{Stopped, Starting, LifecycleStarted, nil},
+		{Failed, Run, LifecycleStarted, nil},
+		{Starting, CatchingUp, "", nil},
+		{CatchingUp, Run, LifecycleCaughtUp, nil},
+		{Run, Backoff, LifecycleStalled, errFetch},
+		{Backoff, Run, "", nil},
+		{Run, Stopped, LifecycleStopped, errFetch},
+		{Starting, Failed, LifecycleStopped, errFetch},
+		{Run, Run, "", nil},
+	}
+
+	var l Lifecycle
+	events, cancel := l.Subscribe(len(tests))
+	defer cancel()
+
+	for _, test := range tests {
+		l.Transit("w", test.from, test.to, errFetch)
+
+		var e LifecycleEvent
+		select {
+		case e = <-events:
+		default:
+		}
+
+		if e.Kind != test.want {
+			t.Errorf("Test_Lifecycle_Transit: %s -> %s: want: %q, result: %q", test.from, test.to, test.want, e.Kind)
+			continue
+		}
+		if e.Kind != "" && (e.WatcherId != "w" || e.State != test.to || e.Err != test.wantErr || e.Time.IsZero()) {
+			t.Errorf("Test_Lifecycle_Transit: %s -> %s: unexpected event: %+v", test.from, test.to, e)
+		}
+	}
+}
+
+func Test_Lifecycle_Subscribe(t *testing.T) {
+	var l Lifecycle
+	full, cancelFull := l.Subscribe(1)
+	other, cancelOther := l.Subscribe(4)
+
+	//the full subscription does not block publishing
+	for i := 0; i < 3; i++ {
+		l.Failed("w", Run, nil)
+	}
+
+	if n := len(full); n != 1 {
+		t.Errorf("Test_Lifecycle_Subscribe: want 1 event in the full subscription, result: %d", n)
+	}
+	if n := len(other); n != 3 {
+		t.Errorf("Test_Lifecycle_Subscribe: want 3 events, result: %d", n)
+	}
+
+	cancelFull()
+	cancelFull()
+	for range full {
+	}
+
+	l.Failed("w", Run, nil)
+	cancelOther()
+	n := 0
+	for range other {
+		n++
+	}
+	if n != 4 {
+		t.Errorf("Test_Lifecycle_Subscribe: want 4 events, result: %d", n)
+	}
+}
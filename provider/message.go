@@ -1,43 +1,24 @@
 package provider
 
 import (
-	"math/rand"
 	"time"
 )
 
-const (
-	//EventType values
+// Action represents the action with an event, that a message reports.
+type Action string
 
-	UnknownType EventType = 0
-	EarthQuake  EventType = 1
-	QuarryBlast EventType = 2
+const (
+	// Create reports a new event.
+	Create Action = "create"
 
-	//Quality values
+	// Update reports a revision of a previously reported event.
+	Update Action = "update"
 
-	UnknownQuality EventQuality = 0
-	Preliminary    EventQuality = 1
-	Good           EventQuality = 2
-	Excellent      EventQuality = 3
+	// Retract reports that a previously reported event has been deleted
+	// (rejected) by the source.
+	Retract Action = "retract"
 )
 
-// EventType represents the type of a sesmic event.
-type EventType int
-
-// RandEventType creates a random value of the EventType type.
-func RandEventType() EventType {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return EventType(r.Intn(int(QuarryBlast)))
-}
-
-// EventQuality represents quality of a seismic event assessment.
-type EventQuality int
-
-// RandEventQuality creates a random value of the EventQuality type.
-func RandEventQuality() EventQuality {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return EventQuality(r.Intn(int(Excellent)))
-}
-
 // Message contains common information about a seismic event.
 type Message struct {
 	// SourceId specifies the string identifier of the message source,
@@ -69,4 +50,147 @@ type Message struct {
 	// Link specifies a url of the message, i.e. an address,
 	// from which the message can be fetched again. Optional.
 	Link string `json:"link" bson:"link"`
+
+	// Depth specifies the hypocentral depth of the event in kilometres.
+	// Optional.
+	Depth *float64 `json:"depth,omitempty" bson:"depth,omitempty"`
+
+	// MagType specifies the magnitude type, e.g. ML, mb, Ms, Mw. Optional.
+	MagType string `json:"mag_type,omitempty" bson:"mag_type,omitempty"`
+
+	// TimeUncertainty specifies the origin time uncertainty in seconds.
+	// Optional.
+	TimeUncertainty *float64 `json:"time_uncertainty,omitempty" bson:"time_uncertainty,omitempty"`
+
+	// LatitudeUncertainty specifies the epicenter latitude uncertainty
+	// in degrees. Optional.
+	LatitudeUncertainty *float64 `json:"latitude_uncertainty,omitempty" bson:"latitude_uncertainty,omitempty"`
+
+	// LongitudeUncertainty specifies the epicenter longitude uncertainty
+	// in degrees. Optional.
+	LongitudeUncertainty *float64 `json:"longitude_uncertainty,omitempty" bson:"longitude_uncertainty,omitempty"`
+
+	// DepthUncertainty specifies the depth uncertainty in kilometres.
+	// Optional.
+	DepthUncertainty *float64 `json:"depth_uncertainty,omitempty" bson:"depth_uncertainty,omitempty"`
+
+	// StationCount specifies the number of stations used to locate
+	// the event. Optional.
+	StationCount *int `json:"station_count,omitempty" bson:"station_count,omitempty"`
+
+	// PhaseCount specifies the number of phases used to locate
+	// the event. Optional.
+	PhaseCount *int `json:"phase_count,omitempty" bson:"phase_count,omitempty"`
+
+	// AzimuthalGap specifies the largest azimuthal gap between stations
+	// in degrees. Optional.
+	AzimuthalGap *float64 `json:"azimuthal_gap,omitempty" bson:"azimuthal_gap,omitempty"`
+
+	// Author specifies the author of the solution. Optional.
+	Author string `json:"author,omitempty" bson:"author,omitempty"`
+
+	// Agency specifies the agency which issued the solution. Optional.
+	Agency string `json:"agency,omitempty" bson:"agency,omitempty"`
+
+	// Extra holds source specific attributes which have no dedicated
+	// field. Optional.
+	Extra map[string]string `json:"extra,omitempty" bson:"extra,omitempty"`
+
+	// Action specifies the action with the event. An empty value means
+	// that the source does not distinguish creations and updates,
+	// such messages are handled as updates if the event is already known.
+	Action Action `json:"action,omitempty" bson:"action,omitempty"`
+
+	// Version specifies the revision number of the event solution
+	// assigned by the source. Zero means unknown. Optional.
+	Version int `json:"version,omitempty" bson:"version,omitempty"`
+
+	// UpdateTime specifies the UTC-time the event solution was created
+	// or revised by the source. The zero value means unknown. Optional.
+	// In JSON the zero value is encoded as "0001-01-01T00:00:00Z" (omitempty
+	// does not omit structs), in BSON the field is omitted.
+	UpdateTime time.Time `json:"update_time" bson:"update_time,omitempty"`
+}
+
+// Supersedes reports whether m must replace "prev", a message about
+// the same event (SourceId and EventId) received earlier.
+//
+// Versions are compared if both messages have them, otherwise
+// update times are compared if both messages have them.
+// If the messages cannot be ordered this way, the latest received
+// message wins, i.e. the method returns true.
+func (m Message) Supersedes(prev Message) bool {
+	if m.Version != 0 && prev.Version != 0 && m.Version != prev.Version {
+		return m.Version > prev.Version
+	}
+
+	if !m.UpdateTime.IsZero() && !prev.UpdateTime.IsZero() && !m.UpdateTime.Equal(prev.UpdateTime) {
+		return m.UpdateTime.After(prev.UpdateTime)
+	}
+
+	return true
+}
+
+// Equal reports whether m and u describe the same data.
+// Optional fields are compared by value, FocusTime is compared
+// by time.Time.Equal.
+func (m Message) Equal(u Message) bool {
+	if m.SourceId != u.SourceId ||
+		!m.FocusTime.Equal(u.FocusTime) ||
+		m.Latitude != u.Latitude ||
+		m.Longitude != u.Longitude ||
+		m.Magnitude != u.Magnitude ||
+		m.EventId != u.EventId ||
+		m.Type != u.Type ||
+		m.Quality != u.Quality ||
+		m.Link != u.Link ||
+		m.MagType != u.MagType ||
+		m.Author != u.Author ||
+		m.Agency != u.Agency ||
+		m.Action != u.Action ||
+		m.Version != u.Version ||
+		!m.UpdateTime.Equal(u.UpdateTime) {
+		return false
+	}
+
+	if !equalPtr(m.Depth, u.Depth) ||
+		!equalPtr(m.TimeUncertainty, u.TimeUncertainty) ||
+		!equalPtr(m.LatitudeUncertainty, u.LatitudeUncertainty) ||
+		!equalPtr(m.LongitudeUncertainty, u.LongitudeUncertainty) ||
+		!equalPtr(m.DepthUncertainty, u.DepthUncertainty) ||
+		!equalPtr(m.StationCount, u.StationCount) ||
+		!equalPtr(m.PhaseCount, u.PhaseCount) ||
+		!equalPtr(m.AzimuthalGap, u.AzimuthalGap) {
+		return false
+	}
+
+	if len(m.Extra) != len(u.Extra) {
+		return false
+	}
+	for k, v := range m.Extra {
+		if w, ok := u.Extra[k]; !ok || w != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Float returns a pointer to v. It is a helper for filling
+// optional numeric fields of Message.
+func Float(v float64) *float64 {
+	return &v
+}
+
+// Int returns a pointer to v. It is a helper for filling
+// optional integer fields of Message.
+func Int(v int) *int {
+	return &v
 }
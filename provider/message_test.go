@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fullMessage() Message {
+	return Message{
+		SourceId:  "fdsn_1",
+		FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC),
+		Latitude:  54.71,
+		Longitude: 83.67,
+		Magnitude: 3.3,
+		EventId:   "gfz2023eesfwx",
+		Type:      QuarryBlast,
+		Quality:   Good,
+		Link:      "https://geofon.gfz-potsdam.de/eqinfo/event.php?id=gfz2023eesfwx",
+
+		Depth:                Float(10),
+		MagType:              "mb",
+		TimeUncertainty:      Float(0.12),
+		LatitudeUncertainty:  Float(0.017),
+		LongitudeUncertainty: Float(0.021),
+		DepthUncertainty:     Float(2.3),
+		StationCount:         Int(23),
+		PhaseCount:           Int(41),
+		AzimuthalGap:         Float(74.5),
+		Author:               "scautoloc",
+		Agency:               "GFZ",
+		Extra:                map[string]string{"region": "Southwestern Siberia, Russia"},
+
+		Action:     Update,
+		Version:    2,
+		UpdateTime: time.Date(2023, 3, 1, 5, 41, 12, 0, time.UTC),
+	}
+}
+
+func Test_Message_JSON(t *testing.T) {
+	for _, want := range []Message{fullMessage(), {SourceId: "pseudo_1", EventId: "1"}} {
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("Test_Message_JSON: %v", err)
+		}
+
+		var res Message
+		if err := json.Unmarshal(b, &res); err != nil {
+			t.Fatalf("Test_Message_JSON: %v", err)
+		}
+
+		if !res.Equal(want) {
+			t.Errorf("Test_Message_JSON: \n\twant: %v\n\tres: %v", want, res)
+		}
+	}
+
+	//optional fields are omitted, so messages without them keep the former form
+	b, err := json.Marshal(Message{SourceId: "pseudo_1"})
+	if err != nil {
+		t.Fatalf("Test_Message_JSON: %v", err)
+	}
+	for _, k := range []string{"depth", "mag_type", "station_count", "extra"} {
+		if strings.Contains(string(b), `"`+k+`"`) {
+			t.Errorf("Test_Message_JSON: %q key is not omitted: %s", k, b)
+		}
+	}
+
+	//the unknown update time is the zero time
+	if !strings.Contains(string(b), `"update_time":"0001-01-01T00:00:00Z"`) {
+		t.Errorf("Test_Message_JSON: unexpected update time: %s", b)
+	}
+}
+
+func Test_Message_Equal(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Message)
+		want   bool
+	}{
+		{"same", func(m *Message) {}, true},
+		{"same pointees", func(m *Message) { m.Depth = Float(10) }, true},
+		{"same time in another location", func(m *Message) { m.FocusTime = m.FocusTime.In(time.FixedZone("NOVT", 7*3600)) }, true},
+		{"magnitude", func(m *Message) { m.Magnitude = 3.4 }, false},
+		{"depth", func(m *Message) { m.Depth = Float(11) }, false},
+		{"no depth", func(m *Message) { m.Depth = nil }, false},
+		{"station count", func(m *Message) { m.StationCount = nil }, false},
+		{"agency", func(m *Message) { m.Agency = "" }, false},
+		{"extra value", func(m *Message) { m.Extra = map[string]string{"region": "Altai"} }, false},
+		{"extra key", func(m *Message) { m.Extra = map[string]string{"place": "Southwestern Siberia, Russia"} }, false},
+		{"no extra", func(m *Message) { m.Extra = nil }, false},
+		{"action", func(m *Message) { m.Action = Retract }, false},
+		{"version", func(m *Message) { m.Version = 3 }, false},
+		{"update time", func(m *Message) { m.UpdateTime = time.Time{} }, false},
+	}
+
+	for _, test := range tests {
+		m := fullMessage()
+		test.change(&m)
+		if res := fullMessage().Equal(m); res != test.want {
+			t.Errorf("Test_Message_Equal: %s: want: %v res: %v", test.name, test.want, res)
+		}
+	}
+}
+
+func Test_Message_Supersedes(t *testing.T) {
+	t1 := time.Date(2023, 3, 1, 5, 20, 1, 0, time.UTC)
+	t2 := time.Date(2023, 3, 1, 5, 41, 12, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		m    Message
+		prev Message
+		want bool
+	}{
+		{"greater version", Message{Version: 2}, Message{Version: 1}, true},
+		{"less version", Message{Version: 1, UpdateTime: t2}, Message{Version: 2, UpdateTime: t1}, false},
+		{"later update", Message{UpdateTime: t2}, Message{UpdateTime: t1}, true},
+		{"earlier update", Message{UpdateTime: t1}, Message{UpdateTime: t2}, false},
+		{"same version, later update", Message{Version: 2, UpdateTime: t2}, Message{Version: 2, UpdateTime: t1}, true},
+		{"unknown version", Message{UpdateTime: t1}, Message{Version: 2, UpdateTime: t2}, false},
+		{"unordered", Message{Version: 1}, Message{UpdateTime: t2}, true},
+		{"retraction", Message{Action: Retract}, Message{Version: 2, UpdateTime: t2}, true},
+		{"same", Message{Version: 2, UpdateTime: t2}, Message{Version: 2, UpdateTime: t2}, true},
+	}
+
+	for _, test := range tests {
+		if res := test.m.Supersedes(test.prev); res != test.want {
+			t.Errorf("Test_Message_Supersedes: %s: want: %v res: %v", test.name, test.want, res)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"seismo/provider/fetch"
+	"strconv"
+	"strings"
+)
+
+// DecodeOptions decodes the raw options block of a watcher configuration (WatcherConfig.Options)
+// into "v", a pointer to an options struct of the provider. Fields absent in the block
+// keep the values set in "v" before the call, so "v" can be filled with defaults.
+// An empty or null block leaves "v" unchanged.
+//
+// A field unknown to the options struct is an error, the returned error
+// wraps UnknownOptionErr in such case.
+func DecodeOptions(raw json.RawMessage, v interface{}) error {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(v); err != nil {
+		if f, ok := unknownField(err); ok {
+			return fmt.Errorf("DecodeOptions: %w", UnknownOptionErr{Field: f})
+		}
+		return fmt.Errorf("DecodeOptions: %w", err)
+	}
+
+	if d.More() {
+		return fmt.Errorf("DecodeOptions: unexpected data after the options object")
+	}
+
+	return nil
+}
+
+// NoOptions checks the raw options block of a watcher configuration of a provider
+// which has no options, i.e. any field of the block is unknown (see DecodeOptions).
+func NoOptions(raw json.RawMessage) error {
+	if err := DecodeOptions(raw, &struct{}{}); err != nil {
+		return fmt.Errorf("NoOptions: %w", err)
+	}
+	return nil
+}
+
+// unknownField extracts the field name from an error of json.Decoder
+// caused by DisallowUnknownFields. The json package has no error type for it.
+func unknownField(err error) (string, bool) {
+	const prefix = "json: unknown field "
+
+	s := err.Error()
+	if !strings.HasPrefix(s, prefix) {
+		return "", false
+	}
+
+	f, uerr := strconv.Unquote(s[len(prefix):])
+	if uerr != nil {
+		return s[len(prefix):], true
+	}
+
+	return f, true
+}
+
+// UnknownOptionErr indicates that the options block of a watcher configuration
+// contains a field not supported by the provider.
+type UnknownOptionErr struct {
+	Field string
+}
+
+func (e UnknownOptionErr) Error() string {
+	return fmt.Sprintf("unknown option: %q", e.Field)
+}
+
+// HTTPOptions contains options common to providers fetching messages over HTTP.
+// Provider options structs embed it.
+type HTTPOptions struct {
+	// UserAgent specifies the User-Agent header of requests. Optional.
+	UserAgent string `json:"user_agent"`
+
+	// Headers specifies additional headers of requests, e.g. an API key. Optional.
+	Headers map[string]string `json:"headers"`
+
+	// Retries specifies max number of retries of a request failed because of a transient
+	// error (see fetch.Transport). If it is 0, fetch.DefRetries is used, if it is negative,
+	// requests are not retried.
+	Retries int `json:"retries"`
+
+	// RateLimit specifies max number of requests per second to a host shared by all watchers
+	// sending requests to the host (the least limit is applied). If it is 0, the rate is not limited.
+	RateLimit float64 `json:"rate_limit"`
+
+	// RateBurst specifies the number of requests to a host which can be sent at once
+	// within the RateLimit. If it is 0, one request can be sent at once.
+	RateBurst int `json:"rate_burst"`
+}
+
+// Header returns the headers specified by the options.
+func (o HTTPOptions) Header() http.Header {
+	h := make(http.Header, len(o.Headers)+1)
+	for k, v := range o.Headers {
+		h.Set(k, v)
+	}
+	if o.UserAgent != "" {
+		h.Set("User-Agent", o.UserAgent)
+	}
+
+	return h
+}
+
+// Fetcher returns the shared fetch layer (see fetch.Transport) sending requests
+// with http.DefaultTransport, retrying and limiting them as specified by the options.
+func (o HTTPOptions) Fetcher() http.RoundTripper {
+	retries := o.Retries
+	switch {
+	case retries == 0:
+		retries = fetch.DefRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	return &fetch.Transport{Retries: retries, Rate: o.RateLimit, Burst: o.RateBurst}
+}
+
+// Transport returns a round tripper setting the headers specified by the options
+// into requests sent by "base". If no headers are specified, "base" is returned.
+// If "base" is nil, the shared fetch layer is used (see Fetcher).
+func (o HTTPOptions) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = o.Fetcher()
+	}
+
+	h := o.Header()
+	if len(h) == 0 {
+		return base
+	}
+
+	return headerTransport{header: h, base: base}
+}
+
+// headerTransport sets headers into requests sent by the base round tripper.
+type headerTransport struct {
+	header http.Header
+	base   http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	//a round tripper should not modify the request
+	r := req.Clone(req.Context())
+	for k, v := range t.header {
+		r.Header[k] = v
+	}
+
+	return t.base.RoundTrip(r)
+}
+
+// FilterOptions contains options limiting messages sent by a watcher
+// by the magnitude and the region. Provider options structs embed it.
+// All fields are optional.
+type FilterOptions struct {
+	// MinMagnitude specifies the minimal magnitude of sent messages.
+	MinMagnitude *float64 `json:"min_magnitude"`
+
+	// MinLatitude, MaxLatitude, MinLongitude and MaxLongitude specify the region of sent messages.
+	// If MinLongitude is greater than MaxLongitude, the region crosses the antimeridian.
+	MinLatitude  *float64 `json:"min_latitude"`
+	MaxLatitude  *float64 `json:"max_latitude"`
+	MinLongitude *float64 `json:"min_longitude"`
+	MaxLongitude *float64 `json:"max_longitude"`
+}
+
+// Validate checks that the values of the options are in their ranges.
+func (f FilterOptions) Validate() error {
+	for _, b := range []struct {
+		name     string
+		v        *float64
+		min, max float64
+	}{
+		{"min_magnitude", f.MinMagnitude, MinMagnitude, MaxMagnitude},
+		{"min_latitude", f.MinLatitude, -90, 90},
+		{"max_latitude", f.MaxLatitude, -90, 90},
+		{"min_longitude", f.MinLongitude, -180, 180},
+		{"max_longitude", f.MaxLongitude, -180, 180},
+	} {
+		if b.v != nil && !inRange(*b.v, b.min, b.max) {
+			return fmt.Errorf("Validate: %s: %v is out of range [%v, %v]", b.name, *b.v, b.min, b.max)
+		}
+	}
+
+	if f.MinLatitude != nil && f.MaxLatitude != nil && *f.MinLatitude > *f.MaxLatitude {
+		return fmt.Errorf("Validate: min_latitude %v is greater than max_latitude %v", *f.MinLatitude, *f.MaxLatitude)
+	}
+
+	return nil
+}
+
+// Match reports whether the message "m" satisfies the options.
+func (f FilterOptions) Match(m Message) bool {
+	if f.MinMagnitude != nil && m.Magnitude < *f.MinMagnitude {
+		return false
+	}
+	if f.MinLatitude != nil && m.Latitude < *f.MinLatitude {
+		return false
+	}
+	if f.MaxLatitude != nil && m.Latitude > *f.MaxLatitude {
+		return false
+	}
+
+	switch {
+	case f.MinLongitude != nil && f.MaxLongitude != nil && *f.MinLongitude > *f.MaxLongitude:
+		return m.Longitude >= *f.MinLongitude || m.Longitude <= *f.MaxLongitude
+	case f.MinLongitude != nil && m.Longitude < *f.MinLongitude:
+		return false
+	case f.MaxLongitude != nil && m.Longitude > *f.MaxLongitude:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"seismo/provider/fetch"
+	"testing"
+)
+
+func Test_DecodeOptions(t *testing.T) {
+	type options struct {
+		HTTPOptions
+		Limit int `json:"limit"`
+	}
+
+	var o options
+	if err := DecodeOptions(nil, &o); err != nil {
+		t.Errorf("Test_DecodeOptions: empty block: unexpected error: %v", err)
+	}
+
+	o = options{Limit: 10}
+	err := DecodeOptions(json.RawMessage(`{"user_agent": "seismo", "headers": {"X-Api-Key": "k"}}`), &o)
+	if err != nil {
+		t.Fatalf("Test_DecodeOptions: unexpected error: %v", err)
+	}
+	if o.UserAgent != "seismo" || o.Headers["X-Api-Key"] != "k" || o.Limit != 10 {
+		t.Errorf("Test_DecodeOptions: unexpected options (the default limit must be kept): %+v", o)
+	}
+
+	err = DecodeOptions(json.RawMessage(`{"limit": 1, "min_mag": 3}`), &o)
+	var ue UnknownOptionErr
+	if !errors.As(err, &ue) || ue.Field != "min_mag" {
+		t.Errorf("Test_DecodeOptions: want UnknownOptionErr of the \"min_mag\" field, result: %v", err)
+	}
+
+	if err := DecodeOptions(json.RawMessage(`{"limit": "1"}`), &o); err == nil || errors.As(err, &ue) {
+		t.Errorf("Test_DecodeOptions: want a type error, result: %v", err)
+	}
+
+	if err := DecodeOptions(json.RawMessage(`{"user_agent": "a"}`), &struct{}{}); !errors.As(err, &ue) {
+		t.Errorf("Test_DecodeOptions: no options: want UnknownOptionErr, result: %v", err)
+	}
+}
+
+func Test_NoOptions(t *testing.T) {
+	for _, raw := range []json.RawMessage{nil, json.RawMessage(`{}`), json.RawMessage(" null ")} {
+		if err := NoOptions(raw); err != nil {
+			t.Errorf("Test_NoOptions: %q: unexpected error: %v", raw, err)
+		}
+	}
+
+	var ue UnknownOptionErr
+	if err := NoOptions(json.RawMessage(`{"user_agent": "a"}`)); !errors.As(err, &ue) || ue.Field != "user_agent" {
+		t.Errorf("Test_NoOptions: want UnknownOptionErr of the \"user_agent\" field, result: %v", err)
+	}
+}
+
+func Test_HTTPOptions_Transport(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer srv.Close()
+
+	if tr, ok := (HTTPOptions{}).Transport(nil).(*fetch.Transport); !ok || tr.Retries != fetch.DefRetries || tr.Rate != 0 {
+		t.Errorf("Test_HTTPOptions_Transport: want the fetch layer with default retries for empty options")
+	}
+	if tr, ok := (HTTPOptions{Retries: -1, RateLimit: 2}).Transport(nil).(*fetch.Transport); !ok || tr.Retries != 0 || tr.Rate != 2 {
+		t.Errorf("Test_HTTPOptions_Transport: want the fetch layer without retries")
+	}
+	if tr := (HTTPOptions{}).Transport(http.DefaultTransport); tr != http.DefaultTransport {
+		t.Errorf("Test_HTTPOptions_Transport: want the base transport for empty options")
+	}
+
+	o := HTTPOptions{UserAgent: "seismo/1.0", Headers: map[string]string{"x-api-key": "secret"}}
+	cl := http.Client{Transport: o.Transport(nil)}
+	resp, err := cl.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Test_HTTPOptions_Transport: %v", err)
+	}
+	resp.Body.Close()
+
+	if header.Get("User-Agent") != "seismo/1.0" || header.Get("X-Api-Key") != "secret" {
+		t.Errorf("Test_HTTPOptions_Transport: unexpected request headers: %v", header)
+	}
+}
+
+func Test_FilterOptions(t *testing.T) {
+	m := Message{Latitude: 54, Longitude: 179, Magnitude: 3}
+
+	cases := []struct {
+		name  string
+		f     FilterOptions
+		valid bool
+		match bool
+	}{
+		{"empty", FilterOptions{}, true, true},
+		{"magnitude", FilterOptions{MinMagnitude: Float(3.5)}, true, false},
+		{"region", FilterOptions{MinLatitude: Float(50), MaxLatitude: Float(60), MinLongitude: Float(170), MaxLongitude: Float(180)}, true, true},
+		{"antimeridian", FilterOptions{MinLongitude: Float(170), MaxLongitude: Float(-170)}, true, true},
+		{"out of antimeridian", FilterOptions{MinLongitude: Float(179.5), MaxLongitude: Float(-170)}, true, false},
+		{"latitude range", FilterOptions{MinLatitude: Float(-91)}, false, true},
+		{"latitude order", FilterOptions{MinLatitude: Float(60), MaxLatitude: Float(50)}, false, false},
+	}
+
+	for _, c := range cases {
+		if err := c.f.Validate(); (err == nil) != c.valid {
+			t.Errorf("Test_FilterOptions: %s: want valid: %v, result: %v", c.name, c.valid, err)
+		}
+		if c.f.Match(m) != c.match {
+			t.Errorf("Test_FilterOptions: %s: want match: %v", c.name, c.match)
+		}
+	}
+}
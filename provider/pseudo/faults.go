@@ -0,0 +1,188 @@
+package pseudo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"seismo/provider"
+	"time"
+)
+
+// FaultConfig specifies faults injected into the messages of a Hub for resilience testing.
+// Every rate is a probability (from 0 to 1) of the fault for a message.
+// A zero rate disables the fault.
+type FaultConfig struct {
+	// Duplicate specifies the rate of sending a message twice.
+	Duplicate float64 `json:"duplicate"`
+
+	// OutOfOrder specifies the rate of holding a message back and sending it after the next one.
+	OutOfOrder float64 `json:"out_of_order"`
+
+	// Delay specifies the rate of delaying a message for a random period up to MaxDelay seconds.
+	Delay    float64 `json:"delay"`
+	MaxDelay float64 `json:"max_delay"`
+
+	// Close specifies the rate of closing the message channel instead of sending a message,
+	// i.e. the watcher stops suddenly.
+	Close float64 `json:"close"`
+
+	// Malformed specifies the rate of corrupting values of a message
+	// (NaN coordinates, impossible magnitudes etc).
+	Malformed float64 `json:"malformed"`
+
+	// Stall specifies the rate of stalling for StallPeriod seconds before sending a message,
+	// while the watcher is still running.
+	Stall       float64 `json:"stall"`
+	StallPeriod float64 `json:"stall_period"`
+
+	// Seed specifies the seed of the random generator. If Seed is 0, the current time is used.
+	Seed int64 `json:"seed"`
+}
+
+// faultInjector injects faults specified by a FaultConfig
+// into a stream of messages.
+type faultInjector struct {
+	conf FaultConfig
+	rng  *rand.Rand
+
+	//held contains a message held back to be sent out of order
+	held *provider.Message
+
+	//sent is called after sending every message, if it is not nil
+	sent func()
+}
+
+func newFaultInjector(conf FaultConfig) *faultInjector {
+	seed := conf.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &faultInjector{conf: conf, rng: rand.New(rand.NewSource(seed))}
+}
+
+// validateFaults checks values of a fault configuration.
+func validateFaults(conf FaultConfig) error {
+	rates := map[string]float64{
+		"duplicate": conf.Duplicate, "out_of_order": conf.OutOfOrder, "delay": conf.Delay,
+		"close": conf.Close, "malformed": conf.Malformed, "stall": conf.Stall,
+	}
+
+	for name, r := range rates {
+		if r < 0 || r > 1 {
+			return fmt.Errorf("validateFaults: the %s rate must be from 0 to 1", name)
+		}
+	}
+
+	if conf.MaxDelay < 0 || conf.StallPeriod < 0 {
+		return fmt.Errorf("validateFaults: max_delay and stall_period cannot be negative")
+	}
+
+	return nil
+}
+
+// happen reports whether a fault with the rate "r" happens.
+func (f *faultInjector) happen(r float64) bool {
+	return r > 0 && f.rng.Float64() < r
+}
+
+// run reads messages from "in", injects faults and sends the messages into "o".
+// The method returns when "in" is closed, the context is canceled or
+// the channel closure fault happens. A message held back is dropped in the last
+// two cases, so it does not leak into the next watching session.
+func (f *faultInjector) run(ctx context.Context, in <-chan provider.Message, o chan<- provider.Message) {
+	defer func() {
+		f.held = nil
+	}()
+
+	for m := range in {
+		if f.happen(f.conf.Close) {
+			log.Println("faultInjector: closing the channel")
+			return
+		}
+
+		if f.happen(f.conf.Stall) && !f.sleep(ctx, f.conf.StallPeriod) {
+			return
+		}
+
+		if f.happen(f.conf.Delay) && !f.sleep(ctx, f.rng.Float64()*f.conf.MaxDelay) {
+			return
+		}
+
+		if f.happen(f.conf.Malformed) {
+			f.corrupt(&m)
+		}
+
+		if f.held == nil && f.happen(f.conf.OutOfOrder) {
+			h := m
+			f.held = &h
+			continue
+		}
+
+		n := 1
+		if f.happen(f.conf.Duplicate) {
+			n = 2
+		}
+
+		for i := 0; i < n; i++ {
+			if !f.send(ctx, o, m) {
+				return
+			}
+		}
+
+		if f.held != nil {
+			h := *f.held
+			f.held = nil
+			if !f.send(ctx, o, h) {
+				return
+			}
+		}
+	}
+
+	if f.held != nil {
+		f.send(ctx, o, *f.held)
+	}
+}
+
+// corrupt sets a random value of "m" to a malformed one.
+func (f *faultInjector) corrupt(m *provider.Message) {
+	switch f.rng.Intn(5) {
+	case 0:
+		m.Latitude = math.NaN()
+	case 1:
+		m.Longitude = math.NaN()
+	case 2:
+		m.Latitude = 90 + f.rng.Float64()*100
+	case 3:
+		m.Magnitude = 12 + f.rng.Float64()*10
+	default:
+		m.Magnitude = -5 - f.rng.Float64()*10
+	}
+}
+
+// sleep waits for "sec" seconds and reports whether the context is still active.
+func (f *faultInjector) sleep(ctx context.Context, sec float64) bool {
+	t := time.NewTimer(time.Duration(sec * float64(time.Second)))
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (f *faultInjector) send(ctx context.Context, o chan<- provider.Message, m provider.Message) bool {
+	select {
+	case o <- m:
+		if f.sent != nil {
+			f.sent()
+		}
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,179 @@
+package pseudo
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"seismo/provider"
+	"testing"
+	"time"
+)
+
+// injectFaults passes messages with the magnitudes 1..n through a fault injector
+// and returns the magnitudes of the result messages.
+func injectFaults(t *testing.T, conf FaultConfig, n int) []provider.Message {
+	in := make(chan provider.Message)
+	o := make(chan provider.Message, 2*n)
+
+	go func() {
+		defer close(in)
+		for i := 1; i <= n; i++ {
+			in <- provider.Message{EventId: "id", Latitude: 50, Longitude: 80, Magnitude: float64(i)}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	newFaultInjector(conf).run(ctx, in, o)
+	cancel()
+	for range in {
+	}
+	close(o)
+
+	var res []provider.Message
+	for m := range o {
+		res = append(res, m)
+	}
+
+	return res
+}
+
+// faultOptions returns an options block of a watcher configuration injecting the faults "fc".
+func faultOptions(fc FaultConfig) json.RawMessage {
+	b, _ := json.Marshal(Options{Faults: &fc})
+	return b
+}
+
+func magnitudes(msgs []provider.Message) []float64 {
+	res := make([]float64, 0, len(msgs))
+	for _, m := range msgs {
+		res = append(res, m.Magnitude)
+	}
+	return res
+}
+
+func Test_faultInjector(t *testing.T) {
+	tests := []struct {
+		name string
+		conf FaultConfig
+		want []float64
+	}{
+		{"none", FaultConfig{}, []float64{1, 2, 3, 4}},
+		{"duplicate", FaultConfig{Duplicate: 1}, []float64{1, 1, 2, 2, 3, 3, 4, 4}},
+		{"out of order", FaultConfig{OutOfOrder: 1}, []float64{2, 1, 4, 3}},
+		{"close", FaultConfig{Close: 1}, []float64{}},
+		{"delay", FaultConfig{Delay: 1, MaxDelay: 0.01}, []float64{1, 2, 3, 4}},
+		{"stall", FaultConfig{Stall: 1, StallPeriod: 0.01}, []float64{1, 2, 3, 4}},
+	}
+
+	for _, test := range tests {
+		res := magnitudes(injectFaults(t, test.conf, 4))
+		if len(res) != len(test.want) {
+			t.Errorf("Test_faultInjector: %s: want: %v, result: %v", test.name, test.want, res)
+			continue
+		}
+		for i := range res {
+			if res[i] != test.want[i] {
+				t.Errorf("Test_faultInjector: %s: want: %v, result: %v", test.name, test.want, res)
+				break
+			}
+		}
+	}
+}
+
+func Test_faultInjector_Held(t *testing.T) {
+	f := newFaultInjector(FaultConfig{Close: 1})
+	f.held = &provider.Message{Magnitude: 1}
+
+	//session sends the message with the magnitude "mag" through the injector
+	session := func(mag float64) []float64 {
+		in := make(chan provider.Message, 1)
+		o := make(chan provider.Message, 2)
+		in <- provider.Message{Magnitude: mag}
+		close(in)
+
+		f.run(context.Background(), in, o)
+		close(o)
+
+		var res []provider.Message
+		for m := range o {
+			res = append(res, m)
+		}
+		return magnitudes(res)
+	}
+
+	if res := session(2); len(res) != 0 || f.held != nil {
+		t.Errorf("Test_faultInjector_Held: the held message is kept after closing: %v %v", res, f.held)
+	}
+
+	//the message held in the closed session is not sent in the next one
+	f.conf = FaultConfig{}
+	if res := session(3); len(res) != 1 || res[0] != 3 {
+		t.Errorf("Test_faultInjector_Held: want: [3] res: %v", res)
+	}
+}
+
+func Test_faultInjector_Malformed(t *testing.T) {
+	for _, m := range injectFaults(t, FaultConfig{Malformed: 1, Seed: 1}, 20) {
+		if !math.IsNaN(m.Latitude) && !math.IsNaN(m.Longitude) && math.Abs(m.Latitude) <= 90 &&
+			m.Magnitude >= -2 && m.Magnitude <= 10 {
+			t.Errorf("Test_faultInjector_Malformed: the message is not malformed: %v", m)
+		}
+	}
+}
+
+func Test_NewHub_Options(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    provider.WatcherConfig
+		wantErr bool
+	}{
+		{"scenario", provider.WatcherConfig{CheckPeriod: 1, Options: json.RawMessage(`{"scenario": "testdata/scenario.json"}`)}, false},
+		{"missing scenario", provider.WatcherConfig{CheckPeriod: 1, Options: json.RawMessage(`{"scenario": "testdata/missing.json"}`)}, true},
+		{"ignored connection string", provider.WatcherConfig{CheckPeriod: 1, ConnStr: "pseudo",
+			Options: json.RawMessage(`{"scenario": "testdata/scenario.json"}`)}, false},
+		{"unknown option", provider.WatcherConfig{CheckPeriod: 1, Options: json.RawMessage(`{"rate": 1}`)}, true},
+	}
+
+	for _, test := range tests {
+		h, err := NewHub(test.conf)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Test_NewHub_Options: %s: want error: %v res: %v", test.name, test.wantErr, err)
+		}
+		if err == nil && h.scenario == nil {
+			t.Errorf("Test_NewHub_Options: %s: the scenario is not loaded", test.name)
+		}
+	}
+}
+
+func Test_NewHub_Faults(t *testing.T) {
+	c := provider.WatcherConfig{Id: "pseudo", CheckPeriod: 1, Options: faultOptions(FaultConfig{Duplicate: 1.5})}
+	if _, err := NewHub(c); err == nil {
+		t.Errorf("Test_NewHub_Faults: an error is expected for an incorrect rate")
+	}
+}
+
+func Test_StartWatch_Close(t *testing.T) {
+	c := provider.WatcherConfig{Id: "pseudo", CheckPeriod: 1, Options: faultOptions(FaultConfig{Close: 1})}
+	h, err := NewHub(c)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Close: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Close: %v", err)
+	}
+
+	if _, ok := <-ch; ok {
+		t.Errorf("Test_StartWatch_Close: the channel is expected to be closed")
+	}
+
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_StartWatch_Close: want state: %s, res: %s", provider.Stopped, s)
+	}
+}
@@ -0,0 +1,235 @@
+package pseudo
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"seismo/provider"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	//kmPerDegree defines the length of a degree of latitude
+	kmPerDegree = 111.2
+
+	//day defines a day as a float number of nanoseconds
+	day = float64(24 * time.Hour)
+)
+
+// Generator creates synthetic seismic events according to a scenario.
+// Events are created in the order of FocusTime.
+//
+// Generator is not safe for concurrent use.
+type Generator struct {
+	sc  Scenario
+	rng *rand.Rand
+
+	//next specifies the time of the next background event
+	next time.Time
+
+	//pending contains created events which have not been returned yet
+	pending eventHeap
+
+	//seq is used to keep the order of creating events with the same time
+	seq int
+
+	//areas contains cumulative areas of fault polygons
+	areas []float64
+}
+
+// NewGenerator returns a pointer to a new Generator creating events of the scenario "sc"
+// beginning from "start". The scenario must be valid (see Scenario.Validate).
+func NewGenerator(sc Scenario, start time.Time) *Generator {
+	seed := sc.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	g := &Generator{sc: sc, rng: rand.New(rand.NewSource(seed))}
+
+	var sum float64
+	for _, p := range sc.Faults {
+		sum += p.area()
+		g.areas = append(g.areas, sum)
+	}
+
+	g.next = start.UTC().Add(g.interval())
+
+	return g
+}
+
+// Next returns the next event of the scenario.
+func (g *Generator) Next() provider.Message {
+	for len(g.pending) == 0 || g.pending[0].msg.FocusTime.After(g.next) {
+		lat, lon := g.location()
+		m := g.event(g.next, lat, lon, g.magnitude(g.sc.MaxMag))
+		g.push(m)
+
+		if a := g.sc.Aftershocks; a != nil && m.Magnitude >= a.MainMag {
+			g.aftershocks(m)
+		}
+		g.next = g.next.Add(g.interval())
+	}
+
+	return heap.Pop(&g.pending).(pendingEvent).msg
+}
+
+// interval returns a random interval between background events of the Poisson process.
+func (g *Generator) interval() time.Duration {
+	return time.Duration(g.rng.ExpFloat64() / g.sc.Rate * float64(time.Hour))
+}
+
+// magnitude returns a random magnitude of the Gutenberg–Richter distribution
+// truncated by MinMag and "max".
+func (g *Generator) magnitude(max float64) float64 {
+	b, min := g.sc.BValue, g.sc.MinMag
+	if max <= min {
+		return min
+	}
+
+	u := g.rng.Float64()
+	m := min - math.Log10(1-u*(1-math.Pow(10, -b*(max-min))))/b
+
+	return math.Round(m*100) / 100
+}
+
+// location returns a random epicenter within the faults or the region of the scenario.
+func (g *Generator) location() (lat float64, lon float64) {
+	if len(g.areas) == 0 {
+		r := g.sc.Region
+		return r.MinLat + g.rng.Float64()*(r.MaxLat-r.MinLat), r.MinLon + g.rng.Float64()*(r.MaxLon-r.MinLon)
+	}
+
+	//a polygon is chosen in proportion to its area
+	x := g.rng.Float64() * g.areas[len(g.areas)-1]
+	i := 0
+	for i < len(g.areas)-1 && x > g.areas[i] {
+		i++
+	}
+
+	p := g.sc.Faults[i]
+	b := p.bounds()
+	for {
+		lat = b.MinLat + g.rng.Float64()*(b.MaxLat-b.MinLat)
+		lon = b.MinLon + g.rng.Float64()*(b.MaxLon-b.MinLon)
+		if p.contains(lat, lon) {
+			return lat, lon
+		}
+	}
+}
+
+// aftershocks creates the aftershock sequence of the mainshock "m".
+//
+// The number of aftershocks is a Poisson random value with the mean equal to
+// the integral of the Reasenberg–Jones rate over the sequence duration. Times are
+// sampled by the inverse of the distribution function of the rate.
+// Epicenters are normally distributed around the epicenter of the mainshock with
+// the deviation equal to a half of the rupture length estimated by the magnitude
+// (Wells and Coppersmith, 1994).
+func (g *Generator) aftershocks(m provider.Message) {
+	a := g.sc.Aftershocks
+	k := math.Pow(10, a.A+g.sc.BValue*(m.Magnitude-g.sc.MinMag))
+
+	//integral of (t + c)^-p from 0 to t
+	integral := func(t float64) float64 {
+		if a.P == 1 {
+			return math.Log((t + a.C) / a.C)
+		}
+		return (math.Pow(a.C, 1-a.P) - math.Pow(t+a.C, 1-a.P)) / (a.P - 1)
+	}
+
+	//inverse of integral
+	inverse := func(v float64) float64 {
+		if a.P == 1 {
+			return a.C*math.Exp(v) - a.C
+		}
+		return math.Pow(math.Pow(a.C, 1-a.P)-v*(a.P-1), 1/(1-a.P)) - a.C
+	}
+
+	total := integral(a.Duration)
+	n := g.poisson(k * total)
+
+	sigma := math.Pow(10, -2.44+0.59*m.Magnitude) / 2 / kmPerDegree
+	for i := 0; i < n; i++ {
+		t := inverse(g.rng.Float64() * total)
+		lat := m.Latitude + g.rng.NormFloat64()*sigma
+		lon := m.Longitude + g.rng.NormFloat64()*sigma/math.Max(math.Cos(lat*math.Pi/180), 0.01)
+		lat = math.Max(-90, math.Min(90, lat))
+
+		ft := m.FocusTime.Add(time.Duration(t * day))
+		g.push(g.event(ft, lat, lon, g.magnitude(math.Min(g.sc.MaxMag, m.Magnitude))))
+	}
+}
+
+// poisson returns a random value of the Poisson distribution with the mean "mean".
+func (g *Generator) poisson(mean float64) int {
+	if mean > 30 { //the normal approximation
+		return int(math.Max(0, math.Round(mean+g.rng.NormFloat64()*math.Sqrt(mean))))
+	}
+
+	l, n, p := math.Exp(-mean), 0, g.rng.Float64()
+	for p > l {
+		n++
+		p *= g.rng.Float64()
+	}
+	return n
+}
+
+// event returns a new event message.
+func (g *Generator) event(ft time.Time, lat float64, lon float64, mag float64) provider.Message {
+	id, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil { //rand.Rand never fails
+		panic(err)
+	}
+
+	return provider.Message{
+		FocusTime: ft,
+		Latitude:  lat,
+		Longitude: lon,
+		Magnitude: mag,
+		EventId:   id.String(),
+		Type:      provider.EarthQuake,
+		Quality:   qualities[g.rng.Intn(len(qualities))],
+	}
+}
+
+// qualities lists the known qualities of generated events.
+var qualities = []provider.EventQuality{provider.Preliminary, provider.Good, provider.Excellent}
+
+func (g *Generator) push(m provider.Message) {
+	g.seq++
+	heap.Push(&g.pending, pendingEvent{msg: m, seq: g.seq})
+}
+
+// pendingEvent is an event created, but not returned by a Generator yet.
+type pendingEvent struct {
+	msg provider.Message
+	seq int
+}
+
+// eventHeap implements heap.Interface ordering events by FocusTime
+// and then by the order of creating.
+type eventHeap []pendingEvent
+
+func (h eventHeap) Len() int { return len(h) }
+
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].msg.FocusTime.Equal(h[j].msg.FocusTime) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].msg.FocusTime.Before(h[j].msg.FocusTime)
+}
+
+func (h eventHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *eventHeap) Push(x any) { *h = append(*h, x.(pendingEvent)) }
+
+func (h *eventHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
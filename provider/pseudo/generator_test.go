@@ -0,0 +1,146 @@
+package pseudo
+
+import (
+	"math"
+	"seismo/provider"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestScenario() Scenario {
+	sc := Scenario{Seed: 7, Rate: 10, BValue: 1.2, MinMag: 2, MaxMag: 9,
+		Region: &Region{MinLat: 50, MaxLat: 55, MinLon: 85, MaxLon: 90}}
+	sc.setDefaults()
+	return sc
+}
+
+func Test_Generator_Seed(t *testing.T) {
+	sc := newTestScenario()
+	sc.Aftershocks = &Omori{MainMag: 4}
+	sc.setDefaults()
+
+	g1, g2 := NewGenerator(sc, testStart), NewGenerator(sc, testStart)
+	sc.Seed = 8
+	g3 := NewGenerator(sc, testStart)
+
+	differs := false
+	prev := testStart
+	for i := 0; i < 1000; i++ {
+		m1, m2, m3 := g1.Next(), g2.Next(), g3.Next()
+		if !m1.Equal(m2) {
+			t.Fatalf("Test_Generator_Seed: event %d: the same seed yields different events: %v %v", i, m1, m2)
+		}
+		if !m1.Equal(m3) {
+			differs = true
+		}
+
+		if m1.FocusTime.Before(prev) {
+			t.Fatalf("Test_Generator_Seed: event %d: events are not ordered by time", i)
+		}
+		prev = m1.FocusTime
+	}
+
+	if !differs {
+		t.Errorf("Test_Generator_Seed: different seeds yield the same events")
+	}
+}
+
+func Test_Generator_GutenbergRichter(t *testing.T) {
+	sc := newTestScenario()
+	g := NewGenerator(sc, testStart)
+
+	const n = 20000
+	var sum float64
+	var last provider.Message
+	for i := 0; i < n; i++ {
+		last = g.Next()
+		if last.Magnitude < sc.MinMag || last.Magnitude > sc.MaxMag {
+			t.Fatalf("Test_Generator_GutenbergRichter: magnitude %v is out of range", last.Magnitude)
+		}
+		if r := sc.Region; last.Latitude < r.MinLat || last.Latitude > r.MaxLat ||
+			last.Longitude < r.MinLon || last.Longitude > r.MaxLon {
+			t.Fatalf("Test_Generator_GutenbergRichter: %v is out of the region", last)
+		}
+		sum += last.Magnitude
+	}
+
+	//the Aki maximum likelihood estimate
+	b := math.Log10(math.E) / (sum/n - sc.MinMag)
+	if math.Abs(b-sc.BValue) > 0.05 {
+		t.Errorf("Test_Generator_GutenbergRichter: want b-value: %v, result: %v", sc.BValue, b)
+	}
+
+	//the Poisson rate
+	rate := n / last.FocusTime.Sub(testStart).Hours()
+	if math.Abs(rate-sc.Rate)/sc.Rate > 0.05 {
+		t.Errorf("Test_Generator_GutenbergRichter: want rate: %v, result: %v", sc.Rate, rate)
+	}
+}
+
+func Test_Generator_Faults(t *testing.T) {
+	sc, err := LoadScenario("testdata/scenario.json")
+	if err != nil {
+		t.Fatalf("Test_Generator_Faults: %v", err)
+	}
+	sc.Aftershocks = nil
+
+	g := NewGenerator(*sc, testStart)
+	counts := make([]int, len(sc.Faults))
+	for i := 0; i < 3000; i++ {
+		m := g.Next()
+		found := false
+		for j, p := range sc.Faults {
+			if p.contains(m.Latitude, m.Longitude) {
+				counts[j]++
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("Test_Generator_Faults: %v is out of the faults", m)
+		}
+	}
+
+	//events are distributed in proportion to areas
+	want := sc.Faults[0].area() / (sc.Faults[0].area() + sc.Faults[1].area())
+	if res := float64(counts[0]) / 3000; math.Abs(res-want) > 0.05 {
+		t.Errorf("Test_Generator_Faults: want share of the first fault: %v, result: %v", want, res)
+	}
+}
+
+func Test_Generator_aftershocks(t *testing.T) {
+	sc := newTestScenario()
+	sc.Aftershocks = &Omori{MainMag: 6}
+	sc.setDefaults()
+	a := sc.Aftershocks
+
+	g := NewGenerator(sc, testStart)
+	main := provider.Message{FocusTime: testStart, Latitude: 52, Longitude: 87, Magnitude: 6.5}
+	g.aftershocks(main)
+
+	k := math.Pow(10, a.A+sc.BValue*(main.Magnitude-sc.MinMag))
+	want := k * (math.Pow(a.C, 1-a.P) - math.Pow(a.Duration+a.C, 1-a.P)) / (a.P - 1)
+	if n := float64(len(g.pending)); math.Abs(n-want) > 4*math.Sqrt(want) {
+		t.Fatalf("Test_Generator_aftershocks: want about %v aftershocks, result: %v", want, n)
+	}
+
+	//the rate decays: the first day contains more aftershocks than the rest days
+	var first, rest int
+	for _, e := range g.pending {
+		if e.msg.Magnitude > main.Magnitude {
+			t.Errorf("Test_Generator_aftershocks: an aftershock is bigger than the mainshock: %v", e.msg)
+		}
+		if d := e.msg.FocusTime.Sub(testStart); d < 0 || d > time.Duration(a.Duration*day) {
+			t.Errorf("Test_Generator_aftershocks: an aftershock is out of the sequence: %v", e.msg)
+		} else if d < 24*time.Hour {
+			first++
+		} else {
+			rest++
+		}
+	}
+
+	if first <= rest {
+		t.Errorf("Test_Generator_aftershocks: the first day: %d, the rest days: %d", first, rest)
+	}
+}
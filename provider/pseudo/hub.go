@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"seismo/provider"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -32,7 +33,7 @@ func (s *stoppedState) startWatch(ctx context.Context, from time.Time) (<-chan p
 	h := s.hub
 	h.setState(newRunState(h))
 	o := make(chan provider.Message)
-	go h.generateMessages(ctx, o, from)
+	go h.watch(ctx, o, from)
 
 	return o, nil
 }
@@ -60,45 +61,194 @@ func (r *runState) stateInfo() provider.WatcherStateInfo {
 
 // Hub implements the provider.Watcher interface,
 // emulates getting seismic event messages,
-// creating new messages randomly.
+// creating new messages randomly or according to a scenario.
 type Hub struct {
 	config provider.WatcherConfig
 
 	//state implements THE STATE PATTERN
 	state hubState
+
+	//scenario specifies generated seismicity. If it is nil, messages are uniformly random.
+	scenario *Scenario
+
+	//faults injects faults specified by the configuration. It is nil if there are no faults.
+	//The injector is kept between watching sessions, so a seeded sequence of faults
+	//continues after restarting.
+	faults *faultInjector
+
+	//mu guards the state, since the watching go-routine stops the Hub
+	//concurrently with StateInfo and StartWatch calls.
+	mu sync.Mutex
+
+	//stats keeps statistics of the Hub
+	stats provider.StatsRecorder
+
+	//lifecycle publishes lifecycle events of the Hub
+	lifecycle provider.Lifecycle
+}
+
+// Options specifies options of the pseudo provider (the options block of a watcher configuration).
+type Options struct {
+	// Scenario specifies a path to a json file of a scenario (see LoadScenario).
+	// If it is empty, the Hub creates uniformly random messages.
+	Scenario string `json:"scenario"`
+
+	// Faults specifies faults injected into the messages for resilience testing. Optional.
+	Faults *FaultConfig `json:"faults"`
+}
+
+// decodeOptions decodes and checks the options block of "conf".
+func decodeOptions(conf provider.WatcherConfig) (Options, error) {
+	var opts Options
+	if err := provider.DecodeOptions(conf.Options, &opts); err != nil {
+		return Options{}, fmt.Errorf("decodeOptions: %w", err)
+	}
+
+	if opts.Faults != nil {
+		if err := validateFaults(*opts.Faults); err != nil {
+			return Options{}, fmt.Errorf("decodeOptions: %w", err)
+		}
+	}
+
+	return opts, nil
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		T: provider.Pseudo,
+		Factory: func(conf provider.WatcherConfig) (provider.Watcher, error) {
+			h, err := NewHub(conf)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		Schema: provider.ConfigSchema{
+			Description: "Generates pseudo (random or scenario-driven) seismic events for testing purposes.",
+			ConnStr:     "Not used.",
+			Options:     Options{},
+		},
+	})
 }
 
 // NewHub returns a pointer to a new pseudo.Hub in the stopped state and an error.
 //
+// The options block of "conf" (see Options) specifies a scenario file and injected faults.
+// Without a scenario the Hub creates uniformly random messages.
+// The connection string of "conf" is not used.
+//
 // If the returned error is not nil, the returned pointer value is nil.
 func NewHub(conf provider.WatcherConfig) (*Hub, error) {
 	if conf.CheckPeriod < 1 {
 		return nil, fmt.Errorf("NewHub: checkperiod cannot be less than 1 second")
 	}
 
+	opts, err := decodeOptions(conf)
+	if err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
 	h := &Hub{}
 	h.config = conf
+	h.setFaults(opts.Faults)
+
+	if opts.Scenario != "" {
+		sc, err := LoadScenario(opts.Scenario)
+		if err != nil {
+			return nil, fmt.Errorf("NewHub: %w", err)
+		}
+		h.scenario = sc
+	}
+
+	h.setState(newStoppedState(h))
+
+	return h, nil
+}
+
+// NewScenarioHub returns a pointer to a new pseudo.Hub in the stopped state
+// generating seismicity of the scenario "sc" and an error. Unspecified (zero) values
+// of the scenario are set to defaults. The "scenario" option of "conf" is not used.
+//
+// If the returned error is not nil, the returned pointer value is nil.
+func NewScenarioHub(conf provider.WatcherConfig, sc Scenario) (*Hub, error) {
+	if conf.CheckPeriod < 1 {
+		return nil, fmt.Errorf("NewScenarioHub: checkperiod cannot be less than 1 second")
+	}
+
+	opts, err := decodeOptions(conf)
+	if err != nil {
+		return nil, fmt.Errorf("NewScenarioHub: %w", err)
+	}
+
+	sc.setDefaults()
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("NewScenarioHub: %w", err)
+	}
+
+	h := &Hub{config: conf, scenario: &sc}
+	h.setFaults(opts.Faults)
 
 	h.setState(newStoppedState(h))
 
 	return h, nil
 }
 
+// setFaults sets the injector of the faults "fc", if it is not nil.
+func (h *Hub) setFaults(fc *FaultConfig) {
+	if fc != nil {
+		h.faults = newFaultInjector(*fc)
+		h.faults.sent = h.stats.Emitted
+	}
+}
+
 // GetConfig returns configuration of the Hub.
 func (h *Hub) GetConfig() provider.WatcherConfig {
 	return h.config
 }
 
+// setState sets the state of the Hub. The caller must hold h.mu,
+// except while the Hub is being created.
 func (h *Hub) setState(s hubState) {
+	prev := h.state
 	h.state = s
+	h.stats.Transited()
+
+	if prev != nil {
+		to := s.stateInfo()
+		var err error
+		if to == provider.Failed || to == provider.Backoff {
+			err = h.stats.LastErr()
+		}
+		h.lifecycle.Transit(h.config.Id, prev.stateInfo(), to, err)
+	}
+}
+
+// Subscribe implements the provider.Observable interface.
+func (h *Hub) Subscribe(buf int) (<-chan provider.LifecycleEvent, func()) {
+	return h.lifecycle.Subscribe(buf)
 }
 
 // StateInfo reports a current state of the Hub
 func (h *Hub) StateInfo() provider.WatcherStateInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	return h.state.stateInfo()
 }
 
+// Stats returns the state and statistics of the Hub. Fetch errors and retries
+// are always zero, since the Hub does not fetch messages. Every generating cycle
+// is counted as a successful poll.
+func (h *Hub) Stats() provider.WatcherStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.Stats(h.state.stateInfo())
+}
+
 // StartWatch starts generating several (1 to 3) random seismic messages every checkPeriod.
+// If the Hub has a scenario, every checkPeriod it sends the events of the scenario
+// occurred since the previous check.
 //
 // The methods returns a channel for fetching messages. If the returned error is not nil, the returned
 // channel is nil.
@@ -106,20 +256,113 @@ func (h *Hub) StateInfo() provider.WatcherStateInfo {
 // The FocusTime of every message corresponds to its generating moment minus an offset.
 // The offset is calculated as the differrence between the moment the method is called
 // and the value of the "from" argument.
+//
+// If the configuration specifies faults (see FaultConfig), they are injected
+// into the sent messages.
 func (h *Hub) StartWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	o, err := h.state.startWatch(ctx, from)
 	return o, err
 }
 
-func (h *Hub) generateMessages(ctx context.Context, o chan<- provider.Message, from time.Time) {
+// Backfill implements the provider.Backfiller interface. It generates events
+// with the FocusTime in the [from, to) range by the scenario of the Hub at once
+// and sends them in the order of FocusTime. A seeded scenario produces the same events
+// as watching from "from". A Hub without a scenario uses the default scenario with the rate
+// of one event per checkPeriod. Faults are not injected into backfilled messages.
+//
+// Backfilling can be performed while the Hub is watching.
+func (h *Hub) Backfill(ctx context.Context, from time.Time, to time.Time) (<-chan provider.Message, error) {
+	if err := provider.CheckRange(from, to); err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	var sc Scenario
+	if h.scenario != nil {
+		sc = *h.scenario
+	} else {
+		sc = Scenario{Rate: float64(time.Hour) / float64(time.Duration(h.config.CheckPeriod)*time.Second)}
+		sc.setDefaults()
+	}
+
+	o := make(chan provider.Message)
+	go func() {
+		defer close(o)
+
+		g := NewGenerator(sc, from)
+		for m := g.Next(); m.FocusTime.Before(to); m = g.Next() {
+			m.SourceId = h.config.Id
+
+			select {
+			case o <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return o, nil
+}
+
+// watch generates messages and sends them into the "o" channel injecting
+// configured faults. The "o" channel is closed when generating is canceled or
+// the channel closure fault happens.
+func (h *Hub) watch(ctx context.Context, o chan<- provider.Message, from time.Time) {
 	defer func() {
-		close(o)
+		h.mu.Lock()
 		h.setState(newStoppedState(h))
+		h.mu.Unlock()
+		close(o)
 	}()
 
+	gctx, cancel := context.WithCancel(ctx)
+	in := make(chan provider.Message)
+	go func() {
+		defer close(in)
+		h.generate(gctx, in, from)
+	}()
+
+	if h.faults != nil {
+		h.faults.run(ctx, in, o)
+	} else {
+		h.forward(ctx, in, o)
+	}
+
+	//stop generating
+	cancel()
+	for range in {
+	}
+}
+
+// forward sends messages from "in" into "o" until "in" is closed or the context is canceled.
+func (h *Hub) forward(ctx context.Context, in <-chan provider.Message, o chan<- provider.Message) {
+	for m := range in {
+		select {
+		case o <- m:
+			h.stats.Emitted()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// generate sends generated messages into the "o" channel until the context is canceled.
+func (h *Hub) generate(ctx context.Context, o chan<- provider.Message, from time.Time) {
+	if h.scenario != nil {
+		h.generateScenario(ctx, o, from.UTC())
+	} else {
+		h.generateMessages(ctx, o, from)
+	}
+}
+
+func (h *Hub) generateMessages(ctx context.Context, o chan<- provider.Message, from time.Time) {
 	offset := time.Now().UTC().Sub(from)
 	for {
-		for _, m := range h.createRandMsgs(offset) {
+		msgs := h.createRandMsgs(offset)
+		h.stats.Polled()
+		for _, m := range msgs {
 			if ctx.Err() != nil {
 				return
 			}
@@ -130,7 +373,12 @@ func (h *Hub) generateMessages(ctx context.Context, o chan<- provider.Message, f
 				return
 			}
 		}
-		time.Sleep(time.Duration(h.config.CheckPeriod) * time.Second)
+
+		select {
+		case <-time.After(time.Duration(h.config.CheckPeriod) * time.Second):
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
@@ -165,3 +413,36 @@ func (h *Hub) createRandMsgs(offset time.Duration) []provider.Message {
 
 	return msgs
 }
+
+// generateScenario sends events of the scenario into the "o" channel. The events are
+// generated beginning from "from" and sent every checkPeriod, when the FocusTime of an
+// event shifted by the offset (see StartWatch) has come.
+func (h *Hub) generateScenario(ctx context.Context, o chan<- provider.Message, from time.Time) {
+	g := NewGenerator(*h.scenario, from)
+	offset := time.Now().UTC().Sub(from)
+
+	wt := time.NewTicker(time.Duration(h.config.CheckPeriod) * time.Second)
+	defer wt.Stop()
+
+	next := g.Next()
+	for {
+		now := time.Now().UTC().Add(-offset)
+		h.stats.Polled()
+		for !next.FocusTime.After(now) {
+			next.SourceId = h.config.Id
+
+			select {
+			case o <- next:
+			case <-ctx.Done():
+				return
+			}
+			next = g.Next()
+		}
+
+		select {
+		case <-wt.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package pseudo
 
 import (
+	"context"
 	"fmt"
 	"seismo/provider"
 	"testing"
+	"time"
 )
 
 // Test_createRandMsgs is ONLY for launching and
@@ -57,3 +59,129 @@ func Test_createRandMsgs(t *testing.T) {
 // 		t.Errorf("starting watch error: %v", err)
 // 	}
 // }
+
+func Test_StartWatch_Scenario(t *testing.T) {
+	sc := newTestScenario()
+	sc.Rate = 36000 //10 events per second
+	c := provider.WatcherConfig{Id: "pseudo", CheckPeriod: 1}
+	h, err := NewScenarioHub(c, sc)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Scenario: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	ch, err := h.StartWatch(ctx, from)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Scenario: %v", err)
+	}
+
+	g := NewGenerator(sc, from)
+	for i := 0; i < 20; i++ {
+		want := g.Next()
+		want.SourceId = "pseudo"
+
+		select {
+		case m := <-ch:
+			if !m.Equal(want) {
+				t.Fatalf("Test_StartWatch_Scenario: message %d:\n\twant: %v\n\tresult: %v", i, want, m)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Test_StartWatch_Scenario: timeout")
+		}
+	}
+
+	cancel()
+	for range ch {
+	}
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_StartWatch_Scenario: want state: %s, res: %s", provider.Stopped, s)
+	}
+}
+
+func Test_Backfill(t *testing.T) {
+	sc := newTestScenario()
+	sc.Rate = 60
+	h, err := NewScenarioHub(provider.WatcherConfig{Id: "pseudo", CheckPeriod: 1}, sc)
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+	ch, err := h.Backfill(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	//the seeded scenario produces the same events as watching from "from"
+	g := NewGenerator(sc, from)
+	n := 0
+	for m := range ch {
+		want := g.Next()
+		want.SourceId = "pseudo"
+		if !m.Equal(want) {
+			t.Fatalf("Test_Backfill: message %d:\n\twant: %v\n\tresult: %v", n, want, m)
+		}
+		n++
+	}
+
+	if next := g.Next(); next.FocusTime.Before(to) {
+		t.Errorf("Test_Backfill: the event at %v is not backfilled", next.FocusTime)
+	}
+	if n == 0 {
+		t.Errorf("Test_Backfill: no events")
+	}
+
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_Backfill: backfilling changed the state: %s", s)
+	}
+}
+
+func Test_Stats(t *testing.T) {
+	sc := newTestScenario()
+	sc.Rate = 36000 //10 events per second
+	c := provider.WatcherConfig{Id: "pseudo", CheckPeriod: 1, Options: faultOptions(FaultConfig{Duplicate: 1})}
+	h, err := NewScenarioHub(c, sc)
+	if err != nil {
+		t.Fatalf("Test_Stats: %v", err)
+	}
+
+	if st := h.Stats(); st.State != provider.Stopped || st.Emitted != 0 {
+		t.Errorf("Test_Stats: unexpected stats of a new hub: %+v", st)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Now().UTC().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Test_Stats: %v", err)
+	}
+
+	//every message is sent twice
+	for i := 0; i < 10; i++ {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			t.Fatalf("Test_Stats: timeout")
+		}
+	}
+
+	st := h.Stats()
+	if st.State != provider.Run || st.LastPoll.IsZero() {
+		t.Errorf("Test_Stats: unexpected stats: %+v", st)
+	}
+
+	cancel()
+	for range ch {
+	}
+
+	//sending is counted after a message is received
+	st = h.Stats()
+	if st.State != provider.Stopped || st.Emitted < 10 || st.Emitted%2 != 0 || st.LastMsg.IsZero() || st.FetchErrors != 0 {
+		t.Errorf("Test_Stats: unexpected stats of the stopped hub: %+v", st)
+	}
+}
@@ -0,0 +1,211 @@
+package pseudo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Default values of scenarios.
+const (
+	defBValue = 1.0
+	defMinMag = 1.0
+	defMaxMag = 8.0
+
+	//Reasenberg–Jones generic parameters
+	defOmoriA        = -1.67
+	defOmoriC        = 0.05 //days
+	defOmoriP        = 1.08
+	defOmoriDuration = 10.0 //days
+)
+
+// defRegion defines the region of events of the default scenario.
+var defRegion = Region{MinLat: 40.0, MaxLat: 60.0, MinLon: 70.0, MaxLon: 100.0}
+
+// Scenario describes synthetic seismicity generated by a Hub.
+//
+// Background events occur as a Poisson process with Rate events per hour.
+// Their epicenters are uniformly distributed within the fault polygons
+// (if any) or the region. Magnitudes follow the Gutenberg–Richter law
+// with the b-value BValue truncated by MinMag and MaxMag. Every event of
+// magnitude Aftershocks.MainMag or more is followed by an Omori-law aftershock sequence.
+//
+// The same Seed produces the same sequence of events for the same start time.
+type Scenario struct {
+	// Seed specifies the seed of the random generator. If Seed is 0, the current time is used.
+	Seed int64 `json:"seed"`
+
+	// Region specifies a bounding region of epicenters. It is used if there are no Faults.
+	Region *Region `json:"region"`
+
+	// Faults specifies fault polygons containing epicenters.
+	Faults []Polygon `json:"faults"`
+
+	// Rate specifies the background rate (events per hour).
+	Rate float64 `json:"rate"`
+
+	// BValue specifies the b-value of the Gutenberg–Richter law.
+	BValue float64 `json:"b_value"`
+
+	// MinMag and MaxMag specify the magnitude range.
+	MinMag float64 `json:"min_mag"`
+	MaxMag float64 `json:"max_mag"`
+
+	// Aftershocks specifies aftershock sequences. If it is nil, there are no aftershocks.
+	Aftershocks *Omori `json:"aftershocks"`
+}
+
+// Region represents a bounding box of coordinates in degrees.
+type Region struct {
+	MinLat float64 `json:"min_lat"`
+	MaxLat float64 `json:"max_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// Polygon represents a polygon as a slice of vertices like [latitude, longitude].
+type Polygon [][2]float64
+
+// Omori describes aftershock sequences by the Reasenberg–Jones model:
+// the rate of aftershocks of magnitude MinMag or more at time t (days) after
+// a mainshock of magnitude Mm is 10^(A + b(Mm - MinMag)) * (t + C)^-P per day.
+type Omori struct {
+	// MainMag specifies the min magnitude of events followed by aftershocks.
+	MainMag float64 `json:"main_mag"`
+
+	// A, C (days) and P specify parameters of the model.
+	A float64 `json:"a"`
+	C float64 `json:"c"`
+	P float64 `json:"p"`
+
+	// Duration specifies the duration of sequences in days.
+	Duration float64 `json:"duration"`
+}
+
+// LoadScenario returns a scenario read from a json file addressed by "path" and an error.
+// Unspecified values are set to defaults. If the returned error is not nil, the returned pointer is nil.
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadScenario: %w", err)
+	}
+
+	var sc Scenario
+	if err := json.Unmarshal(b, &sc); err != nil {
+		return nil, fmt.Errorf("LoadScenario: %q: %w", path, err)
+	}
+
+	sc.setDefaults()
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("LoadScenario: %q: %w", path, err)
+	}
+
+	return &sc, nil
+}
+
+// setDefaults sets unspecified (zero) values to defaults.
+func (sc *Scenario) setDefaults() {
+	if sc.Region == nil && len(sc.Faults) == 0 {
+		r := defRegion
+		sc.Region = &r
+	}
+
+	if sc.BValue == 0 {
+		sc.BValue = defBValue
+	}
+
+	if sc.MinMag == 0 && sc.MaxMag == 0 {
+		sc.MinMag, sc.MaxMag = defMinMag, defMaxMag
+	}
+
+	if a := sc.Aftershocks; a != nil {
+		if a.A == 0 {
+			a.A = defOmoriA
+		}
+		if a.C == 0 {
+			a.C = defOmoriC
+		}
+		if a.P == 0 {
+			a.P = defOmoriP
+		}
+		if a.Duration == 0 {
+			a.Duration = defOmoriDuration
+		}
+	}
+}
+
+// Validate checks values of the scenario.
+func (sc *Scenario) Validate() error {
+	if sc.Rate <= 0 {
+		return fmt.Errorf("Validate: the rate must be positive")
+	}
+
+	if sc.BValue <= 0 {
+		return fmt.Errorf("Validate: the b-value must be positive")
+	}
+
+	if sc.MinMag >= sc.MaxMag {
+		return fmt.Errorf("Validate: min_mag must be less than max_mag")
+	}
+
+	if len(sc.Faults) == 0 {
+		if sc.Region == nil {
+			return fmt.Errorf("Validate: neither region nor faults are specified")
+		}
+		if r := sc.Region; r.MinLat >= r.MaxLat || r.MinLon >= r.MaxLon || r.MinLat < -90 || r.MaxLat > 90 {
+			return fmt.Errorf("Validate: incorrect region %+v", *r)
+		}
+	}
+
+	for i, p := range sc.Faults {
+		if len(p) < 3 {
+			return fmt.Errorf("Validate: fault %d: a polygon must have 3 vertices at least", i)
+		}
+		if p.area() == 0 {
+			return fmt.Errorf("Validate: fault %d: a polygon must have a non-zero area", i)
+		}
+	}
+
+	if a := sc.Aftershocks; a != nil {
+		if a.C <= 0 || a.P <= 0 || a.Duration <= 0 {
+			return fmt.Errorf("Validate: aftershocks: c, p and duration must be positive")
+		}
+	}
+
+	return nil
+}
+
+// area returns the area of the polygon in square degrees (the shoelace formula).
+func (p Polygon) area() float64 {
+	var s float64
+	for i := range p {
+		j := (i + 1) % len(p)
+		s += p[i][1]*p[j][0] - p[j][1]*p[i][0]
+	}
+	return math.Abs(s) / 2
+}
+
+// bounds returns the bounding box of the polygon.
+func (p Polygon) bounds() Region {
+	r := Region{MinLat: p[0][0], MaxLat: p[0][0], MinLon: p[0][1], MaxLon: p[0][1]}
+	for _, v := range p[1:] {
+		r.MinLat = math.Min(r.MinLat, v[0])
+		r.MaxLat = math.Max(r.MaxLat, v[0])
+		r.MinLon = math.Min(r.MinLon, v[1])
+		r.MaxLon = math.Max(r.MaxLon, v[1])
+	}
+	return r
+}
+
+// contains reports whether the point is inside the polygon (the ray casting algorithm).
+func (p Polygon) contains(lat, lon float64) bool {
+	in := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		if (p[i][0] > lat) != (p[j][0] > lat) &&
+			lon < (p[j][1]-p[i][1])*(lat-p[i][0])/(p[j][0]-p[i][0])+p[i][1] {
+			in = !in
+		}
+	}
+	return in
+}
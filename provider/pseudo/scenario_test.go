@@ -0,0 +1,65 @@
+package pseudo
+
+import (
+	"testing"
+)
+
+func Test_LoadScenario(t *testing.T) {
+	sc, err := LoadScenario("testdata/scenario.json")
+	if err != nil {
+		t.Fatalf("Test_LoadScenario: %v", err)
+	}
+
+	if sc.Seed != 42 || len(sc.Faults) != 2 || sc.BValue != 0.9 || sc.MinMag != 1.5 || sc.MaxMag != 7.5 {
+		t.Errorf("Test_LoadScenario: unexpected scenario: %+v", *sc)
+	}
+
+	//defaults
+	if sc.Region != nil {
+		t.Errorf("Test_LoadScenario: the region is not expected for faults")
+	}
+
+	want := Omori{MainMag: 5.0, A: defOmoriA, C: defOmoriC, P: defOmoriP, Duration: defOmoriDuration}
+	if sc.Aftershocks == nil || *sc.Aftershocks != want {
+		t.Errorf("Test_LoadScenario: want aftershocks: %+v, result: %+v", want, sc.Aftershocks)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	tests := []Scenario{
+		{Rate: 0},
+		{Rate: 1, BValue: -1},
+		{Rate: 1, MinMag: 5, MaxMag: 3},
+		{Rate: 1, Region: &Region{MinLat: 60, MaxLat: 40, MinLon: 70, MaxLon: 100}},
+		{Rate: 1, Faults: []Polygon{{{50, 87}, {51, 88}}}},
+		{Rate: 1, Faults: []Polygon{{{50, 87}, {51, 88}, {52, 89}}}},
+		{Rate: 1, Aftershocks: &Omori{MainMag: 5, P: -1}},
+	}
+
+	for i, sc := range tests {
+		sc.setDefaults()
+		if err := sc.Validate(); err == nil {
+			t.Errorf("Test_Validate: case %d: an error is expected for %+v", i, sc)
+		}
+	}
+}
+
+func Test_Polygon_contains(t *testing.T) {
+	p := Polygon{{50.0, 87.0}, {51.0, 88.5}, {50.5, 89.0}, {49.5, 87.5}}
+
+	tests := []struct {
+		lat, lon float64
+		want     bool
+	}{
+		{50.3, 88.0, true},
+		{50.0, 87.1, true},
+		{51.0, 87.0, false},
+		{49.5, 89.0, false},
+	}
+
+	for _, test := range tests {
+		if res := p.contains(test.lat, test.lon); res != test.want {
+			t.Errorf("Test_Polygon_contains: (%v, %v): want: %v, result: %v", test.lat, test.lon, test.want, res)
+		}
+	}
+}
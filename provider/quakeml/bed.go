@@ -0,0 +1,87 @@
+package quakeml
+
+import "encoding/xml"
+
+// Document represents the root element of a QuakeML document.
+// Only the elements of the Basic Event Description (BED) needed
+// to represent messages are declared.
+type Document struct {
+	XMLName         xml.Name
+	XmlnsQ          string          `xml:"xmlns:q,attr,omitempty"`
+	Xmlns           string          `xml:"xmlns,attr,omitempty"`
+	EventParameters EventParameters `xml:"eventParameters"`
+}
+
+// EventParameters represents the container of events.
+type EventParameters struct {
+	PublicID string  `xml:"publicID,attr"`
+	Events   []Event `xml:"event"`
+}
+
+// Event represents a seismic event description.
+type Event struct {
+	PublicID             string        `xml:"publicID,attr"`
+	PreferredOriginID    string        `xml:"preferredOriginID,omitempty"`
+	PreferredMagnitudeID string        `xml:"preferredMagnitudeID,omitempty"`
+	Type                 string        `xml:"type,omitempty"`
+	Comments             []Comment     `xml:"comment"`
+	CreationInfo         *CreationInfo `xml:"creationInfo"`
+	Origins              []Origin      `xml:"origin"`
+	Magnitudes           []Magnitude   `xml:"magnitude"`
+}
+
+// Origin represents the focal time and geographical location of an event.
+type Origin struct {
+	PublicID         string         `xml:"publicID,attr"`
+	Time             TimeQuantity   `xml:"time"`
+	Latitude         RealQuantity   `xml:"latitude"`
+	Longitude        RealQuantity   `xml:"longitude"`
+	Depth            *RealQuantity  `xml:"depth"`
+	Quality          *OriginQuality `xml:"quality"`
+	EvaluationMode   string         `xml:"evaluationMode,omitempty"`
+	EvaluationStatus string         `xml:"evaluationStatus,omitempty"`
+	CreationInfo     *CreationInfo  `xml:"creationInfo"`
+}
+
+// OriginQuality represents parameters describing the quality
+// of an origin determination.
+type OriginQuality struct {
+	UsedPhaseCount   string `xml:"usedPhaseCount,omitempty"`
+	UsedStationCount string `xml:"usedStationCount,omitempty"`
+	AzimuthalGap     string `xml:"azimuthalGap,omitempty"`
+}
+
+// Magnitude represents a magnitude estimation of an event.
+type Magnitude struct {
+	PublicID     string        `xml:"publicID,attr"`
+	Mag          RealQuantity  `xml:"mag"`
+	Type         string        `xml:"type,omitempty"`
+	OriginID     string        `xml:"originID,omitempty"`
+	CreationInfo *CreationInfo `xml:"creationInfo"`
+}
+
+// RealQuantity represents a physical quantity with its uncertainty.
+type RealQuantity struct {
+	Value       string `xml:"value"`
+	Uncertainty string `xml:"uncertainty,omitempty"`
+}
+
+// TimeQuantity represents a point in time with its uncertainty.
+type TimeQuantity struct {
+	Value       string `xml:"value"`
+	Uncertainty string `xml:"uncertainty,omitempty"`
+}
+
+// Comment represents a free-form comment of an element.
+type Comment struct {
+	ID   string `xml:"id,attr,omitempty"`
+	Text string `xml:"text"`
+}
+
+// CreationInfo represents information about the creation of an element.
+type CreationInfo struct {
+	AgencyID     string `xml:"agencyID,omitempty"`
+	Author       string `xml:"author,omitempty"`
+	CreationTime string `xml:"creationTime,omitempty"`
+	Version      string `xml:"version,omitempty"`
+}
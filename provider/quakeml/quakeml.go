@@ -0,0 +1,537 @@
+// Package seismo/provider/quakeml provides encoding and decoding of seismic
+// event messages (provider.Message) to and from QuakeML 1.2 documents
+// (Basic Event Description).
+//
+// Every message is represented by an event with one origin and one magnitude,
+// which are the preferred ones of the event. The event type corresponds to
+// the message EventType, the evaluation mode and status of the origin
+// correspond to the message EventQuality:
+//
+//	Preliminary - automatic, preliminary
+//	Good        - manual, confirmed
+//	Excellent   - manual, reviewed
+//
+// Optional message fields are kept in the corresponding BED elements:
+// the depth (converted between kilometres and metres) and uncertainties
+// of the origin, its quality (station and phase counts, azimuthal gap),
+// the magnitude type and the creation info (author and agency) of the origin.
+// Extra attributes are kept in comments of the event.
+//
+// The version and update time of a message are kept in the creation info
+// of the event. A retraction is encoded as an origin with the "rejected"
+// evaluation status, such an origin or an event of the "not existing" type
+// is decoded as a retraction.
+//
+// Public identifiers of encoded events are built as "smi:seismo/<SourceId>/<EventId>",
+// so messages encoded by the package are decoded with the same source and event
+// identifiers. The Link of a message is kept in a comment of its event.
+// For events of other documents, the identifier is extracted from the public id
+// (e.g. the "eventid" query parameter or the last segment of the public id).
+package quakeml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"seismo/provider"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// BedNamespace is the namespace of the QuakeML BED elements.
+	BedNamespace = "http://quakeml.org/xmlns/bed/1.2"
+
+	// Namespace is the namespace of the QuakeML root element.
+	Namespace = "http://quakeml.org/xmlns/quakeml/1.2"
+
+	//idPrefix is the prefix of public ids of encoded elements
+	idPrefix = "smi:seismo/"
+
+	//linkSuffix is the suffix of the id of a comment containing a message link
+	linkSuffix = "/link"
+
+	//rejectedStatus is the evaluation status of rejected origins
+	rejectedStatus = "rejected"
+
+	//notExistingType is the type of deleted events
+	notExistingType = "not existing"
+
+	//extraInfix precedes the key in the id of a comment containing an extra attribute
+	extraInfix = "/extra/"
+)
+
+// Marshal returns a QuakeML document representing the messages and an error.
+// If the returned error is not nil, the returned slice is nil.
+func Marshal(msgs ...provider.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, msgs); err != nil {
+		return nil, fmt.Errorf("Marshal: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal returns messages decoded from the QuakeML document "data" and an error.
+// If the returned error is not nil, the returned slice is nil.
+func Unmarshal(data []byte) ([]provider.Message, error) {
+	msgs, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshal: %w", err)
+	}
+
+	return msgs, nil
+}
+
+// Encode writes a QuakeML document representing the messages into "w".
+func Encode(w io.Writer, msgs []provider.Message) error {
+	doc := Document{
+		XMLName: xml.Name{Local: "q:quakeml"},
+		XmlnsQ:  Namespace,
+		Xmlns:   BedNamespace,
+	}
+	doc.EventParameters.PublicID = idPrefix + "eventParameters"
+	doc.EventParameters.Events = make([]Event, 0, len(msgs))
+	for _, m := range msgs {
+		doc.EventParameters.Events = append(doc.EventParameters.Events, FromMessage(m))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("Encode: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("Encode: %w", err)
+	}
+
+	return nil
+}
+
+// Decode reads a QuakeML document from "r" and returns messages
+// built from its events and an error.
+// If the returned error is not nil, the returned slice is nil.
+func Decode(r io.Reader) ([]provider.Message, error) {
+	var doc Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Decode: %w", err)
+	}
+
+	msgs := make([]provider.Message, 0, len(doc.EventParameters.Events))
+	for _, e := range doc.EventParameters.Events {
+		m, err := e.Message()
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+
+	return msgs, nil
+}
+
+// FromMessage returns an event representing the message "m".
+func FromMessage(m provider.Message) Event {
+	id := idPrefix + url.PathEscape(m.SourceId) + "/" + url.PathEscape(m.EventId)
+
+	o := Origin{
+		PublicID: id + "/origin",
+		Time: TimeQuantity{
+			Value:       m.FocusTime.UTC().Format(time.RFC3339Nano),
+			Uncertainty: formatOptFloat(m.TimeUncertainty),
+		},
+		Latitude:  RealQuantity{Value: formatFloat(m.Latitude), Uncertainty: formatOptFloat(m.LatitudeUncertainty)},
+		Longitude: RealQuantity{Value: formatFloat(m.Longitude), Uncertainty: formatOptFloat(m.LongitudeUncertainty)},
+	}
+	o.EvaluationMode, o.EvaluationStatus = evaluation(m.Quality)
+	if m.Action == provider.Retract {
+		o.EvaluationStatus = rejectedStatus
+	}
+
+	if m.Depth != nil {
+		o.Depth = &RealQuantity{Value: formatFloat(kmToM(*m.Depth))}
+		if m.DepthUncertainty != nil {
+			o.Depth.Uncertainty = formatFloat(kmToM(*m.DepthUncertainty))
+		}
+	}
+
+	if m.StationCount != nil || m.PhaseCount != nil || m.AzimuthalGap != nil {
+		o.Quality = &OriginQuality{
+			UsedPhaseCount:   formatOptInt(m.PhaseCount),
+			UsedStationCount: formatOptInt(m.StationCount),
+			AzimuthalGap:     formatOptFloat(m.AzimuthalGap),
+		}
+	}
+
+	if m.Author != "" || m.Agency != "" {
+		o.CreationInfo = &CreationInfo{AgencyID: m.Agency, Author: m.Author}
+	}
+
+	mg := Magnitude{
+		PublicID: id + "/magnitude",
+		Mag:      RealQuantity{Value: formatFloat(m.Magnitude)},
+		Type:     m.MagType,
+		OriginID: o.PublicID,
+	}
+
+	e := Event{
+		PublicID:             id,
+		PreferredOriginID:    o.PublicID,
+		PreferredMagnitudeID: mg.PublicID,
+		Type:                 eventTypeName(m.Type),
+		Origins:              []Origin{o},
+		Magnitudes:           []Magnitude{mg},
+	}
+
+	if m.Version != 0 || !m.UpdateTime.IsZero() {
+		e.CreationInfo = &CreationInfo{}
+		if m.Version != 0 {
+			e.CreationInfo.Version = strconv.Itoa(m.Version)
+		}
+		if !m.UpdateTime.IsZero() {
+			e.CreationInfo.CreationTime = m.UpdateTime.UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	if m.Link != "" {
+		e.Comments = append(e.Comments, Comment{ID: id + linkSuffix, Text: m.Link})
+	}
+
+	keys := make([]string, 0, len(m.Extra))
+	for k := range m.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.Comments = append(e.Comments, Comment{ID: id + extraInfix + url.PathEscape(k), Text: m.Extra[k]})
+	}
+
+	return e
+}
+
+// Message returns a message built from the preferred origin and magnitude
+// of the event and an error. If the preferred ones are not specified,
+// the first ones are used. If the returned error is not nil, the returned
+// message is the zero value.
+func (e *Event) Message() (provider.Message, error) {
+	if len(e.Origins) == 0 {
+		return provider.Message{}, fmt.Errorf("Message: event %q has no origin", e.PublicID)
+	}
+
+	o := e.Origins[0]
+	for _, v := range e.Origins {
+		if v.PublicID == e.PreferredOriginID {
+			o = v
+		}
+	}
+
+	var m provider.Message
+	var err error
+
+	m.SourceId, m.EventId = e.ids()
+
+	if m.FocusTime, err = time.Parse(time.RFC3339Nano, o.Time.Value); err != nil {
+		return provider.Message{}, fmt.Errorf("Message: event %q: parse time: %w", e.PublicID, err)
+	}
+	m.FocusTime = m.FocusTime.UTC()
+
+	if m.Latitude, err = strconv.ParseFloat(o.Latitude.Value, 64); err != nil {
+		return provider.Message{}, fmt.Errorf("Message: event %q: parse latitude: %w", e.PublicID, err)
+	}
+
+	if m.Longitude, err = strconv.ParseFloat(o.Longitude.Value, 64); err != nil {
+		return provider.Message{}, fmt.Errorf("Message: event %q: parse longitude: %w", e.PublicID, err)
+	}
+
+	if len(e.Magnitudes) > 0 {
+		mg := e.Magnitudes[0]
+		for _, v := range e.Magnitudes {
+			if v.PublicID == e.PreferredMagnitudeID {
+				mg = v
+			}
+		}
+
+		if m.Magnitude, err = strconv.ParseFloat(mg.Mag.Value, 64); err != nil {
+			return provider.Message{}, fmt.Errorf("Message: event %q: parse magnitude: %w", e.PublicID, err)
+		}
+		m.MagType = mg.Type
+	}
+
+	if err = o.optional(&m); err != nil {
+		return provider.Message{}, fmt.Errorf("Message: event %q: %w", e.PublicID, err)
+	}
+
+	if err = e.revision(&m, &o); err != nil {
+		return provider.Message{}, fmt.Errorf("Message: event %q: %w", e.PublicID, err)
+	}
+
+	m.Type = EventType(e.Type)
+	m.Quality = EventQuality(o.EvaluationMode, o.EvaluationStatus)
+
+	if strings.EqualFold(o.EvaluationStatus, rejectedStatus) || strings.EqualFold(e.Type, notExistingType) {
+		m.Action = provider.Retract
+	}
+
+	for _, c := range e.Comments {
+		if c.ID == e.PublicID+linkSuffix {
+			m.Link = c.Text
+			continue
+		}
+
+		if k, ok := cutPrefix(c.ID, e.PublicID+extraInfix); ok {
+			if k, err := url.PathUnescape(k); err == nil {
+				if m.Extra == nil {
+					m.Extra = map[string]string{}
+				}
+				m.Extra[k] = c.Text
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// revision fills the author, agency, version and update time of the message
+// from the creation info of the origin "o" and the event. The origin info takes
+// precedence for the author and agency, the event info for the version and time.
+// Versions, which are not integer numbers, are ignored.
+func (e *Event) revision(m *provider.Message, o *Origin) error {
+	infos := make([]*CreationInfo, 0, 2)
+	for _, ci := range []*CreationInfo{o.CreationInfo, e.CreationInfo} {
+		if ci != nil {
+			infos = append(infos, ci)
+		}
+	}
+
+	for _, ci := range infos {
+		if m.Author == "" && m.Agency == "" {
+			m.Author, m.Agency = ci.Author, ci.AgencyID
+		}
+	}
+
+	for i := len(infos) - 1; i >= 0; i-- {
+		ci := infos[i]
+		if v, err := strconv.Atoi(strings.TrimSpace(ci.Version)); err == nil && m.Version == 0 {
+			m.Version = v
+		}
+
+		if ci.CreationTime != "" && m.UpdateTime.IsZero() {
+			t, err := time.Parse(time.RFC3339Nano, ci.CreationTime)
+			if err != nil {
+				return fmt.Errorf("revision: parse creation time: %w", err)
+			}
+			m.UpdateTime = t.UTC()
+		}
+	}
+
+	return nil
+}
+
+// optional fills the optional message fields (depth, uncertainties
+// and quality parameters) specified by the origin.
+func (o *Origin) optional(m *provider.Message) error {
+	var err error
+
+	if m.TimeUncertainty, err = parseOptFloat(o.Time.Uncertainty); err != nil {
+		return fmt.Errorf("optional: parse time uncertainty: %w", err)
+	}
+
+	if m.LatitudeUncertainty, err = parseOptFloat(o.Latitude.Uncertainty); err != nil {
+		return fmt.Errorf("optional: parse latitude uncertainty: %w", err)
+	}
+
+	if m.LongitudeUncertainty, err = parseOptFloat(o.Longitude.Uncertainty); err != nil {
+		return fmt.Errorf("optional: parse longitude uncertainty: %w", err)
+	}
+
+	if o.Depth != nil {
+		if m.Depth, err = parseOptFloat(o.Depth.Value); err != nil {
+			return fmt.Errorf("optional: parse depth: %w", err)
+		}
+		if m.DepthUncertainty, err = parseOptFloat(o.Depth.Uncertainty); err != nil {
+			return fmt.Errorf("optional: parse depth uncertainty: %w", err)
+		}
+		for _, v := range []*float64{m.Depth, m.DepthUncertainty} {
+			if v != nil {
+				*v = mToKm(*v)
+			}
+		}
+	}
+
+	if o.Quality != nil {
+		if m.PhaseCount, err = parseOptInt(o.Quality.UsedPhaseCount); err != nil {
+			return fmt.Errorf("optional: parse used phase count: %w", err)
+		}
+		if m.StationCount, err = parseOptInt(o.Quality.UsedStationCount); err != nil {
+			return fmt.Errorf("optional: parse used station count: %w", err)
+		}
+		if m.AzimuthalGap, err = parseOptFloat(o.Quality.AzimuthalGap); err != nil {
+			return fmt.Errorf("optional: parse azimuthal gap: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ids returns the source and event identifiers of the event.
+// The source identifier is known only for events encoded by the package.
+func (e *Event) ids() (sourceId string, eventId string) {
+	if strings.HasPrefix(e.PublicID, idPrefix) {
+		if src, ev, ok := strings.Cut(strings.TrimPrefix(e.PublicID, idPrefix), "/"); ok {
+			src, err1 := url.PathUnescape(src)
+			ev, err2 := url.PathUnescape(ev)
+			if err1 == nil && err2 == nil {
+				return src, ev
+			}
+		}
+	}
+
+	id := e.PublicID
+	if i := strings.Index(id, "eventid="); i >= 0 {
+		id = id[i+len("eventid="):]
+		if j := strings.IndexAny(id, "&;"); j >= 0 {
+			id = id[:j]
+		}
+		return "", id
+	}
+
+	if i := strings.LastIndexAny(id, "/="); i >= 0 {
+		id = id[i+1:]
+	}
+
+	return "", id
+}
+
+// EventType converts a QuakeML event type to the corresponding EventType value.
+func EventType(s string) provider.EventType {
+	return provider.ParseEventType(s)
+}
+
+// eventTypeName converts an EventType value to the corresponding QuakeML event type.
+// The returned string is empty for unknown types.
+func eventTypeName(t provider.EventType) string {
+	return string(t)
+}
+
+// EventQuality converts QuakeML evaluation mode and status values
+// to the corresponding EventQuality value. The status takes precedence
+// over the mode.
+func EventQuality(mode string, status string) provider.EventQuality {
+	switch strings.ToLower(status) {
+	case "final", "reviewed":
+		return provider.Excellent
+	case "confirmed":
+		return provider.Good
+	case "preliminary":
+		return provider.Preliminary
+	}
+
+	switch strings.ToLower(mode) {
+	case "manual":
+		return provider.Good
+	case "automatic":
+		return provider.Preliminary
+	default:
+		return provider.UnknownQuality
+	}
+}
+
+// evaluation converts an EventQuality value to the corresponding QuakeML
+// evaluation mode and status. The returned strings are empty for unknown quality.
+func evaluation(q provider.EventQuality) (mode string, status string) {
+	switch q {
+	case provider.Preliminary:
+		return "automatic", "preliminary"
+	case provider.Good:
+		return "manual", "confirmed"
+	case provider.Excellent:
+		return "manual", "reviewed"
+	default:
+		return "", ""
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatOptFloat returns an empty string for nil.
+func formatOptFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatFloat(*v)
+}
+
+// formatOptInt returns an empty string for nil.
+func formatOptInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// parseOptFloat returns nil for an empty string.
+func parseOptFloat(s string) (*float64, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// parseOptInt returns nil for an empty string.
+func parseOptInt(s string) (*int, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// kmToM converts kilometres to metres.
+func kmToM(v float64) float64 {
+	return shift(v, 3)
+}
+
+// mToKm converts metres to kilometres.
+func mToKm(v float64) float64 {
+	return shift(v, -3)
+}
+
+// shift returns v multiplied by 10^exp. The multiplication is done
+// on the decimal representation of v, so values survive
+// a round trip without binary rounding errors.
+func shift(v float64, exp int) float64 {
+	mant, e, _ := strings.Cut(strconv.FormatFloat(v, 'e', -1, 64), "e")
+	n, err := strconv.Atoi(e)
+	if err != nil {
+		return v * math.Pow10(exp)
+	}
+
+	r, err := strconv.ParseFloat(mant+"e"+strconv.Itoa(n+exp), 64)
+	if err != nil {
+		return v * math.Pow10(exp)
+	}
+	return r
+}
+
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
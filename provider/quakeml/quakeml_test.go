@@ -0,0 +1,193 @@
+package quakeml
+
+import (
+	"encoding/xml"
+	"os"
+	"seismo/provider"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_MarshalUnmarshal(t *testing.T) {
+	want := []provider.Message{
+		{
+			SourceId:  "seishub",
+			FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC),
+			Latitude:  54.71,
+			Longitude: 83.67,
+			Magnitude: 3.3,
+			EventId:   "asb2023eesfwx",
+			Type:      provider.QuarryBlast,
+			Quality:   provider.Excellent,
+			Link:      "http://seishub.ru/pipermail/seismic-report/2023-March/021128.html",
+		},
+		{
+			SourceId:  "pseudo/1",
+			FocusTime: time.Date(2023, 3, 2, 0, 0, 1, 0, time.UTC),
+			Latitude:  -21.38,
+			Longitude: -174.62,
+			Magnitude: 4.8,
+			EventId:   "a b&c",
+			Type:      provider.EarthQuake,
+			Quality:   provider.Preliminary,
+
+			Depth:                provider.Float(10.3),
+			MagType:              "Mw",
+			TimeUncertainty:      provider.Float(0.12),
+			LatitudeUncertainty:  provider.Float(0.017),
+			LongitudeUncertainty: provider.Float(0.021),
+			DepthUncertainty:     provider.Float(2.27),
+			StationCount:         provider.Int(23),
+			PhaseCount:           provider.Int(41),
+			AzimuthalGap:         provider.Float(74.5),
+			Author:               "scautoloc",
+			Agency:               "GFZ",
+			Extra:                map[string]string{"region": "Tonga Islands", "mag/author": "x y"},
+
+			Action:     provider.Retract,
+			Version:    3,
+			UpdateTime: time.Date(2023, 3, 2, 0, 10, 0, 0, time.UTC),
+		},
+		{
+			SourceId:  "pseudo_2",
+			FocusTime: time.Date(2023, 3, 3, 0, 0, 0, 0, time.UTC),
+			EventId:   "3",
+			Type:      provider.UnknownType,
+			Quality:   provider.Good,
+		},
+		{
+			SourceId: "pseudo_2",
+			EventId:  "4",
+			Type:     provider.EarthQuake,
+			Quality:  provider.UnknownQuality,
+		},
+	}
+
+	b, err := Marshal(want...)
+	if err != nil {
+		t.Fatalf("Test_MarshalUnmarshal: Marshal: %v", err)
+	}
+
+	res, err := Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Test_MarshalUnmarshal: Unmarshal: %v\n%s", err, b)
+	}
+
+	if !cmp.Equal(want, res) {
+		t.Errorf("Test_MarshalUnmarshal: %s", cmp.Diff(want, res))
+	}
+}
+
+func Test_Marshal_Document(t *testing.T) {
+	b, err := Marshal(provider.Message{SourceId: "seishub", EventId: "asb2023eesfwx", Quality: provider.Excellent})
+	if err != nil {
+		t.Fatalf("Test_Marshal_Document: %v", err)
+	}
+
+	//check namespaces and BED elements with a namespace-aware decoding
+	var doc struct {
+		XMLName xml.Name
+		Events  []struct {
+			XMLName xml.Name
+			Origin  struct {
+				Mode   string `xml:"evaluationMode"`
+				Status string `xml:"evaluationStatus"`
+			} `xml:"origin"`
+		} `xml:"eventParameters>event"`
+	}
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("Test_Marshal_Document: %v", err)
+	}
+
+	if doc.XMLName.Space != Namespace || doc.XMLName.Local != "quakeml" {
+		t.Errorf("Test_Marshal_Document: root: %v", doc.XMLName)
+	}
+
+	if len(doc.Events) != 1 || doc.Events[0].XMLName.Space != BedNamespace {
+		t.Fatalf("Test_Marshal_Document: events: %v", doc.Events)
+	}
+
+	if doc.Events[0].Origin.Status != "reviewed" {
+		t.Errorf("Test_Marshal_Document: want evaluation status: reviewed, res: %q", doc.Events[0].Origin.Status)
+	}
+}
+
+func Test_Decode(t *testing.T) {
+	f, err := os.Open("testdata/usgs.xml")
+	if err != nil {
+		t.Fatalf("Test_Decode: %v", err)
+	}
+	defer f.Close()
+
+	res, err := Decode(f)
+	if err != nil {
+		t.Fatalf("Test_Decode: %v", err)
+	}
+
+	want := []provider.Message{
+		{
+			EventId:          "us7000jk3l",
+			FocusTime:        time.Date(2023, 3, 1, 7, 21, 19, 458000000, time.UTC),
+			Latitude:         2.4506,
+			Longitude:        127.3547,
+			Magnitude:        4.7,
+			Type:             provider.EarthQuake,
+			Quality:          provider.Excellent,
+			Depth:            provider.Float(35),
+			MagType:          "mb",
+			DepthUncertainty: provider.Float(1.9),
+			StationCount:     provider.Int(41),
+			PhaseCount:       provider.Int(41),
+			AzimuthalGap:     provider.Float(74),
+			Agency:           "us",
+
+			UpdateTime: time.Date(2023, 3, 1, 7, 40, 10, 40000000, time.UTC),
+		},
+		{
+			EventId:   "gfz2023eesfwx",
+			FocusTime: time.Date(2023, 3, 1, 5, 13, 16, 430000000, time.UTC),
+			Latitude:  54.71,
+			Longitude: 83.67,
+			Magnitude: 3.3,
+			Type:      provider.QuarryBlast,
+			Quality:   provider.Preliminary,
+			MagType:   "mb",
+		},
+	}
+
+	if !cmp.Equal(want, res) {
+		t.Errorf("Test_Decode: %s", cmp.Diff(want, res))
+	}
+}
+
+func Test_ids(t *testing.T) {
+	tests := []struct {
+		publicID   string
+		wantSource string
+		wantEvent  string
+	}{
+		{"quakeml:earthquake.usgs.gov/fdsnws/event/1/query?eventid=us7000jk3l&format=quakeml", "", "us7000jk3l"},
+		{"smi:org.gfz-potsdam.de/geofon/gfz2023eesfwx", "", "gfz2023eesfwx"},
+		{"smi:ISC/evid=625394427", "", "625394427"},
+		{"20230301_0000042", "", "20230301_0000042"},
+		{"smi:seismo/seishub/asb2023eesfwx", "seishub", "asb2023eesfwx"},
+		{"smi:seismo/pseudo%2F1/a%20b", "pseudo/1", "a b"},
+	}
+
+	for _, test := range tests {
+		e := Event{PublicID: test.publicID}
+		if src, ev := e.ids(); src != test.wantSource || ev != test.wantEvent {
+			t.Errorf("Test_ids: publicID: %s want: %q %q res: %q %q", test.publicID, test.wantSource, test.wantEvent, src, ev)
+		}
+	}
+}
+
+func Test_EventQuality(t *testing.T) {
+	for _, q := range []provider.EventQuality{provider.UnknownQuality, provider.Preliminary, provider.Good, provider.Excellent} {
+		if res := EventQuality(evaluation(q)); res != q {
+			t.Errorf("Test_EventQuality: want: %v res: %v", q, res)
+		}
+	}
+}
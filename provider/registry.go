@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a new watcher configured by "conf" and returns it and an error.
+// If the returned error is not nil, the returned watcher is nil.
+type Factory func(conf WatcherConfig) (Watcher, error)
+
+// ConfigSchema describes the configuration accepted by watchers of a provider type.
+type ConfigSchema struct {
+	// Description briefly describes the provider.
+	Description string
+
+	// ConnStr describes the format of the connection string.
+	ConnStr string
+
+	// DefConnStr specifies the default connection string, if the provider has one.
+	DefConnStr string
+
+	// Options is a value of the options struct of the provider holding
+	// default options, or nil if the provider has no options.
+	Options interface{}
+}
+
+// Registration describes a provider type registered in the registry.
+type Registration struct {
+	// T specifies the provider type.
+	T ProviderType
+
+	// Factory creates watchers of the type.
+	Factory Factory
+
+	// Schema describes the configuration of watchers of the type.
+	Schema ConfigSchema
+
+	// Rules specifies the rules checked for messages of sources of the type
+	// in addition to DefRules (see RulesFor). Optional.
+	Rules RuleSet
+}
+
+// registry keeps registered provider types.
+var registry = struct {
+	sync.RWMutex
+	regs map[ProviderType]Registration
+}{regs: make(map[ProviderType]Registration)}
+
+// Register makes a provider type available for creating watchers by NewWatcher.
+// Provider packages call it in their init functions, so linking a package
+// (e.g. by a blank import) into an application is enough to use its watchers.
+//
+// Register panics if the type is empty, the factory is nil
+// or the type is already registered.
+func Register(r Registration) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if r.T == "" {
+		panic("Register: empty provider type")
+	}
+	if r.Factory == nil {
+		panic(fmt.Sprintf("Register: nil factory of provider type %q", r.T))
+	}
+	if _, ok := registry.regs[r.T]; ok {
+		panic(fmt.Sprintf("Register: provider type %q is registered twice", r.T))
+	}
+
+	registry.regs[r.T] = r
+}
+
+// Lookup returns the registration of the provider type "t" and true,
+// or the zero value and false if the type is not registered.
+func Lookup(t ProviderType) (Registration, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	r, ok := registry.regs[t]
+	return r, ok
+}
+
+// Registered returns registrations of all registered provider types sorted by type.
+func Registered() []Registration {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	regs := make([]Registration, 0, len(registry.regs))
+	for _, r := range registry.regs {
+		regs = append(regs, r)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].T < regs[j].T })
+
+	return regs
+}
+
+// NewWatcher creates a new watcher by the factory of the provider type specified
+// in "conf" and returns it and an error. If the type is not registered,
+// the returned error is UnknownTypeErr. If the returned error is not nil,
+// the returned watcher is nil.
+func NewWatcher(conf WatcherConfig) (Watcher, error) {
+	r, ok := Lookup(conf.T)
+	if !ok {
+		return nil, fmt.Errorf("NewWatcher: %w", UnknownTypeErr{T: conf.T, Registered: registeredTypes()})
+	}
+
+	w, err := r.Factory(conf)
+	if err != nil {
+		return nil, fmt.Errorf("NewWatcher: %w", err)
+	}
+
+	return w, nil
+}
+
+func registeredTypes() []ProviderType {
+	regs := Registered()
+	types := make([]ProviderType, 0, len(regs))
+	for _, r := range regs {
+		types = append(types, r.T)
+	}
+
+	return types
+}
+
+// UnknownTypeErr indicates that a provider type is not registered.
+type UnknownTypeErr struct {
+	T ProviderType
+
+	// Registered lists the registered types.
+	Registered []ProviderType
+}
+
+func (e UnknownTypeErr) Error() string {
+	names := make([]string, 0, len(e.Registered))
+	for _, t := range e.Registered {
+		names = append(names, string(t))
+	}
+
+	return fmt.Sprintf("unknown watcher type: %q; registered types: %s", e.T, strings.Join(names, ", "))
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testWatcher is a Watcher doing nothing.
+type testWatcher struct {
+	conf WatcherConfig
+}
+
+func (w *testWatcher) StartWatch(ctx context.Context, from time.Time) (<-chan Message, error) {
+	return nil, fmt.Errorf("StartWatch: not implemented")
+}
+
+func (w *testWatcher) StateInfo() WatcherStateInfo { return Stopped }
+
+func (w *testWatcher) Stats() WatcherStats { return WatcherStats{State: Stopped} }
+
+func (w *testWatcher) GetConfig() WatcherConfig { return w.conf }
+
+func Test_Registry(t *testing.T) {
+	const testType ProviderType = "registry_test"
+
+	Register(Registration{
+		T: testType,
+		Factory: func(conf WatcherConfig) (Watcher, error) {
+			if conf.ConnStr == "bad" {
+				return nil, fmt.Errorf("bad connection string")
+			}
+			return &testWatcher{conf: conf}, nil
+		},
+		Schema: ConfigSchema{Description: "test", ConnStr: "anything"},
+	})
+
+	if r, ok := Lookup(testType); !ok || r.Schema.Description != "test" {
+		t.Errorf("Test_Registry: Lookup: unexpected result: %v %v", r, ok)
+	}
+
+	found := false
+	regs := Registered()
+	for i, r := range regs {
+		found = found || r.T == testType
+		if i > 0 && regs[i-1].T >= r.T {
+			t.Errorf("Test_Registry: Registered: unsorted types: %v", regs)
+		}
+	}
+	if !found {
+		t.Errorf("Test_Registry: Registered: %q is not listed", testType)
+	}
+
+	w, err := NewWatcher(WatcherConfig{Id: "test_1", T: testType})
+	if err != nil || w.GetConfig().Id != "test_1" {
+		t.Errorf("Test_Registry: NewWatcher: unexpected result: %v %v", w, err)
+	}
+
+	if _, err := NewWatcher(WatcherConfig{Id: "test_1", T: testType, ConnStr: "bad"}); err == nil {
+		t.Errorf("Test_Registry: NewWatcher: a factory error is expected")
+	}
+
+	_, err = NewWatcher(WatcherConfig{Id: "test_1", T: "no_such_type"})
+	var ute UnknownTypeErr
+	if !errors.As(err, &ute) || ute.T != "no_such_type" {
+		t.Fatalf("Test_Registry: NewWatcher: UnknownTypeErr is expected, result: %v", err)
+	}
+	found = false
+	for _, rt := range ute.Registered {
+		found = found || rt == testType
+	}
+	if !found {
+		t.Errorf("Test_Registry: UnknownTypeErr does not list %q: %v", testType, err)
+	}
+
+	for _, r := range []Registration{
+		{T: testType, Factory: func(conf WatcherConfig) (Watcher, error) { return nil, nil }},
+		{T: "registry_test_nil"},
+		{Factory: func(conf WatcherConfig) (Watcher, error) { return nil, nil }},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Test_Registry: Register: a panic is expected for %v", r)
+				}
+			}()
+			Register(r)
+		}()
+	}
+}
@@ -0,0 +1,273 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"seismo/provider"
+	"sync"
+	"time"
+)
+
+// hubState is implemented to provide a specific behavior within THE STATE PATTERN.
+type hubState interface {
+	startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error)
+	stateInfo() provider.WatcherStateInfo
+}
+
+// stoppedState implements a stopped Hub's behavior within THE STATE PATTERN.
+type stoppedState struct {
+	hub *Hub
+}
+
+func newStoppedState(h *Hub) *stoppedState {
+	return &stoppedState{hub: h}
+}
+
+// startWatch implements the behaivor of Hub.StartWatch in the "stopped" state,
+// i.e. reads recorded messages, starts replaying them and returns a channel for fetching messages.
+// If the returned error is not nil, the returned channel is nil.
+func (s *stoppedState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("cannot start with canceled context")
+	}
+
+	h := s.hub
+	msgs, err := ReadPath(h.path)
+	if err != nil {
+		h.stats.FetchFailed(err)
+		return nil, err
+	}
+	h.stats.Polled()
+
+	//skip messages before "from"; messages are sorted by FocusTime
+	i := 0
+	for i < len(msgs) && msgs[i].FocusTime.Before(from) {
+		i++
+	}
+
+	h.setState(newRunState(h))
+	o := make(chan provider.Message)
+	go h.replay(ctx, o, msgs[i:])
+
+	return o, nil
+}
+
+func (s *stoppedState) stateInfo() provider.WatcherStateInfo {
+	return provider.Stopped
+}
+
+// runState implements a running Hub's behavior within THE STATE PATTERN.
+type runState struct {
+	hub *Hub
+}
+
+func newRunState(h *Hub) *runState {
+	return &runState{hub: h}
+}
+
+func (r *runState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	return nil, provider.AlreadyRunErr{}
+}
+
+func (r *runState) stateInfo() provider.WatcherStateInfo {
+	return provider.Run
+}
+
+// Hub implements the provider.Watcher interface,
+// replays recorded messages read from files addressed by the connection string.
+// CheckPeriod and Timeout of the configuration are not used.
+type Hub struct {
+	config provider.WatcherConfig
+
+	//state implements THE STATE PATTERN
+	state hubState
+
+	//mu guards the state, since the replaying go-routine stops the Hub
+	//concurrently with StateInfo and StartWatch calls.
+	mu sync.Mutex
+
+	//lifecycle publishes lifecycle events of the Hub
+	lifecycle provider.Lifecycle
+
+	//stats keeps statistics of the Hub
+	stats provider.StatsRecorder
+
+	//path specifies a file or a directory of recorded messages
+	path string
+
+	//speed specifies the acceleration factor; 0 means replaying without delays
+	speed float64
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		T: provider.Replay,
+		Factory: func(conf provider.WatcherConfig) (provider.Watcher, error) {
+			h, err := NewHub(conf)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		Schema: provider.ConfigSchema{
+			Description: "Replays messages recorded in json files.",
+			ConnStr:     "\"<path>[?speed=<factor>]\", a file or a directory of recorded messages.",
+		},
+	})
+}
+
+// NewHub returns a pointer to a new replay.Hub in the stopped state
+// configured by "conf" values and an error.
+//
+// If the returned error is not nil, the returned pointer is nil.
+func NewHub(conf provider.WatcherConfig) (*Hub, error) {
+	path, speed, err := ParseConnStr(conf.ConnStr)
+	if err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	if err := provider.NoOptions(conf.Options); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	h := &Hub{config: conf, path: path, speed: speed}
+
+	h.setState(newStoppedState(h))
+
+	return h, nil
+}
+
+// GetConfig returns configuration of the Hub.
+func (h *Hub) GetConfig() provider.WatcherConfig {
+	return h.config
+}
+
+// setState sets the state of the Hub. The caller must hold h.mu,
+// except while the Hub is being created.
+func (h *Hub) setState(s hubState) {
+	prev := h.state
+	h.state = s
+	h.stats.Transited()
+
+	if prev != nil {
+		h.lifecycle.Transit(h.config.Id, prev.stateInfo(), s.stateInfo(), nil)
+	}
+}
+
+// Subscribe implements the provider.Observable interface.
+func (h *Hub) Subscribe(buf int) (<-chan provider.LifecycleEvent, func()) {
+	return h.lifecycle.Subscribe(buf)
+}
+
+// StateInfo reports a current state of the Hub.
+func (h *Hub) StateInfo() provider.WatcherStateInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.state.stateInfo()
+}
+
+// Stats returns the state and statistics of the Hub. Every reading of the recorded
+// messages by StartWatch is counted as a poll, a failed one as a fetch error.
+func (h *Hub) Stats() provider.WatcherStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.Stats(h.state.stateInfo())
+}
+
+// StartWatch reads recorded messages and starts replaying the ones with FocusTime
+// after (or equal to) "from" in the order of FocusTime.
+//
+// The method returns a channel for fetching messages. If the returned error is not nil,
+// e.g. the recorded messages cannot be read, the returned channel is nil.
+//
+// The first message is sent immediately, the next ones are sent according to the gaps
+// between their FocusTime divided by the acceleration factor. The channel is closed
+// after all messages have been sent. Messages without SourceId get the Id of the configuration.
+func (h *Hub) StartWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	o, err := h.state.startWatch(ctx, from)
+	return o, err
+}
+
+// Backfill implements the provider.Backfiller interface. It reads recorded messages
+// and sends the ones with the FocusTime in the [from, to) range in the order
+// of FocusTime immediately, i.e. the acceleration factor is not applied.
+//
+// Backfilling can be performed while the Hub is replaying.
+func (h *Hub) Backfill(ctx context.Context, from time.Time, to time.Time) (<-chan provider.Message, error) {
+	if err := provider.CheckRange(from, to); err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	msgs, err := ReadPath(h.path)
+	if err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	res := make([]provider.Message, 0, len(msgs))
+	for _, m := range msgs {
+		if !provider.InRange(m.FocusTime, from, to) {
+			continue
+		}
+		if m.SourceId == "" {
+			m.SourceId = h.config.Id
+		}
+		res = append(res, m)
+	}
+
+	o := make(chan provider.Message)
+	go func() {
+		defer close(o)
+		provider.SendAll(ctx, o, res)
+	}()
+
+	return o, nil
+}
+
+// replay sends "msgs" into the "o" channel keeping scaled gaps between them.
+// Delays are counted from the start of replaying, so a slow reader
+// does not stretch the sequence.
+func (h *Hub) replay(ctx context.Context, o chan<- provider.Message, msgs []provider.Message) {
+	defer func() {
+		h.mu.Lock()
+		h.setState(newStoppedState(h))
+		h.mu.Unlock()
+		close(o)
+	}()
+
+	start := time.Now()
+	for _, m := range msgs {
+		if h.speed > 0 {
+			offset := time.Duration(float64(m.FocusTime.Sub(msgs[0].FocusTime)) / h.speed)
+			if d := time.Until(start.Add(offset)); d > 0 {
+				t := time.NewTimer(d)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					log.Println("replay: Canceled")
+					return
+				}
+			}
+		}
+
+		if m.SourceId == "" {
+			m.SourceId = h.config.Id
+		}
+
+		select {
+		case o <- m:
+			h.stats.Emitted()
+		case <-ctx.Done():
+			log.Println("replay: Canceled")
+			return
+		}
+	}
+
+	log.Printf("replay: %d messages have been replayed", len(msgs))
+}
@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"context"
+	"seismo/provider"
+	"testing"
+	"time"
+)
+
+func Test_StartWatch(t *testing.T) {
+	//the gaps of the records (10 and 10 seconds) are replayed 100 times faster
+	conf := provider.WatcherConfig{Id: "replay", T: provider.Replay, ConnStr: "testdata/records.jsonl?speed=100"}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("Test_StartWatch: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	//the first record is before "from"
+	start := time.Now()
+	ch, err := h.StartWatch(ctx, time.Date(2023, 3, 1, 5, 13, 20, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch: %v", err)
+	}
+
+	want := []struct {
+		eventId  string
+		sourceId string
+		mag      float64
+	}{
+		{"asb2023eesfwx", "seishub", 3.3},
+		{"asb2023eesfwx", "seishub", 3.4},
+		{"asb2023eestol", "replay", 2.8},
+	}
+
+	var res []provider.Message
+	for m := range ch {
+		res = append(res, m)
+	}
+	elapsed := time.Since(start)
+
+	if len(res) != len(want) {
+		t.Fatalf("Test_StartWatch: want %d messages, result: %d", len(want), len(res))
+	}
+
+	for i, w := range want {
+		if res[i].EventId != w.eventId || res[i].SourceId != w.sourceId || res[i].Magnitude != w.mag {
+			t.Errorf("Test_StartWatch: message %d: want: %v, result: %v", i, w, res[i])
+		}
+	}
+
+	if elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Test_StartWatch: want replaying for about 200ms, result: %v", elapsed)
+	}
+
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_StartWatch: want state: %s, res: %s", provider.Stopped, s)
+	}
+
+	if st := h.Stats(); st.Emitted != 3 || st.LastPoll.IsZero() || st.LastMsg.IsZero() {
+		t.Errorf("Test_StartWatch: unexpected stats: %+v", st)
+	}
+}
+
+func Test_StartWatch_Cancel(t *testing.T) {
+	conf := provider.WatcherConfig{Id: "replay", T: provider.Replay, ConnStr: "testdata/records.jsonl"}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Cancel: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Cancel: %v", err)
+	}
+
+	if _, err := h.StartWatch(ctx, time.Time{}); err == nil {
+		t.Errorf("Test_StartWatch_Cancel: an error is expected for a running hub")
+	}
+
+	<-ch //the second message is 30 seconds later
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Test_StartWatch_Cancel: unexpected message")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Test_StartWatch_Cancel: replaying is not canceled")
+	}
+}
+
+func Test_Backfill(t *testing.T) {
+	conf := provider.WatcherConfig{Id: "replay", T: provider.Replay, ConnStr: "testdata/records.jsonl?speed=0.001"}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	//the slow speed is not applied, so all messages are sent at once
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	from := time.Date(2023, 3, 1, 5, 13, 20, 0, time.UTC)
+	ch, err := h.Backfill(ctx, from, from.Add(40*time.Second))
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	var res []string
+	for m := range ch {
+		res = append(res, m.SourceId+" "+m.EventId)
+	}
+
+	want := []string{"seishub asb2023eesfwx", "seishub asb2023eesfwx"}
+	if len(res) != len(want) || res[0] != want[0] || res[1] != want[1] {
+		t.Errorf("Test_Backfill: want: %v, result: %v", want, res)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Test_Backfill: timeout")
+	}
+}
@@ -0,0 +1,128 @@
+// Package seismo/provider/replay provides a watcher replaying recorded seismic event
+// messages (provider.Message) from files, e.g. to rehearse the Collector and downstream
+// consumers against realistic historical sequences.
+//
+// Files contain JSON encoded messages one after another, e.g. in the JSON Lines format
+// or as separate (even indented) JSON objects like files of the "testdata/json_msg" corpus
+// of the seishub package.
+//
+// The connection string of a watcher specifies a path to a file or a directory
+// and an optional acceleration factor in the following format:
+//
+//	<path>[?speed=<factor>]
+//
+// E.g. "records/2022-February.jsonl?speed=60" replays the messages an hour of the
+// recorded sequence per minute. All files of a directory are read in the order of names.
+// The default factor is 1, i.e. original gaps between events are preserved. The factor 0
+// means replaying without delays.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"seismo/provider"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseConnStr returns a path and an acceleration factor specified by the connection string "s"
+// and an error.
+func ParseConnStr(s string) (path string, speed float64, err error) {
+	path, query, _ := strings.Cut(s, "?")
+	if path == "" {
+		return "", 0, fmt.Errorf("ParseConnStr: %q: empty path", s)
+	}
+
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return "", 0, fmt.Errorf("ParseConnStr: %q: %w", s, err)
+	}
+
+	speed = 1
+	if v := q.Get("speed"); v != "" {
+		speed, err = strconv.ParseFloat(v, 64)
+		if err != nil || speed < 0 {
+			return "", 0, fmt.Errorf("ParseConnStr: %q: the speed must be a non-negative number", s)
+		}
+	}
+
+	return path, speed, nil
+}
+
+// ReadMsgs returns messages decoded from "r" and an error.
+// If the returned error is not nil, the returned slice is nil.
+func ReadMsgs(r io.Reader) ([]provider.Message, error) {
+	var msgs []provider.Message
+	dec := json.NewDecoder(r)
+	for {
+		var m provider.Message
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ReadMsgs: message %d: %w", len(msgs)+1, err)
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+// ReadPath returns messages read from a file or all files of a directory addressed by "path"
+// and sorted by FocusTime, and an error. Messages with the same FocusTime keep the order
+// of reading. If the returned error is not nil, the returned slice is nil.
+func ReadPath(path string) ([]provider.Message, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadPath: %w", err)
+	}
+
+	files := []string{path}
+	if fi.IsDir() {
+		entries, err := os.ReadDir(path) //sorted by name
+		if err != nil {
+			return nil, fmt.Errorf("ReadPath: %w", err)
+		}
+
+		files = files[:0]
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	var msgs []provider.Message
+	for _, name := range files {
+		fm, err := readFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("ReadPath: %w", err)
+		}
+		msgs = append(msgs, fm...)
+	}
+
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].FocusTime.Before(msgs[j].FocusTime)
+	})
+
+	return msgs, nil
+}
+
+func readFile(name string) ([]provider.Message, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("readFile: %w", err)
+	}
+	defer f.Close()
+
+	msgs, err := ReadMsgs(f)
+	if err != nil {
+		return nil, fmt.Errorf("readFile: %q: %w", name, err)
+	}
+
+	return msgs, nil
+}
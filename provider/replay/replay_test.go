@@ -0,0 +1,62 @@
+package replay
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseConnStr(t *testing.T) {
+	tests := []struct {
+		input string
+		path  string
+		speed float64
+	}{
+		{"testdata/records.jsonl", "testdata/records.jsonl", 1},
+		{"testdata/records.jsonl?speed=60", "testdata/records.jsonl", 60},
+		{"testdata?speed=0", "testdata", 0},
+	}
+
+	for _, test := range tests {
+		path, speed, err := ParseConnStr(test.input)
+		if err != nil {
+			t.Fatalf("Test_ParseConnStr: %q: %v", test.input, err)
+		}
+
+		if path != test.path || speed != test.speed {
+			t.Errorf("Test_ParseConnStr: %q: want: %q %v, result: %q %v", test.input, test.path, test.speed, path, speed)
+		}
+	}
+
+	for _, s := range []string{"", "?speed=2", "testdata?speed=-1", "testdata?speed=fast"} {
+		if _, _, err := ParseConnStr(s); err == nil {
+			t.Errorf("Test_ParseConnStr: an error is expected for %q", s)
+		}
+	}
+}
+
+func Test_ReadPath(t *testing.T) {
+	msgs, err := ReadPath("testdata/dir")
+	if err != nil {
+		t.Fatalf("Test_ReadPath: %v", err)
+	}
+
+	want := []string{"asb2022cfjaaa", "asb2022cfjhkl", "asb2022cfjhkl"}
+	if len(msgs) != len(want) {
+		t.Fatalf("Test_ReadPath: want %d messages, result: %d", len(want), len(msgs))
+	}
+
+	for i, id := range want {
+		if msgs[i].EventId != id {
+			t.Errorf("Test_ReadPath: message %d: want event id %q, result: %q", i, id, msgs[i].EventId)
+		}
+	}
+
+	//messages are sorted by FocusTime
+	if want := time.Date(2022, 2, 1, 5, 55, 11, 40000000, time.UTC); !msgs[1].FocusTime.Equal(want) {
+		t.Errorf("Test_ReadPath: want focus time %v, result: %v", want, msgs[1].FocusTime)
+	}
+
+	if _, err := ReadPath("testdata/absent.jsonl"); err == nil {
+		t.Errorf("Test_ReadPath: an error is expected for an absent file")
+	}
+}
@@ -0,0 +1,198 @@
+package seishub
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"seismo/provider"
+	"strings"
+	"time"
+)
+
+// Monthly archives.
+//
+// Pipermail publishes all messages of a month as a text archive in the mbox
+// format, e.g. "http://seishub.ru/pipermail/seismic-report/2022-April.txt.gz".
+// Every message of an archive begins with a separator line like
+// "From automatics at lists.seishub.ru  Tue Feb  1 05:56:54 2022".
+
+// fromLineRe matches mbox separator lines.
+var fromLineRe = regexp.MustCompile(`^From \S.* +[A-Z][a-z]{2} [A-Z][a-z]{2} [ 0-9]?[0-9] [0-9]{2}:[0-9]{2}:[0-9]{2} [0-9]{4}$`)
+
+// escapedFromRe matches body lines beginning with "From ", which are escaped with ">".
+var escapedFromRe = regexp.MustCompile(`^>+From `)
+
+// maxArchiveLine defines the max length of an archive line in bytes.
+const maxArchiveLine = 1024 * 1024
+
+// archiveDelay defines the time after the end of a month when the month is considered
+// archived, i.e. its resources do not change. The delay allows for late mails
+// and differences of time zones.
+const archiveDelay = 24 * time.Hour
+
+// ArchiveName returns a name of a monthly archive in "2022-April.txt.gz" format.
+func ArchiveName(m provider.MonthYear) string {
+	return MonthYearPathSeg(m.Month, m.Year) + ".txt.gz"
+}
+
+// GetArchive returns mails of a monthly archive addressed by "link" and an error.
+// If the returned error is not nil, the returned slice is nil.
+// If the archive is not found, the returned error wraps NotFoundErr.
+//
+// If the "cl" parameter is nil, the function uses the default package-level http client.
+func GetArchive(ctx context.Context, link string, cl *http.Client) ([]*Mail, error) {
+	if cl == nil {
+		cl = &defClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetArchive: link: %q error: %w", link, err)
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GetArchive: link: %q error: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp, link); err != nil {
+		return nil, fmt.Errorf("GetArchive: error: %w", err)
+	}
+
+	mails, err := ReadArchive(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("GetArchive: link: %q error: %w", link, err)
+	}
+
+	return mails, nil
+}
+
+// ReadArchive splits a monthly archive read from "r" into mails and returns them and an error.
+// If the returned error is not nil, the returned slice is nil.
+//
+// The archive can be gzip-compressed or plain text. Mails which cannot be read
+// are skipped.
+func ReadArchive(r io.Reader) ([]*Mail, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("ReadArchive: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+
+	mails := make([]*Mail, 0, avgMonthMsgNum)
+	var buf bytes.Buffer
+	started := false
+
+	flush := func() {
+		if !started {
+			return
+		}
+		m, err := ReadMail(&buf)
+		if err != nil {
+			log.Printf("ReadArchive: %v", err)
+		} else {
+			mails = append(mails, m)
+		}
+		buf.Reset()
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxArchiveLine)
+	for sc.Scan() {
+		line := sc.Text()
+		if fromLineRe.MatchString(line) {
+			flush()
+			started = true
+			continue
+		}
+
+		if !started {
+			continue
+		}
+
+		if escapedFromRe.MatchString(line) {
+			line = line[1:]
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ReadArchive: %w", err)
+	}
+	flush()
+
+	return mails, nil
+}
+
+// GetArchiveMsgs returns seismic event messages extracted from a monthly archive
+// addressed by "link" and an error. If the returned error is not nil, the returned slice is nil.
+//
+// Mails which are not seismic event reports are skipped. The link of a message
+// is the archive link with the message id of the mail as a fragment,
+// since archived mails have no pages.
+//
+// If the "cl" parameter is nil, the function uses the default package-level http client.
+func GetArchiveMsgs(ctx context.Context, link string, cl *http.Client) ([]*provider.Message, error) {
+	mails, err := GetArchive(ctx, link, cl)
+	if err != nil {
+		return nil, fmt.Errorf("GetArchiveMsgs: %w", err)
+	}
+
+	return ArchiveMsgs(mails, link), nil
+}
+
+// ArchiveMsgs returns seismic event messages parsed from mails of a monthly archive
+// addressed by "link". Mails which are not seismic event reports are skipped.
+// The update time of a message is the date of its mail.
+func ArchiveMsgs(mails []*Mail, link string) []*provider.Message {
+	msgs := make([]*provider.Message, 0, len(mails))
+	for _, ml := range mails {
+		m, err := ParseMsg(ml.Body)
+		if err != nil {
+			log.Printf("ArchiveMsgs: message id %q subject %q: %v", ml.MessageId, ml.Subject, err)
+			continue
+		}
+
+		m.Link = link
+		if ml.MessageId != "" {
+			m.Link += "#" + url.PathEscape(ml.MessageId)
+		}
+		m.UpdateTime = ml.Date
+		msgs = append(msgs, m)
+	}
+
+	return msgs
+}
+
+// archived reports whether the URL "u" addresses a resource of an archived month
+// at the time "now" (e.g. a message list, a message page or a monthly archive),
+// the month has ended more than archiveDelay before.
+func archived(u *url.URL, now time.Time) bool {
+	for _, sg := range strings.Split(u.Path, "/") {
+		t, err := time.Parse("2006-January", strings.TrimSuffix(sg, ".txt.gz"))
+		if err != nil {
+			continue
+		}
+
+		m := provider.MonthYear{Month: t.Month(), Year: t.Year()}
+		next := m.AddMonth(1)
+		return now.Sub(next.Date()) > archiveDelay
+	}
+
+	return false
+}
@@ -0,0 +1,263 @@
+package seishub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"seismo/provider"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_ReadArchive(t *testing.T) {
+	f, err := os.Open("testdata/archive/2022-February.txt.gz")
+	if err != nil {
+		t.Fatalf("Test_ReadArchive: %v", err)
+	}
+	defer f.Close()
+
+	mails, err := ReadArchive(f)
+	if err != nil {
+		t.Fatalf("Test_ReadArchive: %v", err)
+	}
+
+	if len(mails) != 6 {
+		t.Fatalf("Test_ReadArchive: want 6 mails, result: %d", len(mails))
+	}
+
+	if want := "[Seismic-Report] ОПЕРАТИВНОЕ СООБЩЕНИЕ О СЕЙСМИЧЕСКОМ СОБЫТИИ (asb2022cfjhkl)"; mails[0].Subject != want {
+		t.Errorf("Test_ReadArchive: want subject: %q, result: %q", want, mails[0].Subject)
+	}
+
+	if want := "20220201055654.4927.17289@sc3-oper-processing.gsn"; mails[0].MessageId != want {
+		t.Errorf("Test_ReadArchive: want message id: %q, result: %q", want, mails[0].MessageId)
+	}
+
+	//the escaped "From " line of the body
+	if !strings.Contains(mails[2].Body, "\nFrom the next week") {
+		t.Errorf("Test_ReadArchive: the escaped line is not restored: %q", mails[2].Body)
+	}
+}
+
+func Test_ArchiveMsgs(t *testing.T) {
+	const link = "http://seishub.ru/pipermail/seismic-report/2022-February.txt.gz"
+	wantNames := []string{"017538.html", "017539.html", "017540.html", "017541.html", "017542.html"}
+
+	f, err := os.Open("testdata/archive/2022-February.txt.gz")
+	if err != nil {
+		t.Fatalf("Test_ArchiveMsgs: %v", err)
+	}
+	defer f.Close()
+
+	mails, err := ReadArchive(f)
+	if err != nil {
+		t.Fatalf("Test_ArchiveMsgs: %v", err)
+	}
+
+	msgs := ArchiveMsgs(mails, link)
+	if len(msgs) != len(wantNames) {
+		t.Fatalf("Test_ArchiveMsgs: want %d messages, result: %d", len(wantNames), len(msgs))
+	}
+
+	for i, n := range wantNames {
+		b, err := os.ReadFile(path.Join("testdata/json_msg/2022-February", n+".json"))
+		if err != nil {
+			t.Fatalf("Test_ArchiveMsgs: %v", err)
+		}
+
+		var want provider.Message
+		if err := json.Unmarshal(b, &want); err != nil {
+			t.Fatalf("Test_ArchiveMsgs: %v", err)
+		}
+
+		if !strings.HasPrefix(msgs[i].Link, link+"#") {
+			t.Errorf("Test_ArchiveMsgs: unexpected link %q", msgs[i].Link)
+		}
+		msgs[i].Link = ""
+
+		if msgs[i].UpdateTime.IsZero() || msgs[i].UpdateTime.Before(msgs[i].FocusTime) {
+			t.Errorf("Test_ArchiveMsgs: unexpected update time %v", msgs[i].UpdateTime)
+		}
+		msgs[i].UpdateTime = time.Time{}
+
+		if !msgs[i].Equal(want) {
+			t.Errorf("Test_ArchiveMsgs: \twant: %v\n\t result: %v\n", want, *msgs[i])
+		}
+	}
+}
+
+func Test_Extract_UseArchives(t *testing.T) {
+	archive, err := os.ReadFile("testdata/archive/2022-February.txt.gz")
+	if err != nil {
+		t.Fatalf("Test_Extract_UseArchives: %v", err)
+	}
+
+	var reqs int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqs, 1)
+		if r.URL.Path == "/2022-February.txt.gz" {
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(archive)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1})
+	if err != nil {
+		t.Fatalf("Test_Extract_UseArchives: %v", err)
+	}
+	h.UseArchives = true
+
+	feb := provider.MonthYear{Month: 2, Year: 2022}
+	msgs, err := h.Extract(context.Background(), feb, feb, 0)
+	if err != nil {
+		t.Fatalf("Test_Extract_UseArchives: %v", err)
+	}
+
+	if len(msgs) != 5 {
+		t.Errorf("Test_Extract_UseArchives: want 5 messages, result: %d", len(msgs))
+	}
+
+	for _, m := range msgs {
+		if m.SourceId != "seishub" {
+			t.Errorf("Test_Extract_UseArchives: want source id %q, result: %q", "seishub", m.SourceId)
+		}
+	}
+
+	if n := atomic.LoadInt32(&reqs); n != 1 {
+		t.Errorf("Test_Extract_UseArchives: want 1 request, result: %d", n)
+	}
+
+	//the archive of January is absent: the message list page is requested after the archive
+	atomic.StoreInt32(&reqs, 0)
+	jan := provider.MonthYear{Month: 1, Year: 2022}
+	if _, err := h.Extract(context.Background(), jan, jan, 0); err != nil {
+		t.Fatalf("Test_Extract_UseArchives: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&reqs); n != 2 {
+		t.Errorf("Test_Extract_UseArchives: want 2 requests, result: %d", n)
+	}
+}
+
+func Test_Backfill(t *testing.T) {
+	archive, err := os.ReadFile("testdata/archive/2022-February.txt.gz")
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2022-February.txt.gz" {
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(archive)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1,
+		Options: []byte(`{"use_archives": true}`)})
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	from := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	ch, err := h.Backfill(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	var res []provider.Message
+	for m := range ch {
+		res = append(res, m)
+	}
+
+	if len(res) != 5 {
+		t.Fatalf("Test_Backfill: want 5 messages, result: %d", len(res))
+	}
+
+	for i, m := range res {
+		if !provider.InRange(m.FocusTime, from, to) || (i > 0 && m.FocusTime.Before(res[i-1].FocusTime)) {
+			t.Errorf("Test_Backfill: message %d is out of the range or the order: %v", i, m.FocusTime)
+		}
+	}
+
+	//the range of the last message only
+	last := res[len(res)-1].FocusTime
+	ch, err = h.Backfill(context.Background(), last, last.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Test_Backfill: %v", err)
+	}
+
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("Test_Backfill: want 1 message, result: %d", n)
+	}
+}
+
+func Test_archived(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"http://seishub.ru/pipermail/seismic-report/2022-January/", true},
+		{"http://seishub.ru/pipermail/seismic-report/2022-January/017500.html", true},
+		{"http://seishub.ru/pipermail/seismic-report/2022-January.txt.gz", true},
+		{"http://seishub.ru/pipermail/seismic-report/2022-February/date.html", false}, //within archiveDelay
+		{"http://seishub.ru/pipermail/seismic-report/2022-March/", false},
+		{"http://seishub.ru/pipermail/seismic-report/", false},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.link)
+		if err != nil {
+			t.Fatalf("Test_archived: %v", err)
+		}
+		if res := archived(u, now); res != test.want {
+			t.Errorf("Test_archived: %s: want: %v res: %v", test.link, test.want, res)
+		}
+	}
+}
+
+func Test_Extract_Cache(t *testing.T) {
+	last, broken := int32(17545), int32(0)
+	srv := newStateTestServer(t, &last, &broken)
+	defer srv.Close()
+
+	opts := json.RawMessage(`{"cache_dir": "` + t.TempDir() + `", "cache_max_size": 1048576}`)
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1, Options: opts})
+	if err != nil {
+		t.Fatalf("Test_Extract_Cache: %v", err)
+	}
+
+	feb := provider.MonthYear{Month: 2, Year: 2022}
+	want, err := h.Extract(context.Background(), feb, feb, 0)
+	if err != nil || len(want) != 8 {
+		t.Fatalf("Test_Extract_Cache: messages: %d error: %v", len(want), err)
+	}
+
+	//the month is archived, so the pages are got from the cache without requests
+	atomic.StoreInt32(&broken, 1)
+	res, err := h.Extract(context.Background(), feb, feb, 0)
+	if err != nil {
+		t.Fatalf("Test_Extract_Cache: %v", err)
+	}
+	if len(res) != len(want) {
+		t.Errorf("Test_Extract_Cache: want %d messages from the cache, result: %d", len(want), len(res))
+	}
+}
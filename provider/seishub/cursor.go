@@ -0,0 +1,144 @@
+package seishub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"seismo/provider"
+	"sync"
+	"time"
+)
+
+// Cursor specifies the position of a watching Hub on SEISHUB.
+type Cursor struct {
+	// MsgNum specifies the number of the message which will be checked next.
+	MsgNum int `json:"msg_num"`
+
+	// Month specifies the month in which the message is checked.
+	Month provider.MonthYear `json:"month"`
+
+	// FocusTime specifies the latest FocusTime of the messages sent before
+	// the cursor. It is zero if no messages have been sent.
+	FocusTime time.Time `json:"focus_time"`
+}
+
+// consistent reports whether watching beginning from the time "from"
+// can be resumed from the cursor, i.e. the cursor is not in a month before
+// the month of "from" and no messages after "from" have been sent before the cursor.
+func (c Cursor) consistent(from time.Time) bool {
+	if c.MsgNum <= 0 {
+		return false
+	}
+
+	m := provider.MonthYear{Month: from.Month(), Year: from.Year()}
+	return !m.After(c.Month) && !c.FocusTime.After(from)
+}
+
+// moved reports whether the cursor has moved from the position "prev",
+// i.e. messages have been processed or sent since then.
+func (c Cursor) moved(prev Cursor) bool {
+	return c.MsgNum != prev.MsgNum || c.Month != prev.Month || !c.FocusTime.Equal(prev.FocusTime)
+}
+
+// CursorStore represents a storage of cursors of watching Hubs by their identifiers
+// (see provider.WatcherConfig.Id). Implementations must be safe for concurrent use.
+type CursorStore interface {
+	// LoadCursor returns the cursor of the Hub "id", a flag reporting whether
+	// the cursor has been found and an error.
+	LoadCursor(ctx context.Context, id string) (Cursor, bool, error)
+
+	// SaveCursor saves the cursor "c" of the Hub "id" replacing the previous one.
+	SaveCursor(ctx context.Context, id string, c Cursor) error
+}
+
+// MemCursorStore keeps cursors in memory. The zero value is ready to use.
+type MemCursorStore struct {
+	mu      sync.Mutex
+	cursors map[string]Cursor
+}
+
+func (s *MemCursorStore) LoadCursor(ctx context.Context, id string) (Cursor, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.cursors[id]
+	return c, ok, nil
+}
+
+func (s *MemCursorStore) SaveCursor(ctx context.Context, id string, c Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cursors == nil {
+		s.cursors = make(map[string]Cursor)
+	}
+	s.cursors[id] = c
+
+	return nil
+}
+
+// FileCursorStore keeps every cursor in a separate JSON file of the Dir directory.
+// The file name is the escaped identifier of the Hub with the ".json" extension.
+// The directory is created on saving if it does not exist.
+type FileCursorStore struct {
+	Dir string
+}
+
+func (s FileCursorStore) fileName(id string) string {
+	return filepath.Join(s.Dir, url.PathEscape(id)+".json")
+}
+
+func (s FileCursorStore) LoadCursor(ctx context.Context, id string) (Cursor, bool, error) {
+	b, err := os.ReadFile(s.fileName(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Cursor{}, false, nil
+	}
+	if err != nil {
+		return Cursor{}, false, fmt.Errorf("LoadCursor: %w", err)
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Cursor{}, false, fmt.Errorf("LoadCursor: file %q: %w", s.fileName(id), err)
+	}
+
+	return c, true, nil
+}
+
+// SaveCursor writes the cursor into a temporary file and renames it,
+// so a cursor file is never left partially written.
+func (s FileCursorStore) SaveCursor(ctx context.Context, id string, c Cursor) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("SaveCursor: %w", err)
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("SaveCursor: %w", err)
+	}
+
+	f, err := os.CreateTemp(s.Dir, url.PathEscape(id)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("SaveCursor: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("SaveCursor: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("SaveCursor: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), s.fileName(id)); err != nil {
+		return fmt.Errorf("SaveCursor: %w", err)
+	}
+
+	return nil
+}
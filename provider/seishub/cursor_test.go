@@ -0,0 +1,184 @@
+package seishub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"seismo/provider"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_FileCursorStore(t *testing.T) {
+	ctx := context.Background()
+	s := FileCursorStore{Dir: t.TempDir() + "/cursors"}
+
+	if _, ok, err := s.LoadCursor(ctx, "seishub/1"); ok || err != nil {
+		t.Errorf("Test_FileCursorStore: missing cursor: ok: %v error: %v", ok, err)
+	}
+
+	want := Cursor{MsgNum: 17541, Month: provider.MonthYear{Month: time.February, Year: 2022},
+		FocusTime: time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC)}
+	for i := 0; i < 2; i++ {
+		if err := s.SaveCursor(ctx, "seishub/1", want); err != nil {
+			t.Fatalf("Test_FileCursorStore: %v", err)
+		}
+	}
+
+	res, ok, err := s.LoadCursor(ctx, "seishub/1")
+	if !ok || err != nil {
+		t.Fatalf("Test_FileCursorStore: ok: %v error: %v", ok, err)
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("Test_FileCursorStore: (-want +res):\n%s", diff)
+	}
+}
+
+func Test_Cursor_consistent(t *testing.T) {
+	feb := provider.MonthYear{Month: time.February, Year: 2022}
+	ft := time.Date(2022, 2, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		c    Cursor
+		from time.Time
+		want bool
+	}{
+		{Cursor{MsgNum: 17540, Month: feb, FocusTime: ft}, ft, true},
+		{Cursor{MsgNum: 17540, Month: feb, FocusTime: ft}, ft.Add(time.Hour), true},
+		{Cursor{MsgNum: 17540, Month: feb}, time.Date(2022, 1, 20, 0, 0, 0, 0, time.UTC), true},
+		{Cursor{MsgNum: 17540, Month: feb, FocusTime: ft}, ft.Add(-time.Hour), false},
+		{Cursor{MsgNum: 17540, Month: feb, FocusTime: ft}, time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{Cursor{Month: feb}, ft, false},
+	}
+
+	for i, test := range tests {
+		if res := test.c.consistent(test.from); res != test.want {
+			t.Errorf("Test_Cursor_consistent: test %d: want: %v res: %v", i, test.want, res)
+		}
+	}
+}
+
+// countTransport counts requests of the month page.
+type countTransport struct {
+	month string
+	n     int32
+}
+
+func (c *countTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path == c.month || r.URL.Path == c.month+"/" {
+		atomic.AddInt32(&c.n, 1)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func Test_StartWatch_Cursor(t *testing.T) {
+	last, broken := int32(17541), int32(0)
+	srv := newStateTestServer(t, &last, &broken)
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1})
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Cursor: %v", err)
+	}
+	tr := &countTransport{month: "/2022-February"}
+	h.Client.Transport = tr
+
+	from := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	store := &MemCursorStore{}
+	store.SaveCursor(context.Background(), "seishub",
+		Cursor{MsgNum: 17541, Month: provider.MonthYear{Month: time.February, Year: 2022}})
+	h.Cursors = store
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, from)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Cursor: %v", err)
+	}
+
+	var m provider.Message
+	select {
+	case m = <-ch:
+		if m.EventId != "asb2022cfkkhd" {
+			t.Errorf("Test_StartWatch_Cursor: the watching has not been resumed from the cursor: %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Test_StartWatch_Cursor: timeout")
+	}
+
+	cancel()
+	for range ch {
+	}
+
+	if n := atomic.LoadInt32(&tr.n); n != 0 {
+		t.Errorf("Test_StartWatch_Cursor: the month page has been requested %d times", n)
+	}
+
+	c, _, _ := store.LoadCursor(context.Background(), "seishub")
+	want := Cursor{MsgNum: 17542, Month: provider.MonthYear{Month: time.February, Year: 2022}, FocusTime: m.FocusTime}
+	if diff := cmp.Diff(want, c); diff != "" {
+		t.Errorf("Test_StartWatch_Cursor: saved cursor (-want +res):\n%s", diff)
+	}
+}
+
+func Test_StartWatch_CursorNoEvents(t *testing.T) {
+	const month = "/2022-February/"
+
+	//the mails after the cursor are not event reports
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != month && r.URL.Path+"/" != month && r.URL.Path != month+IndexPage {
+			http.NotFound(w, r)
+			return
+		}
+		for n := 17538; n <= 17544; n++ {
+			fmt.Fprintf(w, "<LI><A HREF=\"%s\">[Seismic-Report] notice %d</A>\n", msgNumToName(n), n)
+		}
+	}))
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1})
+	if err != nil {
+		t.Fatalf("Test_StartWatch_CursorNoEvents: %v", err)
+	}
+
+	feb := provider.MonthYear{Month: time.February, Year: 2022}
+	store := &MemCursorStore{}
+	store.SaveCursor(context.Background(), "seishub", Cursor{MsgNum: 17542, Month: feb})
+	h.Cursors = store
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_CursorNoEvents: %v", err)
+	}
+
+	want := Cursor{MsgNum: 17545, Month: feb}
+	for {
+		c, _, _ := store.LoadCursor(context.Background(), "seishub")
+		if c == want {
+			break
+		}
+		select {
+		case m, ok := <-ch:
+			if ok {
+				t.Fatalf("Test_StartWatch_CursorNoEvents: unexpected message: %v", m)
+			}
+			t.Fatalf("Test_StartWatch_CursorNoEvents: the watching has stopped, saved cursor: %+v", c)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("Test_StartWatch_CursorNoEvents: timeout, saved cursor: %+v", c)
+		}
+	}
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,303 @@
+package seishub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"seismo/provider"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SkippedMsg describes a message number skipped while watching, since the message
+// has not appeared on the index pages during the grace period (see Hub.GapGrace).
+type SkippedMsg struct {
+	Num int
+
+	// Month specifies the cursor month at the moment of skipping.
+	Month provider.MonthYear
+
+	// Noticed specifies the time when the number was noticed missing.
+	Noticed time.Time
+
+	// Time specifies the time of skipping.
+	Time time.Time
+}
+
+// monthIndex maps numbers of the messages listed on the index page of a month
+// to their event identifiers.
+type monthIndex map[int]string
+
+// tracker keeps the state of discovering new messages while watching.
+type tracker struct {
+	// listed keeps the last read indexes of the months to notice disappeared messages.
+	listed map[provider.MonthYear]monthIndex
+
+	// maxListed is the maximum number of listed messages.
+	maxListed int
+
+	// done keeps the months of the listed messages after the cursor, which have been processed,
+	// i.e. fetched or passed (as not event reports or disappeared).
+	done map[int]provider.MonthYear
+
+	// holes keeps the missing numbers after the cursor and the time they were noticed.
+	holes map[int]time.Time
+
+	// grace specifies the time during which a missing number is waited for.
+	grace time.Duration
+
+	// backlog is the number of the listed event messages after the cursor, which have
+	// not been got yet, after the last discovering.
+	backlog int
+}
+
+func newTracker(grace time.Duration) *tracker {
+	return &tracker{
+		listed: make(map[provider.MonthYear]monthIndex),
+		done:   make(map[int]provider.MonthYear),
+		holes:  make(map[int]time.Time),
+		grace:  grace,
+	}
+}
+
+// read replaces the index of the month "m" with "entries" and returns the entries, which
+// have numbers not less than "next" and have not been processed, in ascending order of numbers.
+// The messages of the previous index, which have disappeared from "entries", are logged.
+func (t *tracker) read(m provider.MonthYear, entries []IndexEntry, next int) []IndexEntry {
+	prev := t.listed[m]
+	cur := make(monthIndex, len(entries))
+	pending := make([]IndexEntry, 0)
+	for _, e := range entries {
+		cur[e.Num] = e.EventId
+		if e.Num > t.maxListed {
+			t.maxListed = e.Num
+		}
+		if _, ok := t.done[e.Num]; !ok && e.Num >= next {
+			pending = append(pending, e)
+		}
+	}
+
+	for n, id := range prev {
+		if _, ok := cur[n]; !ok {
+			log.Printf("read: message %s (event %q) of %s has disappeared from the index", msgNumToName(n), id, m.String())
+		}
+	}
+
+	t.listed[m] = cur
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Num < pending[j].Num })
+
+	return pending
+}
+
+// isListed reports whether the message number "n" is listed in the last read indexes.
+func (t *tracker) isListed(n int) bool {
+	for _, idx := range t.listed {
+		if _, ok := idx[n]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the cursor "cur" over the processed messages and the holes,
+// i.e. the numbers missing on the index pages while greater numbers are listed.
+// A hole is skipped if it has been missing for the grace period, otherwise
+// the cursor stays at it, so the message is got if it appears.
+// A listed, but not processed message (e.g. it cannot be fetched) stops the cursor too.
+//
+// The method returns the skipped holes.
+func (t *tracker) advance(cur *Cursor, now time.Time) []SkippedMsg {
+	var skipped []SkippedMsg
+	for n := cur.MsgNum; ; n++ {
+		if m, ok := t.done[n]; ok {
+			delete(t.done, n)
+			cur.MsgNum, cur.Month = n+1, m
+			continue
+		}
+
+		if n >= t.maxListed || t.isListed(n) {
+			break
+		}
+
+		noticed, ok := t.holes[n]
+		if !ok {
+			t.holes[n] = now
+			break
+		}
+		if now.Sub(noticed) < t.grace {
+			break
+		}
+
+		delete(t.holes, n)
+		skipped = append(skipped, SkippedMsg{Num: n, Month: cur.Month, Noticed: noticed, Time: now})
+		cur.MsgNum = n + 1
+	}
+
+	//holes behind the cursor have been listed
+	for n := range t.holes {
+		if n < cur.MsgNum {
+			delete(t.holes, n)
+		}
+	}
+
+	//indexes of the months before the cursor month are not needed anymore
+	for m := range t.listed {
+		if cur.Month.After(m) {
+			delete(t.listed, m)
+		}
+	}
+
+	return skipped
+}
+
+// pendingMsg represents a listed event message which is to be fetched.
+type pendingMsg struct {
+	num   int
+	month provider.MonthYear
+	link  string
+}
+
+// discover reads the index pages of the months from the cursor month up to the current one
+// (so months without messages are walked across) and fetches, in ascending order of numbers,
+// the listed event messages which are after the cursor and have not been processed before.
+// Mail which is not an event report is passed without fetching. Then the cursor is advanced
+// (see tracker.advance), the skipped holes are logged and recorded (see Hub.Skipped).
+//
+// Up to catchUpBatch messages are fetched back-to-back by defParal go-routines in one call,
+// the number of messages left (the backlog) is kept in t.backlog and the statistics.
+//
+// The method returns the fetched messages and an error. If the error is not nil,
+// the messages fetched successfully are returned too, so they are not lost.
+func (h *Hub) discover(ctx context.Context, cur *Cursor, t *tracker) ([]*provider.Message, error) {
+	now := time.Now().UTC()
+	defer func() {
+		for _, s := range t.advance(cur, now) {
+			log.Printf("discover: message %s has been missing since %v, skipped", msgNumToName(s.Num), s.Noticed)
+			h.recordSkipped(s)
+		}
+	}()
+
+	var pending []pendingMsg
+	last := provider.MonthYear{Month: now.Month(), Year: now.Year()}
+	for m := cur.Month; !m.After(last); m = m.AddMonth(1) {
+		monthLink, err := url.JoinPath(h.config.ConnStr, MonthYearPathSeg(m.Month, m.Year))
+		if err != nil {
+			return nil, fmt.Errorf("discover: %w", err)
+		}
+
+		entries, err := GetMsgIndex(ctx, monthLink, &h.Client)
+		if errors.As(err, &NotFoundErr{}) { //the month has no messages
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("discover: %w", err)
+		}
+
+		for _, e := range t.read(m, entries, cur.MsgNum) {
+			if e.EventId == "" { //not an event report
+				t.done[e.Num] = m
+				continue
+			}
+
+			l, err := url.JoinPath(monthLink, e.Name)
+			if err != nil {
+				return nil, fmt.Errorf("discover: %w", err)
+			}
+			pending = append(pending, pendingMsg{num: e.Num, month: m, link: l})
+		}
+	}
+
+	batch := pending
+	if len(batch) > catchUpBatch {
+		batch = batch[:catchUpBatch]
+	}
+
+	var mu sync.Mutex
+	t.backlog = len(pending)
+	h.stats.SetBacklog(uint64(t.backlog))
+	res := h.fetchAll(ctx, batch, defParal, func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		t.backlog--
+		h.stats.SetBacklog(uint64(t.backlog))
+	})
+
+	msgs := make([]*provider.Message, 0, len(batch))
+	var err error
+	for i, r := range res {
+		p := batch[i]
+		switch {
+		case r.err == nil:
+			msgs = append(msgs, r.msg)
+			t.done[p.num] = p.month
+		case errors.As(r.err, &NotFoundErr{}): //the message has disappeared after reading the index
+			log.Printf("discover: %v", r.err)
+			t.done[p.num] = p.month
+		case err == nil:
+			err = fmt.Errorf("discover: %w", r.err)
+		}
+	}
+
+	//messages which have not been got are left in the backlog
+	t.backlog = len(pending) - len(msgs)
+	h.stats.SetBacklog(uint64(t.backlog))
+
+	return msgs, err
+}
+
+// fetchResult represents a result of fetching a message.
+type fetchResult struct {
+	msg *provider.Message
+	err error
+}
+
+// fetchAll gets the messages "msgs" by "paral" go-routines and returns the results
+// in the order of "msgs". The "fetched" function is called after getting every message.
+func (h *Hub) fetchAll(ctx context.Context, msgs []pendingMsg, paral int, fetched func()) []fetchResult {
+	res := make([]fetchResult, len(msgs))
+	ind := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(paral)
+	for i := 0; i < paral; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range ind {
+				res[i].msg, res[i].err = h.getMsgByLink(ctx, msgs[i].link)
+				fetched()
+			}
+		}()
+	}
+
+	for i := range msgs {
+		ind <- i
+	}
+	close(ind)
+	wg.Wait()
+
+	return res
+}
+
+// recordSkipped records the skipped message "s" keeping maxSkipped last records.
+func (h *Hub) recordSkipped(s SkippedMsg) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.skipped = append(h.skipped, s)
+	if len(h.skipped) > maxSkipped {
+		h.skipped = h.skipped[len(h.skipped)-maxSkipped:]
+	}
+}
+
+// Skipped returns the records of the message numbers skipped while watching
+// (maxSkipped last ones), since the messages have been missing on SEISHUB.
+func (h *Hub) Skipped() []SkippedMsg {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]SkippedMsg(nil), h.skipped...)
+}
@@ -0,0 +1,143 @@
+package seishub
+
+import (
+	"context"
+	"seismo/provider"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_tracker_read(t *testing.T) {
+	m := provider.MonthYear{Month: time.February, Year: 2022}
+	tr := newTracker(time.Minute)
+	tr.listed[m] = monthIndex{10: "a", 11: "b", 12: "c"}
+	tr.done[12] = m
+
+	entries := []IndexEntry{{Num: 14, EventId: "e"}, {Num: 9, EventId: "z"}, {Num: 10, EventId: "a"},
+		{Num: 12, EventId: "c"}, {Num: 13, EventId: "d"}}
+	res := tr.read(m, entries, 10)
+
+	want := []IndexEntry{{Num: 10, EventId: "a"}, {Num: 13, EventId: "d"}, {Num: 14, EventId: "e"}}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("Test_tracker_read: (-want +res):\n%s", diff)
+	}
+
+	if _, ok := tr.listed[m][11]; ok || len(tr.listed[m]) != len(entries) || tr.maxListed != 14 {
+		t.Errorf("Test_tracker_read: the index has not been replaced: %v max: %d", tr.listed[m], tr.maxListed)
+	}
+}
+
+func Test_tracker_advance(t *testing.T) {
+	feb := provider.MonthYear{Month: time.February, Year: 2022}
+	mar := provider.MonthYear{Month: time.March, Year: 2022}
+	now := time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	tr := newTracker(time.Minute)
+	tr.read(feb, []IndexEntry{{Num: 10}, {Num: 11}}, 10)
+	tr.read(mar, []IndexEntry{{Num: 13}, {Num: 15}, {Num: 16}}, 10)
+	tr.done[10], tr.done[11], tr.done[13], tr.done[15] = feb, feb, mar, mar
+
+	//the cursor stays at the hole during the grace period
+	cur := Cursor{MsgNum: 10, Month: feb}
+	if s := tr.advance(&cur, now); len(s) != 0 || cur != (Cursor{MsgNum: 12, Month: feb}) {
+		t.Errorf("Test_tracker_advance: cursor: %+v skipped: %v", cur, s)
+	}
+	if s := tr.advance(&cur, now.Add(30*time.Second)); len(s) != 0 || cur.MsgNum != 12 {
+		t.Errorf("Test_tracker_advance: cursor: %+v skipped: %v", cur, s)
+	}
+
+	//the hole is skipped after the grace period, the unprocessed message 16 stops the cursor
+	s := tr.advance(&cur, now.Add(time.Minute))
+	want := []SkippedMsg{{Num: 12, Month: feb, Noticed: now, Time: now.Add(time.Minute)}}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("Test_tracker_advance: skipped (-want +res):\n%s", diff)
+	}
+	if cur != (Cursor{MsgNum: 14, Month: mar}) {
+		t.Errorf("Test_tracker_advance: cursor: %+v", cur)
+	}
+
+	//the hole at 14 is noticed, the one appeared before the grace period expiry is not skipped
+	tr.advance(&cur, now.Add(time.Minute))
+	tr.read(mar, []IndexEntry{{Num: 13}, {Num: 14}, {Num: 15}, {Num: 16}}, cur.MsgNum)
+	tr.done[14], tr.done[16] = mar, mar
+	if s := tr.advance(&cur, now.Add(time.Hour)); len(s) != 0 || cur != (Cursor{MsgNum: 17, Month: mar}) {
+		t.Errorf("Test_tracker_advance: cursor: %+v skipped: %v", cur, s)
+	}
+	if _, ok := tr.listed[feb]; ok {
+		t.Errorf("Test_tracker_advance: the index of the month before the cursor month is kept")
+	}
+}
+
+func Test_discover_EmptyMonths(t *testing.T) {
+	last, broken := int32(17540), int32(0)
+	srv := newStateTestServer(t, &last, &broken)
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1})
+	if err != nil {
+		t.Fatalf("Test_discover_EmptyMonths: %v", err)
+	}
+
+	//November 2021, December 2021 and January 2022 have no messages
+	cur := Cursor{MsgNum: 17538, Month: provider.MonthYear{Month: time.November, Year: 2021}}
+	msgs, err := h.discover(context.Background(), &cur, newTracker(h.GapGrace))
+	if err != nil {
+		t.Fatalf("Test_discover_EmptyMonths: %v", err)
+	}
+
+	if len(msgs) != 3 || cur.MsgNum != 17541 || cur.Month != (provider.MonthYear{Month: time.February, Year: 2022}) {
+		t.Errorf("Test_discover_EmptyMonths: messages: %d cursor: %+v", len(msgs), cur)
+	}
+}
+
+func Test_StartWatch_CatchUp(t *testing.T) {
+	last, broken := int32(17801), int32(0)
+	srv := newStateTestServer(t, &last, &broken)
+	defer srv.Close()
+
+	const checkPeriod = 3 * time.Second
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: uint(checkPeriod / time.Second)})
+	if err != nil {
+		t.Fatalf("Test_StartWatch_CatchUp: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_CatchUp: %v", err)
+	}
+
+	//the messages of several batches are got back-to-back
+	var start time.Time
+	for n := 17538; n <= 17801; n++ {
+		select {
+		case m := <-ch:
+			if m.Version != n {
+				t.Fatalf("Test_StartWatch_CatchUp: want message: %d res: %d", n, m.Version)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Test_StartWatch_CatchUp: timeout, message: %d", n)
+		}
+
+		if n == 17538 {
+			start = time.Now()
+			if st := h.Stats(); st.State != provider.CatchingUp || st.Backlog != 264-catchUpBatch {
+				t.Errorf("Test_StartWatch_CatchUp: unexpected stats while catching up: %+v", st)
+			}
+		}
+	}
+	if d := time.Since(start); d >= checkPeriod {
+		t.Errorf("Test_StartWatch_CatchUp: messages have been got in %v, not back-to-back", d)
+	}
+
+	cancel()
+	for range ch {
+	}
+	if st := h.Stats(); st.Backlog != 0 || st.Emitted != 264 {
+		t.Errorf("Test_StartWatch_CatchUp: unexpected stats after stopping: %+v", st)
+	}
+}
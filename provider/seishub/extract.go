@@ -0,0 +1,270 @@
+package seishub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"seismo/provider"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExtractResult represents a result of extracting a message by Hub.ExtractStream:
+// the message or the error of getting it.
+type ExtractResult struct {
+	// Link specifies the link of the message page or, for an archived message,
+	// the archive link with the message id as a fragment. If a message list
+	// cannot be got, Link specifies the link of the list.
+	Link string
+
+	// Msg specifies the extracted message. It is nil if Err is not nil.
+	Msg *provider.Message
+
+	// Err specifies the error of getting the message.
+	Err error
+}
+
+// ExtractProgress represents the progress of extracting messages by Hub.ExtractStream.
+type ExtractProgress struct {
+	// Months specifies the number of months of the extracted period.
+	Months int
+
+	// MonthsScanned specifies the number of months, whose message lists
+	// (or archives) have been processed.
+	MonthsScanned int
+
+	// Links specifies the number of message links found in the scanned months.
+	Links int
+
+	// LinksDone specifies the number of links, which have been processed
+	// (the messages have been got or have failed).
+	LinksDone int
+}
+
+// ExtractOptions specifies options of Hub.ExtractStream.
+type ExtractOptions struct {
+	// Paral specifies the number of go-routines getting messages.
+	// If Paral is less than (or equal to) 0, the default value is used.
+	Paral int
+
+	// Ordered specifies whether the results are sent in the order of months and
+	// message numbers within a month (the order of mails for archived months).
+	// Otherwise the results are sent as soon as the messages are got.
+	Ordered bool
+
+	// Progress is called after every change of the progress. The calls are
+	// serial, and a slow function delays the results. It can be nil.
+	Progress func(ExtractProgress)
+}
+
+// ExtractStream extracts seismic messages from SEISHUB for the period defined with
+// the "from" and "to" parameters and returns a channel of the results and an error.
+// If the returned error is not nil, the returned channel is nil.
+//
+// Every result is a message or the error of getting it with its link, so a failed link
+// does not stop extracting. The channel is closed after all the results have been sent
+// or promptly after the context is canceled (the remaining results are dropped).
+//
+// If UseArchives is set, messages of the months before the current one are extracted
+// from monthly archives (see GetArchiveMsgs). If an archive cannot be got,
+// the message pages of its month are used.
+func (h *Hub) ExtractStream(ctx context.Context,
+	from provider.MonthYear, to provider.MonthYear, opts ExtractOptions) (<-chan ExtractResult, error) {
+
+	if from.After(to) {
+		return nil, fmt.Errorf(`ExtractStream: the "from" arg cannot be more than the "to" arg`)
+	}
+
+	return h.extractStream(ctx, from, to, opts, h.UseArchives), nil
+}
+
+// extractItem represents an event of extracting processed by the collector:
+// a scanned month with the number of its links or a result of a link with its sequence number.
+// A month, whose message list cannot be got, has the result with the error.
+type extractItem struct {
+	month bool
+	links int
+	seq   int
+	res   *ExtractResult
+}
+
+// extractJob represents a message link to be got by a worker.
+type extractJob struct {
+	seq  int
+	link string
+}
+
+// extractStream implements ExtractStream. The "useArchives" parameter specifies
+// whether monthly archives are used for the months before the current one.
+//
+// The producer scans the months and numbers the results in the order of months and
+// message numbers, the workers get the messages, and the collector counts the progress,
+// restores the order if it is requested and sends the results.
+func (h *Hub) extractStream(ctx context.Context,
+	from provider.MonthYear, to provider.MonthYear, opts ExtractOptions, useArchives bool) <-chan ExtractResult {
+
+	paral := opts.Paral
+	if paral <= 0 {
+		paral = defParal
+	}
+
+	items := make(chan extractItem)
+	jobs := make(chan extractJob)
+
+	//put sends an item to the collector, it returns false if the context is done
+	put := func(it extractItem) bool {
+		select {
+		case items <- it:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(paral + 1)
+	for i := 0; i < paral; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				msg, err := h.getMsgByLink(ctx, j.link)
+				if !put(extractItem{seq: j.seq, res: &ExtractResult{Link: j.link, Msg: msg, Err: err}}) {
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		h.scanMonths(ctx, from, to, useArchives, put, jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(items)
+	}()
+
+	o := make(chan ExtractResult)
+	go func() {
+		defer close(o)
+
+		pr := ExtractProgress{Months: to.Diff(from) + 1}
+		pending := make(map[int]ExtractResult)
+		next := 0
+
+		send := func(r ExtractResult) {
+			select {
+			case o <- r:
+			case <-ctx.Done():
+			}
+		}
+
+		//items are drained after cancellation, so all the go-routines are finished
+		//when the channel is closed
+		for it := range items {
+			if ctx.Err() != nil {
+				continue
+			}
+
+			if it.month {
+				pr.MonthsScanned++
+				pr.Links += it.links
+			}
+			if it.res != nil && !it.month {
+				pr.LinksDone++
+			}
+			if opts.Progress != nil {
+				opts.Progress(pr)
+			}
+
+			if it.res == nil {
+				continue
+			}
+			if !opts.Ordered {
+				send(*it.res)
+				continue
+			}
+
+			pending[it.seq] = *it.res
+			for r, ok := pending[next]; ok; r, ok = pending[next] {
+				delete(pending, next)
+				next++
+				send(r)
+			}
+		}
+	}()
+
+	return o
+}
+
+// scanMonths scans the months from "from" to "to" for extractStream: it sends the links
+// of message pages sorted by message numbers to "jobs", the messages of archives and
+// the errors of getting message lists to the collector with "put".
+func (h *Hub) scanMonths(ctx context.Context, from provider.MonthYear, to provider.MonthYear,
+	useArchives bool, put func(extractItem) bool, jobs chan<- extractJob) {
+
+	now := time.Now().UTC()
+	curMonth := provider.MonthYear{Month: now.Month(), Year: now.Year()}
+	seq := 0
+
+	for m := from; !m.After(to); m = m.AddMonth(1) {
+		if useArchives && curMonth.After(m) {
+			am, err := h.getArchiveMsgs(ctx, m)
+			if err == nil {
+				if !put(extractItem{month: true, links: len(am)}) {
+					return
+				}
+				for _, msg := range am {
+					if !put(extractItem{seq: seq, res: &ExtractResult{Link: msg.Link, Msg: msg}}) {
+						return
+					}
+					seq++
+				}
+				continue
+			}
+			log.Printf("scanMonths: %v", err)
+		}
+
+		var names []string
+		monthLink, err := url.JoinPath(h.config.ConnStr, MonthYearPathSeg(m.Month, m.Year))
+		if err == nil {
+			names, err = GetMsgNames(ctx, monthLink, &h.Client)
+		}
+		if err != nil {
+			if !put(extractItem{month: true, seq: seq, res: &ExtractResult{Link: monthLink, Err: fmt.Errorf("scanMonths: %w", err)}}) {
+				return
+			}
+			seq++
+			continue
+		}
+
+		//names are zero-padded message numbers like "017540.html"
+		sort.Strings(names)
+		if !put(extractItem{month: true, links: len(names)}) {
+			return
+		}
+
+		for _, n := range names {
+			l, err := url.JoinPath(monthLink, n)
+			if err != nil {
+				//the failed link is sent as a result to keep the counts and the order
+				if !put(extractItem{seq: seq, res: &ExtractResult{Link: n, Err: fmt.Errorf("scanMonths: %w", err)}}) {
+					return
+				}
+				seq++
+				continue
+			}
+
+			select {
+			case jobs <- extractJob{seq: seq, link: l}:
+			case <-ctx.Done():
+				return
+			}
+			seq++
+		}
+	}
+}
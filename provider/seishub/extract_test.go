@@ -0,0 +1,142 @@
+package seishub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"seismo/provider"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newExtractTestServer returns a stand-in of SEISHUB listing the February 2022 messages
+// with numbers from 17538 to 17546 in reverse order. The page of 17546 is missing,
+// and there are no other months. If "hang" is set, message pages are not answered
+// until the requests are canceled.
+func newExtractTestServer(t *testing.T, hang bool) *httptest.Server {
+	const month = "/2022-February/"
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == month || r.URL.Path+"/" == month {
+			var b strings.Builder
+			for n := 17546; n >= 17538; n-- {
+				fmt.Fprintf(&b, "<LI><A HREF=\"%s\">[Seismic-Report] message (event%d)</A>\n", msgNumToName(n), n)
+			}
+			w.Write([]byte(b.String()))
+			return
+		}
+
+		n, err := parseMsgNum(r.URL.Path)
+		if !strings.HasPrefix(r.URL.Path, month) || err != nil || n == 17546 {
+			http.NotFound(w, r)
+			return
+		}
+
+		if hang {
+			<-r.Context().Done()
+			return
+		}
+
+		b, err := os.ReadFile(path.Join("testdata/html", r.URL.Path))
+		if err != nil {
+			t.Errorf("newExtractTestServer: %v", err)
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+}
+
+func Test_ExtractStream(t *testing.T) {
+	srv := newExtractTestServer(t, false)
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1})
+	if err != nil {
+		t.Fatalf("Test_ExtractStream: %v", err)
+	}
+
+	feb := provider.MonthYear{Month: time.February, Year: 2022}
+	mar := feb.AddMonth(1)
+	if _, err := h.ExtractStream(context.Background(), mar, feb, ExtractOptions{}); err == nil {
+		t.Errorf("Test_ExtractStream: want an error of the wrong period")
+	}
+
+	var last ExtractProgress
+	ch, err := h.ExtractStream(context.Background(), feb, mar,
+		ExtractOptions{Paral: 3, Ordered: true, Progress: func(p ExtractProgress) { last = p }})
+	if err != nil {
+		t.Fatalf("Test_ExtractStream: %v", err)
+	}
+
+	var res []ExtractResult
+	for r := range ch {
+		res = append(res, r)
+	}
+
+	//8 messages in the order of numbers, the missing page and the missing month
+	if len(res) != 10 {
+		t.Fatalf("Test_ExtractStream: want results: 10 res: %d", len(res))
+	}
+	for i, r := range res[:8] {
+		if r.Err != nil || r.Msg == nil || r.Msg.Version != 17538+i || !strings.HasSuffix(r.Link, msgNumToName(17538+i)) {
+			t.Errorf("Test_ExtractStream: result %d: unexpected message: %q %v", i, r.Link, r.Err)
+		}
+	}
+	if r := res[8]; !errors.As(r.Err, &NotFoundErr{}) || r.Msg != nil || !strings.HasSuffix(r.Link, msgNumToName(17546)) {
+		t.Errorf("Test_ExtractStream: want the error of the missing page, res: %q %v", r.Link, r.Err)
+	}
+	if r := res[9]; r.Err == nil || !strings.Contains(r.Link, "2022-March") {
+		t.Errorf("Test_ExtractStream: want the error of the missing month, res: %q %v", r.Link, r.Err)
+	}
+
+	want := ExtractProgress{Months: 2, MonthsScanned: 2, Links: 9, LinksDone: 9}
+	if last != want {
+		t.Errorf("Test_ExtractStream: want progress: %+v res: %+v", want, last)
+	}
+}
+
+func Test_ExtractStream_Cancel(t *testing.T) {
+	srv := newExtractTestServer(t, true)
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 60, CheckPeriod: 1})
+	if err != nil {
+		t.Fatalf("Test_ExtractStream_Cancel: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	feb := provider.MonthYear{Month: time.February, Year: 2022}
+	ch, err := h.ExtractStream(ctx, feb, feb, ExtractOptions{Paral: 10})
+	if err != nil {
+		t.Fatalf("Test_ExtractStream_Cancel: %v", err)
+	}
+
+	//all the links are requested, the missing page is answered, the others hang
+	select {
+	case r := <-ch:
+		if r.Err == nil {
+			t.Errorf("Test_ExtractStream_Cancel: want the error of the missing page")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Test_ExtractStream_Cancel: timeout of waiting for the first result")
+	}
+
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Test_ExtractStream_Cancel: the channel has not been closed after cancellation")
+		}
+	}
+}
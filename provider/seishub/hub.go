@@ -2,13 +2,13 @@ package seishub
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"regexp"
 	"seismo/provider"
+	"seismo/provider/httpcache"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,6 +24,21 @@ const (
 	//messages from seishub
 	defParal = 7
 
+	//maxBackoff constant defines the max delay before retrying a failed check
+	//of a new message while watching
+	maxBackoff = 5 * time.Minute
+
+	//defGapGrace constant defines the default time during which a missing message
+	//number is waited for while watching before it is skipped
+	defGapGrace = 10 * time.Minute
+
+	//maxSkipped constant defines max number of kept records of skipped messages
+	maxSkipped = 1000
+
+	//catchUpBatch constant defines max number of messages fetched at a time while
+	//watching. The next batch is fetched immediately while the backlog is not empty.
+	catchUpBatch = 50
+
 	//avgMonthMsgNum constant defines average number of seismic messages per month
 	//on SEISHUB. This constant is used to create slices with proper capacity.
 	avgMonthMsgNum = 200
@@ -35,13 +50,15 @@ type hubState interface {
 	stateInfo() provider.WatcherStateInfo
 }
 
-// stoppedState implements a stopped Hub's behavior within THE STATE PATTERN
+// stoppedState implements a stopped Hub's behavior within THE STATE PATTERN.
+// The state information is provider.Stopped or provider.Failed.
 type stoppedState struct {
-	hub *Hub
+	hub  *Hub
+	info provider.WatcherStateInfo
 }
 
-func newStoppedState(h *Hub) *stoppedState {
-	return &stoppedState{hub: h}
+func newStoppedState(h *Hub, info provider.WatcherStateInfo) *stoppedState {
+	return &stoppedState{hub: h, info: info}
 }
 
 // startWatch implements the behaivor of Hub.StartWatch in the "stopped" state,
@@ -70,26 +87,29 @@ func (s *stoppedState) startWatch(ctx context.Context, from time.Time) (<-chan p
 		return nil, fmt.Errorf(`watching cannot be started in the future (the "from" arg cannot be after the start time)`)
 	}
 	h := s.hub
-	h.setState(newRunState(s.hub))
+	h.setState(newRunState(s.hub, provider.Starting))
 	o := make(chan provider.Message) //output channel for fetched messages
-	sn := make(chan int, 1)          //channel to transfer the start message number from getStartMsgNum() to watch()
+	sn := make(chan Cursor, 1)       //channel to transfer the start cursor from getStartMsgNum() to watch()
 	go h.getStartMsgNum(ctx, sn, from, time.Duration(h.config.CheckPeriod)*time.Second)
-	go h.watch(ctx, o, sn, from, time.Duration(h.config.CheckPeriod)*time.Second)
+	go h.watch(ctx, o, sn, time.Duration(h.config.CheckPeriod)*time.Second)
 
 	return o, nil
 }
 
 func (s *stoppedState) stateInfo() provider.WatcherStateInfo {
-	return provider.Stopped
+	return s.info
 }
 
-// runState implements a running Hub's behavior within THE STATE PATTERN
+// runState implements a running Hub's behavior within THE STATE PATTERN.
+// The state information is provider.Starting, provider.CatchingUp,
+// provider.Run or provider.Backoff.
 type runState struct {
-	hub *Hub
+	hub  *Hub
+	info provider.WatcherStateInfo
 }
 
-func newRunState(h *Hub) *runState {
-	return &runState{hub: h}
+func newRunState(h *Hub, info provider.WatcherStateInfo) *runState {
+	return &runState{hub: h, info: info}
 }
 
 func (r *runState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
@@ -97,7 +117,7 @@ func (r *runState) startWatch(ctx context.Context, from time.Time) (<-chan provi
 }
 
 func (r *runState) stateInfo() provider.WatcherStateInfo {
-	return provider.Run
+	return r.info
 }
 
 // Hub provides getting SEISHUB's seismic event messages
@@ -110,6 +130,89 @@ type Hub struct {
 
 	//state implements the State pattern
 	state hubState
+
+	//mu guards the state and skipped, since the watching go-routine changes them
+	//concurrently with StateInfo, Stats and StartWatch calls.
+	mu sync.Mutex
+
+	//stats keeps statistics of the Hub
+	stats provider.StatsRecorder
+
+	//lifecycle publishes lifecycle events of the Hub
+	lifecycle provider.Lifecycle
+
+	//UseArchives specifies that Extract gets messages of past months
+	//from monthly archives (one request per month) instead of message pages.
+	UseArchives bool
+
+	//Cursors keeps the cursor of the Hub while watching, so watching
+	//restarted later is resumed from the cursor (see Cursor).
+	//If Cursors is nil, the cursor is not kept.
+	Cursors CursorStore
+
+	//GapGrace specifies the time during which a message number missing on SEISHUB
+	//is waited for while watching before it is skipped (see Skipped).
+	GapGrace time.Duration
+
+	//skipped keeps records of skipped messages, guarded by mu
+	skipped []SkippedMsg
+}
+
+// Options contains options of a seishub watcher (see provider.WatcherConfig.Options).
+type Options struct {
+	provider.HTTPOptions
+
+	// UseArchives sets Hub.UseArchives.
+	UseArchives bool `json:"use_archives"`
+
+	// CursorDir sets Hub.Cursors to a FileCursorStore keeping cursors in the directory.
+	CursorDir string `json:"cursor_dir"`
+
+	// GapGrace sets Hub.GapGrace in seconds. If it is 0, the default value is used.
+	GapGrace uint `json:"gap_grace"`
+
+	// CacheDir specifies the directory of the on-disk HTTP cache (see httpcache.Transport).
+	// Resources of archived months are got from the cache without requests, others are
+	// revalidated by conditional requests. If CacheDir is empty, responses are not cached.
+	CacheDir string `json:"cache_dir"`
+
+	// CacheMaxAge specifies the time in seconds after the last use of a cached response
+	// when it is removed. If it is 0, responses are not removed by age.
+	CacheMaxAge uint `json:"cache_max_age"`
+
+	// CacheMaxSize specifies max size of the cache in bytes. If it is 0, the size is not limited.
+	CacheMaxSize int64 `json:"cache_max_size"`
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		T: provider.Seishub,
+		Factory: func(conf provider.WatcherConfig) (provider.Watcher, error) {
+			h, err := NewHub(conf)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		Schema: provider.ConfigSchema{
+			Description: "Watches the SEISHUB mailing list web archive for seismic event reports.",
+			ConnStr:     "The address of the mailing list archive.",
+			DefConnStr:  DefConnStr,
+			Options:     Options{},
+		},
+		Rules: provider.RuleSet{provider.RequiredLink, eventIdRule},
+	})
+}
+
+// eventIdRe matches SeisComP event ids of SEISHUB, e.g. "asb2022cfjhkl".
+var eventIdRe = regexp.MustCompile(`^[a-z]+\d{4}[a-z]+$`)
+
+// eventIdRule is a source specific rule checking the format of a non-empty EventId.
+func eventIdRule(m provider.Message) []provider.FieldError {
+	if m.EventId != "" && !eventIdRe.MatchString(m.EventId) {
+		return []provider.FieldError{{Field: "event_id", Reason: fmt.Sprintf("%q is not a SEISHUB event id", m.EventId)}}
+	}
+	return nil
 }
 
 // NewHub returns a pointer to a new seishub.Hub in the stopped state
@@ -129,9 +232,29 @@ func NewHub(conf provider.WatcherConfig) (*Hub, error) {
 		conf.ConnStr = DefConnStr
 	}
 
-	h := &Hub{config: conf, Client: http.Client{Timeout: time.Duration(conf.Timeout) * time.Second}}
+	var opts Options
+	if err := provider.DecodeOptions(conf.Options, &opts); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	var base http.RoundTripper
+	if opts.CacheDir != "" {
+		base = &httpcache.Transport{Base: opts.Fetcher(), Dir: opts.CacheDir, MaxAge: time.Duration(opts.CacheMaxAge) * time.Second,
+			MaxSize: opts.CacheMaxSize, Immutable: func(u *url.URL) bool { return archived(u, time.Now().UTC()) }}
+	}
+
+	h := &Hub{config: conf, UseArchives: opts.UseArchives, GapGrace: defGapGrace,
+		Client: http.Client{Timeout: time.Duration(conf.Timeout) * time.Second, Transport: opts.Transport(base)}}
+
+	if opts.GapGrace > 0 {
+		h.GapGrace = time.Duration(opts.GapGrace) * time.Second
+	}
+
+	if opts.CursorDir != "" {
+		h.Cursors = FileCursorStore{Dir: opts.CursorDir}
+	}
 
-	h.setState(newStoppedState(h))
+	h.setState(newStoppedState(h, provider.Stopped))
 
 	return h, nil
 }
@@ -140,14 +263,60 @@ func (h *Hub) GetConfig() provider.WatcherConfig {
 	return h.config
 }
 
+// setState sets the state of the Hub. The caller must hold h.mu,
+// except while the Hub is being created.
 func (h *Hub) setState(s hubState) {
+	prev := h.state
 	h.state = s
+	h.stats.Transited()
+
+	if prev != nil {
+		to := s.stateInfo()
+		var err error
+		if to == provider.Failed || to == provider.Backoff {
+			err = h.stats.LastErr()
+		}
+		h.lifecycle.Transit(h.config.Id, prev.stateInfo(), to, err)
+	}
+}
+
+// Subscribe implements the provider.Observable interface.
+func (h *Hub) Subscribe(buf int) (<-chan provider.LifecycleEvent, func()) {
+	return h.lifecycle.Subscribe(buf)
+}
+
+// transit sets the state of the Hub holding h.mu.
+// It is called by watching go-routines.
+func (h *Hub) transit(s hubState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.setState(s)
 }
 
 func (h *Hub) StateInfo() provider.WatcherStateInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	return h.state.stateInfo()
 }
 
+// Stats returns the state and statistics of the Hub.
+//
+// Watching starts in the provider.Starting state, while the number of the start message
+// is searched. Then the Hub is in the provider.CatchingUp state until all messages
+// appeared before are got, and in the provider.Run state after that. A failed request
+// switches the Hub into the provider.Backoff state for a growing delay. If the start
+// message cannot be searched, the Hub stops in the provider.Failed state.
+// While catching up, the Backlog of the statistics specifies the number
+// of the discovered messages, which have not been got yet.
+func (h *Hub) Stats() provider.WatcherStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.Stats(h.state.stateInfo())
+}
+
 // StartWatch starts monitoring the appearance of new messages on SEISHUB
 // and extracting such messages (message information).
 //
@@ -157,90 +326,158 @@ func (h *Hub) StateInfo() provider.WatcherStateInfo {
 // Can start watching only in the current month or before.
 // Watching can't be started in future months.Returns an error in such case.
 func (h *Hub) StartWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	o, err := h.state.startWatch(ctx, from)
 	return o, err
 }
 
-// watch waits the start message number from the "sn" channel, then the method checks for new messages
-// with a frequency of "checkPeriod" and sends into the "o" channel.
-func (h *Hub) watch(ctx context.Context, o chan<- provider.Message, sn <-chan int, from time.Time, checkPeriod time.Duration) {
-	defer close(o)
-	msgNum, ok := <-sn //Wait for the start message number
+// watch waits the start cursor from the "sn" channel, then the method discovers new messages
+// on the index pages with a frequency of "checkPeriod" and sends them into the "o" channel
+// (all new messages are fetched in one pass, see discover).
+// The cursor is saved into h.Cursors whenever it moves, i.e. after the discovered
+// messages have been sent or the cursor has passed skipped holes and non-event mails.
+//
+// While the backlog of discovered messages is not empty (e.g. after downtime),
+// the Hub catches up, i.e. the next batch of messages is fetched immediately.
+//
+// A failed check is retried after a delay growing from "checkPeriod" up to maxBackoff.
+// When watching is canceled, the Hub is stopped and the "o" channel is closed.
+func (h *Hub) watch(ctx context.Context, o chan<- provider.Message, sn <-chan Cursor, checkPeriod time.Duration) {
+	final := provider.Stopped
+	defer func() {
+		h.stats.SetBacklog(0)
+		h.transit(newStoppedState(h, final))
+		close(o)
+	}()
+
+	cur, ok := <-sn //Wait for the start cursor
 	if !ok {
+		if ctx.Err() == nil {
+			final = provider.Failed
+		}
 		log.Println("watch: Start msg num channel has been closed. Return.")
 		return
 	}
-	wt := time.NewTicker(checkPeriod)
+
+	state := provider.Starting
+	set := func(info provider.WatcherStateInfo) {
+		if info != state {
+			h.transit(newRunState(h, info))
+			state = info
+		}
+	}
+	set(provider.CatchingUp)
+
+	wt := time.NewTimer(checkPeriod)
 	defer wt.Stop()
 
-	month := provider.MonthYear{Month: from.Month(), Year: from.Year()}
+	caughtUp := false
+	failures := 0
+	t := newTracker(h.GapGrace)
 	for {
 		select {
 		case <-wt.C:
-			msg, err := h.checkMsg(ctx, &msgNum, &month)
-			if err != nil {
-				log.Printf("watch: %v\n", err)
-
-			} else if msg != nil {
-				o <- *msg
-			}
 		case <-ctx.Done():
 			log.Println("watch: Canceled")
+			return
 		}
-	}
-}
 
-// checkMsg checks for a message with the message number "msgNum" in "month"
-// and increments "msgNum" if the message has been found. Otherwise the method
-// checks for a message with this number in the next month and increments
-// "msgNum" and "month" if the message has been found.
-//
-// The method returns a pointer to provider.Message and error. If error is not nil,
-// the message pointer is nil.
-// If the message is not found, the message pointer is nil.
-func (h *Hub) checkMsg(ctx context.Context, msgNum *int, month *provider.MonthYear) (*provider.Message, error) {
-	msgName := msgNumToName(*msgNum)
-	l, err := url.JoinPath(h.config.ConnStr, MonthYearPathSeg(month.Month, month.Year), msgName)
-	if err != nil {
-		return nil, fmt.Errorf("checkMsg: %w", err)
-	}
+		if failures > 0 {
+			h.stats.Retried()
+		}
 
-	msg, err := h.getMsgByLink(ctx, l)
-	if err == nil { //err is EQUAL to nil !!! Getting is succeful
-		*msgNum++
-		return msg, nil
-	}
+		prev := cur
+		msgs, err := h.discover(ctx, &cur, t)
+		if !h.send(ctx, o, msgs, &cur) {
+			log.Println("watch: Canceled")
+			return
+		}
+		if cur.moved(prev) {
+			h.saveCursor(ctx, cur)
+		}
 
-	if !errors.As(err, &NotFoundErr{}) { //all errors except NotFoundErr
-		return nil, err
-	}
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("watch: Canceled")
+				return
+			}
 
-	//NotFound error: check next month
-	nextMonth := month.AddMonth(1)
-	l, err = url.JoinPath(h.config.ConnStr, MonthYearPathSeg(nextMonth.Month, nextMonth.Year), msgName)
-	if err != nil {
-		return nil, fmt.Errorf("checkMsg: %w", err)
+			log.Printf("watch: %v\n", err)
+			h.stats.FetchFailed(err)
+			h.lifecycle.Failed(h.config.Id, state, err)
+			failures++
+			set(provider.Backoff)
+			wt.Reset(backoffDelay(checkPeriod, failures))
+			continue
+		}
+
+		failures = 0
+		h.stats.Polled()
+
+		//catching up: the next batch is fetched immediately
+		if t.backlog > 0 {
+			caughtUp = false
+			set(provider.CatchingUp)
+			wt.Reset(0)
+			continue
+		}
+
+		//the first check without a new message means that
+		//all messages appeared before have been got
+		if len(msgs) == 0 {
+			caughtUp = true
+		}
+		if caughtUp {
+			set(provider.Run)
+		} else {
+			set(provider.CatchingUp)
+		}
+
+		wt.Reset(checkPeriod)
 	}
+}
 
-	msg, err = h.getMsgByLink(ctx, l)
-	if err == nil { //err is EQUAL to nil !!! a message has been found in the next month
-		*msgNum++
-		*month = nextMonth //move to the next month
-		return msg, nil
+// send sends "msgs" into the "o" channel and updates the FocusTime of the cursor "cur".
+// It reports whether all messages have been sent, i.e. watching has not been canceled.
+func (h *Hub) send(ctx context.Context, o chan<- provider.Message, msgs []*provider.Message, cur *Cursor) bool {
+	for _, m := range msgs {
+		select {
+		case o <- *m:
+			h.stats.Emitted()
+		case <-ctx.Done():
+			return false
+		}
+
+		if m.FocusTime.After(cur.FocusTime) {
+			cur.FocusTime = m.FocusTime
+		}
 	}
 
-	if !errors.As(err, &NotFoundErr{}) { //all errors except NotFoundErr
-		return nil, err
+	return true
+}
+
+// backoffDelay returns the delay before retrying after "failures" consecutive
+// failed checks: "checkPeriod" doubled for every failure after the first one,
+// but not more than maxBackoff (or "checkPeriod" if it is greater).
+func backoffDelay(checkPeriod time.Duration, failures int) time.Duration {
+	d := checkPeriod
+	for i := 1; i < failures && d < maxBackoff; i++ {
+		d *= 2
 	}
 
-	//The message has not been found
-	return nil, nil
+	if d > maxBackoff && checkPeriod <= maxBackoff {
+		return maxBackoff
+	}
+	return d
 }
 
-// getStartMsgNum finds a message number according to the logic described below
+// getStartMsgNum finds a start cursor according to the logic described below
 // and sends it into the "sn" channel.
 //
-// The method fetches all messages for the month of the "from" parameter and
+// If h.Cursors has a cursor of the Hub consistent with the "from" parameter
+// (see Cursor), watching is resumed from it. Otherwise the method fetches all messages for the month of the "from" parameter and
 // searches among the messages for the one that has the minimum number and
 // FocusTime of which is after (or equal to) the value of the "from" parameter.
 // This logic is neccesary because SEISHUB DOESN'T ENSURE that a message
@@ -248,30 +485,38 @@ func (h *Hub) checkMsg(ctx context.Context, msgNum *int, month *provider.MonthYe
 // If no messages are fetched, i.e., there are no messages in the specified
 // (as a rule current) month yet, fetching will be repeated with a frequency
 // of "checkPeriod" until at least one message is received.
-func (h *Hub) getStartMsgNum(ctx context.Context, sn chan<- int, from time.Time, checkPeriod time.Duration) {
+func (h *Hub) getStartMsgNum(ctx context.Context, sn chan<- Cursor, from time.Time, checkPeriod time.Duration) {
+	defer close(sn)
+
+	if c, ok := h.loadCursor(ctx, from); ok {
+		sn <- c
+		return
+	}
+
 	m := provider.MonthYear{Month: from.Month(), Year: from.Year()}
 	wt := time.NewTicker(checkPeriod)
-	defer func() {
-		wt.Stop()
-		close(sn)
-	}()
+	defer wt.Stop()
 
 	for {
 		select {
 		case <-wt.C:
-			msgs, err := h.Extract(ctx, m, m, 0)
+			//message numbers are known only for messages got from message pages
+			msgs, err := h.extract(ctx, m, m, ExtractOptions{}, false)
 			if err != nil {
 				log.Printf("getStartMsgNum: %v", err)
+				h.stats.SetErr(fmt.Errorf("getStartMsgNum: %w", err))
 				return
 			}
+			h.stats.Polled()
 
 			if len(msgs) > 0 {
 				n, err := findStartMsgNum(msgs, from)
 				if err != nil {
 					log.Printf("getStartMsgNum: %v", err)
+					h.stats.SetErr(fmt.Errorf("getStartMsgNum: %w", err))
 					return
 				}
-				sn <- n
+				sn <- Cursor{MsgNum: n, Month: m}
 				return
 			}
 		case <-ctx.Done():
@@ -281,6 +526,43 @@ func (h *Hub) getStartMsgNum(ctx context.Context, sn chan<- int, from time.Time,
 	}
 }
 
+// loadCursor returns the cursor of the Hub from h.Cursors and reports
+// whether watching beginning from the time "from" can be resumed from it.
+func (h *Hub) loadCursor(ctx context.Context, from time.Time) (Cursor, bool) {
+	if h.Cursors == nil {
+		return Cursor{}, false
+	}
+
+	c, ok, err := h.Cursors.LoadCursor(ctx, h.config.Id)
+	if err != nil {
+		log.Printf("loadCursor: %v", err)
+		return Cursor{}, false
+	}
+	if !ok {
+		return Cursor{}, false
+	}
+
+	if !c.consistent(from) {
+		log.Printf("loadCursor: cursor %+v is inconsistent with the start time %v, searching the start message", c, from)
+		return Cursor{}, false
+	}
+
+	return c, true
+}
+
+// saveCursor saves the cursor "c" of the Hub into h.Cursors.
+// An error is logged and recorded in the statistics, but watching goes on.
+func (h *Hub) saveCursor(ctx context.Context, c Cursor) {
+	if h.Cursors == nil {
+		return
+	}
+
+	if err := h.Cursors.SaveCursor(ctx, h.config.Id, c); err != nil {
+		log.Printf("saveCursor: %v", err)
+		h.stats.SetErr(fmt.Errorf("saveCursor: %w", err))
+	}
+}
+
 // findStartMsgNum searches among the messages for the one that has the minimum
 // number and FocusTime of which is after (or equal to) the value of the "from"
 // parameter.
@@ -327,6 +609,12 @@ func (h *Hub) getMsgByLink(ctx context.Context, link string) (*provider.Message,
 	}
 	m.SourceId = h.config.Id
 
+	//numbers of message pages grow with every mail of the list,
+	//so a later report about the same event has a greater version
+	if n, err := parseMsgNum(link); err == nil {
+		m.Version = n
+	}
+
 	return m, nil
 }
 
@@ -337,71 +625,107 @@ func (h *Hub) getMsgByLink(ctx context.Context, link string) (*provider.Message,
 // The "paral" parameter defines a number of go-routines to process message links (getting messages).
 // if "paral" is less (or equal to) 0, the default falue is used.
 //
-// Attention! The method does not guarantee immediate termination by context cancellation.
+// If UseArchives is set, messages of the months before the current one are extracted
+// from monthly archives (see GetArchiveMsgs). If an archive cannot be got,
+// the message pages of its month are used.
+//
+// Errors of getting messages are logged, see ExtractStream to get them with the messages.
 func (h *Hub) Extract(ctx context.Context,
 	from provider.MonthYear, to provider.MonthYear, paral int) ([]*provider.Message, error) {
 
-	monthNum := to.Diff(from) + 1
-	if monthNum <= 0 {
-		return nil, fmt.Errorf(`Extract: the "from" arg cannot be more than the "to" arg`)
+	msgs, err := h.extract(ctx, from, to, ExtractOptions{Paral: paral}, h.UseArchives)
+	if err != nil {
+		return nil, fmt.Errorf("Extract: %w", err)
 	}
 
-	if paral <= 0 {
-		paral = defParal
-	}
+	return msgs, nil
+}
 
-	//Result slice of messages
-	msgs := make([]*provider.Message, 0, avgMonthMsgNum*monthNum)
-	links := make(chan string)
+// Backfill implements the provider.Backfiller interface. It extracts messages
+// of the months covering the [from, to) range like Extract and sends the ones
+// with the FocusTime in the range in ascending order of the FocusTime.
+//
+// Backfilling can be performed while the Hub is watching.
+func (h *Hub) Backfill(ctx context.Context, from time.Time, to time.Time) (<-chan provider.Message, error) {
+	if err := provider.CheckRange(from, to); err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(paral)
-	for i := 0; i < paral; i++ {
-		go func() {
-			defer func() {
-				wg.Done()
-			}()
-			for l := range links {
-				msg, err := h.getMsgByLink(ctx, l)
-				if err != nil {
-					log.Printf("Extract: link: %q error: %v", l, err)
-				} else {
-					msgs = append(msgs, msg)
-				}
+	f, t := from.UTC(), to.UTC().Add(-time.Nanosecond)
+	first := provider.MonthYear{Month: f.Month(), Year: f.Year()}
+	last := provider.MonthYear{Month: t.Month(), Year: t.Year()}
+
+	o := make(chan provider.Message)
+	go func() {
+		defer close(o)
+
+		//the progress is logged once per scanned month
+		scanned := 0
+		progress := func(p ExtractProgress) {
+			if p.MonthsScanned != scanned {
+				scanned = p.MonthsScanned
+				log.Printf("Backfill: %s: months scanned: %d/%d, links done: %d/%d",
+					h.config.Id, p.MonthsScanned, p.Months, p.LinksDone, p.Links)
 			}
-		}()
-	}
+		}
 
-	for m := from; !m.After(to); m = m.AddMonth(1) {
-		sg := MonthYearPathSeg(m.Month, m.Year)
-		monthLink, err := url.JoinPath(h.config.ConnStr, sg)
+		msgs, err := h.extract(ctx, first, last, ExtractOptions{Progress: progress}, h.UseArchives)
 		if err != nil {
-			//return nil, fmt.Errorf("Extract: %v ", err)
-			log.Printf("Extract: %v", err)
-			continue
+			log.Printf("Backfill: %v", err)
+			return
 		}
 
-		names, err := GetMsgNames(ctx, monthLink, &h.Client)
-		if err != nil { //&& errors.As(err, &NotFoundErr{}) {
-			log.Printf("Extract: %v", err)
-			continue
+		res := make([]provider.Message, 0, len(msgs))
+		for _, m := range msgs {
+			if provider.InRange(m.FocusTime, from, to) {
+				res = append(res, *m)
+			}
 		}
-		// // else if err != nil {
-		// // 	return nil, fmt.Errorf("Extract: %v", err)
-		// }
+		sort.SliceStable(res, func(i, j int) bool { return res[i].FocusTime.Before(res[j].FocusTime) })
 
-		for _, n := range names {
-			l, err := url.JoinPath(monthLink, n)
-			if err != nil {
-				//return nil, fmt.Errorf("Extract: %v", err)
-				log.Printf("Extract: %v", err)
-				continue
-			}
-			links <- l
+		provider.SendAll(ctx, o, res)
+	}()
+
+	return o, nil
+}
+
+// extract implements Extract. The "useArchives" parameter specifies
+// whether monthly archives are used for the months before the current one.
+// Errors of getting messages are logged.
+func (h *Hub) extract(ctx context.Context,
+	from provider.MonthYear, to provider.MonthYear, opts ExtractOptions, useArchives bool) ([]*provider.Message, error) {
+
+	if from.After(to) {
+		return nil, fmt.Errorf(`extract: the "from" arg cannot be more than the "to" arg`)
+	}
+
+	msgs := make([]*provider.Message, 0, avgMonthMsgNum*(to.Diff(from)+1))
+	for r := range h.extractStream(ctx, from, to, opts, useArchives) {
+		if r.Err != nil {
+			log.Printf("extract: link: %q error: %v", r.Link, r.Err)
+			continue
 		}
+		msgs = append(msgs, r.Msg)
+	}
+
+	return msgs, nil
+}
+
+// getArchiveMsgs returns messages extracted from the monthly archive of "m" and an error.
+func (h *Hub) getArchiveMsgs(ctx context.Context, m provider.MonthYear) ([]*provider.Message, error) {
+	l, err := url.JoinPath(h.config.ConnStr, ArchiveName(m))
+	if err != nil {
+		return nil, fmt.Errorf("getArchiveMsgs: %w", err)
+	}
+
+	msgs, err := GetArchiveMsgs(ctx, l, &h.Client)
+	if err != nil {
+		return nil, fmt.Errorf("getArchiveMsgs: %w", err)
+	}
+
+	for _, msg := range msgs {
+		msg.SourceId = h.config.Id
 	}
-	close(links)
-	wg.Wait()
 
 	return msgs, nil
 }
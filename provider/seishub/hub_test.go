@@ -1,12 +1,21 @@
 package seishub
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"seismo/provider"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // func Test_ExtractMessages(t *testing.T) {
@@ -123,3 +132,241 @@ func Test_msgNumToName(t *testing.T) {
 		}
 	}
 }
+
+// newStateTestServer returns a stand-in of SEISHUB serving the February 2022 message pages
+// from testdata with numbers up to "last" and the message list of them. If "broken" is set,
+// connections of all requests are broken.
+func newStateTestServer(t *testing.T, last *int32, broken *int32) *httptest.Server {
+	const month = "/2022-February/"
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(broken) != 0 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+
+		if r.URL.Path == month || r.URL.Path+"/" == month || r.URL.Path == month+IndexPage {
+			var b strings.Builder
+			for n := 17538; n <= int(atomic.LoadInt32(last)); n++ {
+				fmt.Fprintf(&b, "<LI><A HREF=\"%s\">[Seismic-Report] message (event%d)</A>\n", msgNumToName(n), n)
+			}
+			w.Write([]byte(b.String()))
+			return
+		}
+
+		if !strings.HasPrefix(r.URL.Path, month) {
+			http.NotFound(w, r)
+			return
+		}
+
+		n, err := parseMsgNum(r.URL.Path)
+		if err != nil || n > int(atomic.LoadInt32(last)) {
+			http.NotFound(w, r)
+			return
+		}
+
+		b, err := os.ReadFile(path.Join("testdata/html", r.URL.Path))
+		if err != nil {
+			t.Errorf("newStateTestServer: %v", err)
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+}
+
+func Test_StartWatch_States(t *testing.T) {
+	last, broken := int32(17540), int32(0)
+	srv := newStateTestServer(t, &last, &broken)
+	defer srv.Close()
+
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: 1})
+	if err != nil {
+		t.Fatalf("Test_StartWatch_States: %v", err)
+	}
+
+	events, unsubscribe := h.Subscribe(32)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_States: %v", err)
+	}
+	if s := h.StateInfo(); s != provider.Starting {
+		t.Errorf("Test_StartWatch_States: want state: %s, res: %s", provider.Starting, s)
+	}
+
+	//waitState waits for the state "want" receiving messages
+	waitState := func(want provider.WatcherStateInfo) {
+		for h.StateInfo() != want {
+			select {
+			case <-ch:
+			case <-time.After(10 * time.Millisecond):
+			case <-ctx.Done():
+				t.Fatalf("Test_StartWatch_States: timeout of waiting for the %s state", want)
+			}
+		}
+	}
+
+	//the existing messages are caught up
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			t.Fatalf("Test_StartWatch_States: timeout")
+		}
+		if s := h.StateInfo(); s != provider.CatchingUp {
+			t.Errorf("Test_StartWatch_States: message %d: want state: %s, res: %s", i, provider.CatchingUp, s)
+		}
+	}
+	waitState(provider.Run)
+
+	atomic.StoreInt32(&broken, 1)
+	waitState(provider.Backoff)
+
+	st := h.Stats()
+	if st.FetchErrors == 0 || st.LastErr == nil || st.Emitted != 3 || st.LastPoll.IsZero() || st.LastMsg.IsZero() {
+		t.Errorf("Test_StartWatch_States: unexpected stats in the backoff state: %+v", st)
+	}
+
+	//a new message is got after recovering
+	atomic.AddInt32(&last, 1)
+	atomic.StoreInt32(&broken, 0)
+	select {
+	case m := <-ch:
+		if m.EventId != "asb2022cfkkhd" {
+			t.Errorf("Test_StartWatch_States: unexpected message after recovering: %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Test_StartWatch_States: timeout")
+	}
+	if st := h.Stats(); st.State != provider.Run || st.Retries == 0 || st.Emitted != 4 {
+		t.Errorf("Test_StartWatch_States: unexpected stats after recovering: %+v", st)
+	}
+
+	cancel()
+	for range ch {
+	}
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_StartWatch_States: want state: %s, res: %s", provider.Stopped, s)
+	}
+
+	//errors are repeated while the connection is broken
+	unsubscribe()
+	var kinds []string
+	for e := range events {
+		if e.Kind == provider.LifecycleError && kinds[len(kinds)-1] == string(provider.LifecycleError) {
+			continue
+		}
+		kinds = append(kinds, string(e.Kind))
+	}
+
+	want := "started caught_up error stalled stopped"
+	if res := strings.Join(kinds, " "); !strings.HasPrefix(res, strings.TrimSuffix(want, " stopped")) ||
+		!strings.HasSuffix(res, "stopped") {
+		t.Errorf("Test_StartWatch_States: want lifecycle events: %s, result: %s", want, res)
+	}
+}
+
+func Test_backoffDelay(t *testing.T) {
+	tests := []struct {
+		period   time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{time.Second, 1, time.Second},
+		{time.Second, 3, 4 * time.Second},
+		{time.Second, 100, maxBackoff},
+		{10 * time.Minute, 2, 10 * time.Minute},
+	}
+
+	for _, test := range tests {
+		if res := backoffDelay(test.period, test.failures); res != test.want {
+			t.Errorf("Test_backoffDelay: period: %v failures: %d want: %v res: %v", test.period, test.failures, test.want, res)
+		}
+	}
+}
+
+func Test_StartWatch_Discover(t *testing.T) {
+	last, broken := int32(17540), int32(0)
+	srv := newStateTestServer(t, &last, &broken)
+	defer srv.Close()
+
+	const checkPeriod = 2 * time.Second
+	h, err := NewHub(provider.WatcherConfig{Id: "seishub", ConnStr: srv.URL, Timeout: 5, CheckPeriod: uint(checkPeriod / time.Second)})
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Discover: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Discover: %v", err)
+	}
+
+	receive := func() provider.Message {
+		select {
+		case m := <-ch:
+			return m
+		case <-ctx.Done():
+			t.Fatalf("Test_StartWatch_Discover: timeout")
+		}
+		return provider.Message{}
+	}
+
+	for i := 0; i < 3; i++ {
+		receive()
+	}
+	for h.StateInfo() != provider.Run {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//all new messages are fetched in one pass
+	atomic.StoreInt32(&last, 17545)
+	first := receive()
+	start := time.Now()
+	nums := []int{first.Version}
+	for i := 0; i < 4; i++ {
+		nums = append(nums, receive().Version)
+	}
+	if d := time.Since(start); d >= checkPeriod {
+		t.Errorf("Test_StartWatch_Discover: new messages have been got in %v, not in one pass", d)
+	}
+	if diff := cmp.Diff([]int{17541, 17542, 17543, 17544, 17545}, nums); diff != "" {
+		t.Errorf("Test_StartWatch_Discover: message numbers (-want +res):\n%s", diff)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func Test_RulesFor(t *testing.T) {
+	m := provider.Message{
+		SourceId:  "seishub",
+		EventId:   "us7000jk3l",
+		FocusTime: time.Date(2022, 2, 1, 5, 55, 14, 445000000, time.UTC),
+		Latitude:  54.38,
+		Longitude: 86.13,
+		Magnitude: 2.4,
+		Depth:     provider.Float(10),
+	}
+
+	var ve provider.ValidationErr
+	if err := provider.RulesFor(provider.Seishub).Validate(m); !errors.As(err, &ve) || !cmp.Equal(ve.FieldNames(), []string{"link", "event_id"}) {
+		t.Errorf("Test_RulesFor: unexpected result: %v", err)
+	}
+
+	m.EventId = "asb2022cfjhkl"
+	m.Link = "http://seishub.ru/pipermail/seismic-report/2022-February/017538.html"
+	if err := provider.RulesFor(provider.Seishub).Validate(m); err != nil {
+		t.Errorf("Test_RulesFor: unexpected error: %v", err)
+	}
+}
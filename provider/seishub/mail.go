@@ -0,0 +1,170 @@
+package seishub
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Mail represents a decoded mail message of the SEISHUB mailing list.
+type Mail struct {
+	// MessageId specifies the value of the "Message-ID" header without angle brackets.
+	MessageId string
+
+	// Subject specifies the decoded subject.
+	Subject string
+
+	// Date specifies the time the mail was sent. It is zero if the date is absent or malformed.
+	Date time.Time
+
+	// Body specifies the decoded text of the mail.
+	Body string
+}
+
+// ReadMail returns a mail read from "r" and an error.
+// If the returned error is not nil, the returned pointer is nil.
+//
+// Encoded words of the subject (RFC 2047) are decoded, as well as the body
+// in the "base64" or "quoted-printable" transfer encoding. Bodies in charsets
+// other than UTF-8 (e.g. "koi8-r", "windows-1251") are converted to UTF-8.
+// The body of a multipart mail is its first "text/plain" part.
+func ReadMail(r io.Reader) (*Mail, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("ReadMail: %w", err)
+	}
+
+	m := Mail{MessageId: strings.Trim(msg.Header.Get("Message-Id"), " <>")}
+
+	dec := mime.WordDecoder{CharsetReader: charsetReader}
+	m.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil { //keep the subject as is
+		m.Subject = msg.Header.Get("Subject")
+	}
+
+	if d, err := msg.Header.Date(); err == nil {
+		m.Date = d.UTC()
+	}
+
+	m.Body, err = decodeBody(msg.Header, msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ReadMail: message id %q: %w", m.MessageId, err)
+	}
+
+	return &m, nil
+}
+
+// decodeBody returns a text of a mail body read from "r" and decoded according to
+// the "Content-Type" and "Content-Transfer-Encoding" headers "h" and an error.
+func decodeBody(h header, r io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil { //plain text is assumed
+		mediaType, params = "text/plain", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, &newlineSkipper{r: r})
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return decodeMultipart(r, params["boundary"])
+	}
+
+	if cs := params["charset"]; cs != "" {
+		if r, err = charsetReader(cs, r); err != nil {
+			return "", fmt.Errorf("decodeBody: %w", err)
+		}
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("decodeBody: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// decodeMultipart returns a decoded text of the first "text/plain" part
+// (including nested multipart ones) read from "r" and an error.
+func decodeMultipart(r io.Reader, boundary string) (string, error) {
+	if boundary == "" {
+		return "", fmt.Errorf("decodeMultipart: no boundary")
+	}
+
+	mr := multipart.NewReader(r, boundary)
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			return "", fmt.Errorf("decodeMultipart: no text part")
+		}
+		if err != nil {
+			return "", fmt.Errorf("decodeMultipart: %w", err)
+		}
+
+		mediaType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			mediaType = "text/plain"
+		}
+
+		if mediaType == "text/plain" || strings.HasPrefix(mediaType, "multipart/") {
+			s, err := decodeBody(p.Header, p)
+			if err != nil {
+				return "", fmt.Errorf("decodeMultipart: %w", err)
+			}
+			return s, nil
+		}
+	}
+}
+
+// header is implemented by headers of mails (mail.Header) and
+// their parts (textproto.MIMEHeader).
+type header interface {
+	Get(key string) string
+}
+
+// charsetReader returns a reader converting text in "charset" read from "input" to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii":
+		return input, nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("charsetReader: unsupported charset %q", charset)
+	}
+
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// newlineSkipper is a reader skipping line breaks, which split base64 encoded text.
+type newlineSkipper struct {
+	r io.Reader
+}
+
+func (s *newlineSkipper) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package seishub
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ReadMail(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Mail
+	}{
+		{
+			"plain",
+			"Message-ID: <1@lists.seishub.ru>\nDate: Tue, 01 Feb 2022 05:56:54 +0300\nSubject: =?utf-8?b?0KLQtdGB0YI=?=\n\nEVENT PUBLIC ID: asb2022cfjhkl\n",
+			Mail{MessageId: "1@lists.seishub.ru", Subject: "Тест",
+				Date: time.Date(2022, 2, 1, 2, 56, 54, 0, time.UTC), Body: "EVENT PUBLIC ID: asb2022cfjhkl\n"},
+		},
+		{
+			"base64",
+			"Subject: =?utf-8?q?=D0=A2=D0=B5=D1=81=D1=82?=\nContent-Transfer-Encoding: base64\n\n0KjQmNCg0J7QotCQ\nOiA1NC4zOA==\n",
+			Mail{Subject: "Тест", Body: "ШИРОТА: 54.38"},
+		},
+		{
+			"quoted-printable",
+			"Subject: Test\nContent-Transfer-Encoding: quoted-printable\n\n=D0=A8=D0=98=D0=A0=D0=9E=D0=A2=D0=90: 54=\n.38\n",
+			Mail{Subject: "Test", Body: "ШИРОТА: 54.38\n"},
+		},
+	}
+
+	for _, test := range tests {
+		res, err := ReadMail(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("Test_ReadMail: %s: %v", test.name, err)
+		}
+
+		if *res != test.want {
+			t.Errorf("Test_ReadMail: %s:\n\twant: %+v\n\tresult: %+v", test.name, test.want, *res)
+		}
+	}
+}
+
+func Test_ReadMail_MIME(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"koi8-r",
+			"Subject: =?koi8-r?b?9MXT1A==?=\nContent-Type: text/plain; charset=koi8-r\n\n\xfb\xe9\xf2\xef\xf4\xe1: 54.38\n",
+			"ШИРОТА: 54.38\n",
+		},
+		{
+			"multipart",
+			"Subject: =?koi8-r?b?9MXT1A==?=\nContent-Type: multipart/mixed; boundary=\"b1\"\n\n" +
+				"--b1\nContent-Type: multipart/alternative; boundary=\"b2\"\n\n" +
+				"--b2\nContent-Type: text/html; charset=utf-8\n\n<p>html</p>\n" +
+				"--b2\nContent-Type: text/plain; charset=windows-1251\nContent-Transfer-Encoding: base64\n\n2MjQztLAOiA1NC4zOA==\n" +
+				"--b2--\n" +
+				"--b1\nContent-Type: application/octet-stream\n\nAAAA\n--b1--\n",
+			"ШИРОТА: 54.38",
+		},
+	}
+
+	for _, test := range tests {
+		res, err := ReadMail(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("Test_ReadMail_MIME: %s: %v", test.name, err)
+		}
+
+		if res.Subject != "Тест" {
+			t.Errorf("Test_ReadMail_MIME: %s: want subject: %q, result: %q", test.name, "Тест", res.Subject)
+		}
+
+		if res.Body != test.want {
+			t.Errorf("Test_ReadMail_MIME: %s: want body: %q, result: %q", test.name, test.want, res.Body)
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"net/url"
 	"regexp"
 	"seismo/provider"
+	"seismo/provider/fetch"
 	"strconv"
 	"strings"
 	"time"
@@ -33,8 +34,8 @@ import (
 
 // defClient is a package-level default http client, that can be
 // used by package functions, having no specified client(s).
-// The Timeout value is 60 sec.
-var defClient = http.Client{Timeout: 60 * time.Second}
+// The Timeout value is 60 sec, failed requests are retried by the shared fetch layer.
+var defClient = http.Client{Timeout: 60 * time.Second, Transport: &fetch.Transport{Retries: fetch.DefRetries}}
 
 // NotFoundErr indicates that resource was not found.
 // Can be useful to represent a 404 status as an error type.
@@ -46,6 +47,20 @@ func (e NotFoundErr) Error() string {
 	return fmt.Sprintf("Not found %s", e.link)
 }
 
+// checkStatus returns an error if the status of the response "resp" of the resource
+// addressed by "link" is not 200 OK. The error is NotFoundErr for the 404 Not Found
+// and 410 Gone statuses, fetch.StatusErr otherwise (e.g. a transient server error).
+func checkStatus(resp *http.Response, link string) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound, http.StatusGone:
+		return NotFoundErr{link: link}
+	default:
+		return fetch.StatusErr{URL: link, StatusCode: resp.StatusCode}
+	}
+}
+
 // GetMsgPages returns a map of message pages (html code), where the key is
 // a name of a message and nil.
 // If the returned error is not nil, the returned map is nil.
@@ -94,8 +109,8 @@ func GetMsgNamesPage(ctx context.Context, dir string, cl *http.Client) (string,
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GetMsgNamesPage: error: %w", NotFoundErr{link: dir})
+	if err := checkStatus(resp, dir); err != nil {
+		return "", fmt.Errorf("GetMsgNamesPage: error: %w", err)
 	}
 
 	buf := new(strings.Builder)
@@ -131,6 +146,65 @@ func GetMsgNames(ctx context.Context, dir string, cl *http.Client) ([]string, er
 	return ParseMsgNames(namesPage), nil
 }
 
+// IndexPage is the name of the message list page of a month ordered by date.
+const IndexPage = "date.html"
+
+// IndexEntry represents a message listed on a message list page.
+type IndexEntry struct {
+	// Name specifies the message name, e.g. "017540.html".
+	Name string
+
+	// Num specifies the message number.
+	Num int
+
+	// EventId specifies the event identifier from the message subject.
+	// It is empty if the subject contains no identifier (the mail is not an event report).
+	EventId string
+}
+
+// ParseMsgIndex finds all messages on a message list page (in its html code passed in s)
+// and returns their entries in the order of the page.
+func ParseMsgIndex(s string) []IndexEntry {
+	re := regexp.MustCompile(`(?i)<LI><A HREF="((\d+)\.html)">([^<]*)</A>`)
+	reId := regexp.MustCompile(`\((\w+)\)\s*$`)
+
+	matches := re.FindAllStringSubmatch(s, -1)
+	res := make([]IndexEntry, 0, len(matches))
+	for _, m := range matches {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+
+		e := IndexEntry{Name: m[1], Num: n}
+		if id := reId.FindStringSubmatch(m[3]); id != nil {
+			e.EventId = id[1]
+		}
+		res = append(res, e)
+	}
+
+	return res
+}
+
+// GetMsgIndex returns entries of the messages listed on the IndexPage
+// of the message list addressed by "dir" and an error.
+// If the returned error is not nil, the returned slice is nil.
+//
+// If the "cl" parameter is nil, the function uses the default package-level http client.
+func GetMsgIndex(ctx context.Context, dir string, cl *http.Client) ([]IndexEntry, error) {
+	l, err := url.JoinPath(dir, IndexPage)
+	if err != nil {
+		return nil, fmt.Errorf("GetMsgIndex: %w", err)
+	}
+
+	page, err := GetMsgNamesPage(ctx, l, cl)
+	if err != nil {
+		return nil, fmt.Errorf("GetMsgIndex: %w", err)
+	}
+
+	return ParseMsgIndex(page), nil
+}
+
 // GetMsgPage returns a message html page addressed by link and error.
 // If the returned error is not nil, the returned string is empty.
 //
@@ -151,8 +225,8 @@ func GetMsgPage(ctx context.Context, link string, cl *http.Client) (string, erro
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("getMsgPage: error: %w", NotFoundErr{link: link})
+	if err := checkStatus(resp, link); err != nil {
+		return "", fmt.Errorf("getMsgPage: error: %w", err)
 	}
 
 	buf := new(strings.Builder)
@@ -246,19 +320,65 @@ func GetMsg(ctx context.Context, link string, cl *http.Client) (m *provider.Mess
 	}
 	m.Link = link
 
+	//the date of the mail is the update time like the date of an archived mail,
+	//so messages of pages and archives can be ordered against each other
+	if d, err := ParsePageDate(sm); err == nil {
+		m.UpdateTime = d
+	} else {
+		log.Printf("GetMsg: link %s: %v", link, err)
+	}
+
 	return m, nil
 }
 
+// pageMonths maps month abbreviations of message pages (Russian and English) to months.
+var pageMonths = map[string]time.Month{
+	"янв": time.January, "фев": time.February, "мар": time.March, "апр": time.April,
+	"май": time.May, "июн": time.June, "июл": time.July, "авг": time.August,
+	"сен": time.September, "окт": time.October, "ноя": time.November, "дек": time.December,
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// pageZones maps time zone abbreviations of message pages to their offsets.
+var pageZones = map[string]time.Duration{
+	"UTC": 0, "GMT": 0, "MSK": 3 * time.Hour,
+}
+
+// ParsePageDate returns the UTC-time a mail was sent, found on its message page
+// (in its html code passed in s) like "<I>Вт Фев  1 05:55:38 UTC 2022</I>", and an error.
+// The month and the time zone abbreviations must be known.
+func ParsePageDate(s string) (time.Time, error) {
+	re := regexp.MustCompile(`<I>\S+ +(\S+) +(\d{1,2}) (\d{2}:\d{2}:\d{2}) ([A-Z]+) (\d{4})</I>`)
+	sm := re.FindStringSubmatch(s)
+	if sm == nil {
+		return time.Time{}, fmt.Errorf("ParsePageDate: the date is not found")
+	}
+
+	mon, ok := pageMonths[strings.ToLower(sm[1])]
+	if !ok {
+		return time.Time{}, fmt.Errorf("ParsePageDate: unknown month %q", sm[1])
+	}
+	off, ok := pageZones[sm[4]]
+	if !ok {
+		return time.Time{}, fmt.Errorf("ParsePageDate: unknown time zone %q", sm[4])
+	}
+
+	c, err := time.Parse("15:04:05", sm[3])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ParsePageDate: %w", err)
+	}
+	day, _ := strconv.Atoi(sm[2])
+	year, _ := strconv.Atoi(sm[5])
+
+	t := time.Date(year, mon, day, c.Hour(), c.Minute(), c.Second(), 0, time.UTC)
+	return t.Add(-off), nil
+}
+
 // defineEventType converts a passed string value to the corresponding EventType value.
 func defineEventType(s string) provider.EventType {
-	switch strings.ToLower(s) {
-	case "quarry blast":
-		return provider.QuarryBlast
-	case "earthquake":
-		return provider.EarthQuake
-	default:
-		return provider.UnknownType
-	}
+	return provider.ParseEventType(s)
 }
 
 // defineEventQuality converts a passed string value to the corresponding EventQuality value.
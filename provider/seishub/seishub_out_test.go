@@ -53,7 +53,7 @@ func Test_GetMsg(t *testing.T) {
 		t.Errorf("Test_extractMsg: \n\t error: %v", err)
 	}
 
-	if res == nil || *res != want {
+	if res == nil || !res.Equal(want) {
 		t.Errorf("extractMsg: \n\t result != want")
 	}
 }
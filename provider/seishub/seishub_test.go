@@ -2,12 +2,16 @@ package seishub
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path"
 	"seismo/provider"
+	"seismo/provider/fetch"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -124,6 +128,30 @@ func max(a, b int) int {
 	return b
 }
 
+func Test_ParseMsgIndex(t *testing.T) {
+	b, err := os.ReadFile("testdata/html/2022-April.html")
+	if err != nil {
+		t.Fatalf("Test_ParseMsgIndex: %v", err)
+	}
+
+	res := ParseMsgIndex(string(b))
+	if len(res) != 280 {
+		t.Errorf("Test_ParseMsgIndex: want: 280 entries, res: %d", len(res))
+	}
+
+	want := IndexEntry{Name: "018087.html", Num: 18087, EventId: "asb2022gjtojy"}
+	if len(res) > 0 && res[0] != want {
+		t.Errorf("Test_ParseMsgIndex: want: %+v res: %+v", want, res[0])
+	}
+
+	//mail which is not an event report
+	res = ParseMsgIndex(`<LI><A HREF="018500.html">[Seismic-Report] Maintenance</A><A NAME="18500">&nbsp;</A>`)
+	want = IndexEntry{Name: "018500.html", Num: 18500}
+	if len(res) != 1 || res[0] != want {
+		t.Errorf("Test_ParseMsgIndex: want: %+v res: %+v", want, res)
+	}
+}
+
 func Test_ParseMsg(t *testing.T) {
 	inputDataDir := "testdata/html/2022-February"
 	wantDataDir := "testdata/json_msg/2022-February"
@@ -165,7 +193,7 @@ func Test_ParseMsg(t *testing.T) {
 			t.Fatalf("\nTest_ParseMsg: Cannot unmarshal \"%s\"; error: %v", f.Name()+".json", err)
 		}
 
-		if *resultMsg != wantMsg {
+		if !resultMsg.Equal(wantMsg) {
 			t.Errorf("\nTest_ParseMsg: \twant: %v\n\t result: %v\n", wantMsg, *resultMsg)
 		}
 	}
@@ -196,3 +224,63 @@ func Test_monthYearPathSeg(t *testing.T) {
 		}
 	}
 }
+
+func Test_checkStatus(t *testing.T) {
+	tests := []struct {
+		code      int
+		notFound  bool
+		transient bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNotFound, true, false},
+		{http.StatusGone, true, false},
+		{http.StatusBadGateway, false, true},
+		{http.StatusForbidden, false, false},
+	}
+
+	for _, test := range tests {
+		err := checkStatus(&http.Response{StatusCode: test.code}, "http://seishub.ru/x")
+		if (test.code == http.StatusOK) != (err == nil) {
+			t.Errorf("Test_checkStatus: %d: unexpected error: %v", test.code, err)
+		}
+		if nf := errors.As(err, &NotFoundErr{}); nf != test.notFound {
+			t.Errorf("Test_checkStatus: %d: want NotFoundErr: %v res: %v", test.code, test.notFound, nf)
+		}
+		if tr := fetch.IsTransient(err); tr != test.transient {
+			t.Errorf("Test_checkStatus: %d: want transient: %v res: %v", test.code, test.transient, tr)
+		}
+	}
+}
+
+func Test_ParsePageDate(t *testing.T) {
+	page, err := os.ReadFile("testdata/html/2022-February/017540.html")
+	if err != nil {
+		t.Fatalf("Test_ParsePageDate: %v", err)
+	}
+
+	tests := []struct {
+		s    string
+		want time.Time
+		ok   bool
+	}{
+		{string(page), time.Date(2022, 2, 1, 5, 55, 38, 0, time.UTC), true},
+		{"<I>Tue Feb 28 08:56:20 MSK 2023</I>", time.Date(2023, 2, 28, 5, 56, 20, 0, time.UTC), true},
+		{"<I>Вс Дек 31 23:00:00 GMT 2023</I>", time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC), true},
+		{"<I>Tue Feb  1 08:56:20 XYZ 2022</I>", time.Time{}, false},
+		{"no date", time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		res, err := ParsePageDate(test.s)
+		if (err == nil) != test.ok || !res.Equal(test.want) {
+			t.Errorf("Test_ParsePageDate: want: %v %v res: %v %v", test.want, test.ok, res, err)
+		}
+	}
+
+	//a page message and an archived message of the same event are ordered by the mail dates
+	pm := provider.Message{Version: 17540, UpdateTime: tests[0].want}
+	am := provider.Message{UpdateTime: tests[0].want.Add(-time.Minute)}
+	if am.Supersedes(pm) || !pm.Supersedes(am) {
+		t.Errorf("Test_ParsePageDate: the archived message must not supersede the later page message")
+	}
+}
@@ -0,0 +1,353 @@
+package smtpd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"seismo/provider"
+	"seismo/provider/seishub"
+	"sync"
+	"time"
+)
+
+const (
+	//minAcceptDelay and maxAcceptDelay define the bounds of the delay
+	//before retrying a failed Accept
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = time.Second
+)
+
+// hubState is implemented to provide a specific behavior within THE STATE PATTERN.
+type hubState interface {
+	startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error)
+	stateInfo() provider.WatcherStateInfo
+}
+
+// stoppedState implements a stopped Hub's behavior within THE STATE PATTERN.
+type stoppedState struct {
+	hub *Hub
+}
+
+func newStoppedState(h *Hub) *stoppedState {
+	return &stoppedState{hub: h}
+}
+
+// startWatch implements the behaivor of Hub.StartWatch in the "stopped" state,
+// i.e. starts listening for SMTP connections and returns a channel for fetching messages.
+// If the returned error is not nil, the returned channel is nil.
+func (s *stoppedState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("cannot start with canceled context")
+	}
+
+	h := s.hub
+	ln, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	h.setState(newRunState(h))
+	h.ln = ln
+	o := make(chan provider.Message)
+	go h.watch(ctx, ln, o, from.UTC())
+
+	return o, nil
+}
+
+func (s *stoppedState) stateInfo() provider.WatcherStateInfo {
+	return provider.Stopped
+}
+
+// runState implements a running Hub's behavior within THE STATE PATTERN.
+type runState struct {
+	hub *Hub
+}
+
+func newRunState(h *Hub) *runState {
+	return &runState{hub: h}
+}
+
+func (r *runState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	return nil, provider.AlreadyRunErr{}
+}
+
+func (r *runState) stateInfo() provider.WatcherStateInfo {
+	return provider.Run
+}
+
+// Hub implements the provider.Watcher interface running an embedded SMTP server,
+// which accepts mails addressed to the mailboxes specified by the connection string.
+// Messages are sent as soon as mails are received, so there is no polling
+// and CheckPeriod is not used.
+type Hub struct {
+	config provider.WatcherConfig
+
+	//state implements THE STATE PATTERN
+	state hubState
+
+	//mu guards the state and the listener, since the listening go-routine stops
+	//the Hub concurrently with StateInfo, Addr and StartWatch calls.
+	mu sync.Mutex
+
+	//ln specifies the listener of the running Hub, it is nil if the Hub is stopped
+	ln net.Listener
+
+	//lifecycle publishes lifecycle events of the Hub
+	lifecycle provider.Lifecycle
+
+	//stats keeps statistics of the Hub
+	stats provider.StatsRecorder
+
+	//addr specifies the listening address
+	addr string
+
+	//rcpts specifies accepted mailboxes
+	rcpts map[string]bool
+
+	//parsers specifies parsers of mail bodies in the order of applying
+	parsers []Parser
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		T: provider.Smtp,
+		Factory: func(conf provider.WatcherConfig) (provider.Watcher, error) {
+			h, err := NewHub(conf)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		Schema: provider.ConfigSchema{
+			Description: "Receives event reports (SEISHUB texts, QuakeML documents) by e-mail.",
+			ConnStr:     "\"smtp://<host>:<port>[?rcpt=<mailbox>...]\", the listening address and accepted mailboxes.",
+			DefConnStr:  DefConnStr,
+		},
+	})
+}
+
+// NewHub returns a pointer to a new smtpd.Hub in the stopped state
+// configured by "conf" values and an error. Mail bodies are parsed by DefParsers.
+//
+// If the returned error is not nil, the returned pointer is nil.
+func NewHub(conf provider.WatcherConfig) (*Hub, error) {
+	if conf.Timeout < 1 {
+		return nil, fmt.Errorf("NewHub: timeout cannot be less than 1 (second)")
+	}
+
+	if conf.ConnStr == "" {
+		conf.ConnStr = DefConnStr
+	}
+
+	addr, rcpts, err := ParseConnStr(conf.ConnStr)
+	if err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	if err := provider.NoOptions(conf.Options); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	h := &Hub{config: conf, addr: addr, rcpts: make(map[string]bool, len(rcpts)), parsers: DefParsers}
+	for _, r := range rcpts {
+		h.rcpts[r] = true
+	}
+
+	h.setState(newStoppedState(h))
+
+	return h, nil
+}
+
+// GetConfig returns configuration of the Hub.
+func (h *Hub) GetConfig() provider.WatcherConfig {
+	return h.config
+}
+
+// setState sets the state of the Hub. The caller must hold h.mu,
+// except while the Hub is being created.
+func (h *Hub) setState(s hubState) {
+	prev := h.state
+	h.state = s
+	h.stats.Transited()
+
+	if prev != nil {
+		h.lifecycle.Transit(h.config.Id, prev.stateInfo(), s.stateInfo(), nil)
+	}
+}
+
+// Subscribe implements the provider.Observable interface.
+func (h *Hub) Subscribe(buf int) (<-chan provider.LifecycleEvent, func()) {
+	return h.lifecycle.Subscribe(buf)
+}
+
+// StateInfo reports a current state of the Hub.
+func (h *Hub) StateInfo() provider.WatcherStateInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.state.stateInfo()
+}
+
+// Stats returns the state and statistics of the Hub. Every received mail is counted
+// as a poll, a failed Accept as a fetch error and accepting after it as a retry.
+// Failed sessions and mails which cannot be parsed are recorded as the last error.
+func (h *Hub) Stats() provider.WatcherStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.Stats(h.state.stateInfo())
+}
+
+// Addr returns the address the Hub listens on, or nil if the Hub is stopped.
+// It is useful when the port of the connection string is 0.
+func (h *Hub) Addr() net.Addr {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.ln == nil {
+		return nil
+	}
+	return h.ln.Addr()
+}
+
+// StartWatch starts listening for SMTP connections and receiving mails.
+//
+// The method returns a channel for fetching messages. If the returned error is not nil,
+// e.g. the address is already in use, the returned channel is nil.
+//
+// Messages with FocusTime before "from" are skipped.
+func (h *Hub) StartWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	o, err := h.state.startWatch(ctx, from)
+	return o, err
+}
+
+// watch accepts connections on "ln" and serves SMTP sessions until
+// watching is canceled. Messages of received mails are sent into the "o" channel.
+func (h *Hub) watch(ctx context.Context, ln net.Listener, o chan<- provider.Message, from time.Time) {
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		h.mu.Lock()
+		h.ln = nil
+		h.setState(newStoppedState(h))
+		h.mu.Unlock()
+		close(o)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	//delay grows while Accept fails, e.g. when the process is out of file descriptors
+	var delay time.Duration
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("watch: Canceled")
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				log.Printf("watch: %v", err)
+				return
+			}
+
+			delay = acceptDelay(delay)
+			log.Printf("watch: %v; retrying in %v", err, delay)
+			h.stats.FetchFailed(err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+			h.stats.Retried()
+			continue
+		}
+		delay = 0
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.serve(ctx, conn, o, from)
+		}()
+	}
+}
+
+// acceptDelay returns the delay before retrying a failed Accept following the delay "d":
+// it starts with minAcceptDelay and doubles up to maxAcceptDelay.
+func acceptDelay(d time.Duration) time.Duration {
+	if d == 0 {
+		return minAcceptDelay
+	}
+	if d *= 2; d > maxAcceptDelay {
+		d = maxAcceptDelay
+	}
+	return d
+}
+
+// serve serves an SMTP session on "conn". The connection is closed on cancellation.
+func (h *Hub) serve(ctx context.Context, conn net.Conn, o chan<- provider.Message, from time.Time) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	deliver := func(data []byte) error {
+		return h.deliver(ctx, data, o, from)
+	}
+
+	s := newSession(conn, time.Duration(h.config.Timeout)*time.Second, h.rcpts, deliver)
+	if err := s.serve(); err != nil && ctx.Err() == nil {
+		log.Printf("serve: remote %s: %v", conn.RemoteAddr(), err)
+		h.stats.SetErr(fmt.Errorf("serve: %w", err))
+	}
+}
+
+// deliver parses a received mail and sends its messages into the "o" channel.
+// Mails which cannot be parsed are skipped. The method returns an error
+// if watching is canceled before the messages are sent.
+func (h *Hub) deliver(ctx context.Context, data []byte, o chan<- provider.Message, from time.Time) error {
+	h.stats.Polled()
+
+	ml, err := seishub.ReadMail(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("deliver: %v", err)
+		h.stats.SetErr(fmt.Errorf("deliver: %w", err))
+		return nil
+	}
+
+	msgs, err := ParseMail(ml, h.parsers)
+	if err != nil {
+		log.Printf("deliver: %v", err)
+		h.stats.SetErr(fmt.Errorf("deliver: %w", err))
+		return nil
+	}
+
+	for _, m := range msgs {
+		if m.FocusTime.Before(from) {
+			continue
+		}
+		m.SourceId = h.config.Id
+
+		select {
+		case o <- m:
+			h.stats.Emitted()
+		case <-ctx.Done():
+			return fmt.Errorf("deliver: %w", ctx.Err())
+		}
+	}
+
+	return nil
+}
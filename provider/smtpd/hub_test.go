@@ -0,0 +1,153 @@
+package smtpd
+
+import (
+	"context"
+	"encoding/base64"
+	"mime"
+	"net/smtp"
+	"seismo/provider"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRcpt = "reports@seismo.local"
+
+func newTestHub(t *testing.T) *Hub {
+	conf := provider.WatcherConfig{Id: "mail", T: provider.Smtp, Timeout: 5,
+		ConnStr: "smtp://127.0.0.1:0?rcpt=" + testRcpt}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("newTestHub: %v", err)
+	}
+
+	return h
+}
+
+// newTestMail returns a mail with an encoded subject and a base64 encoded body.
+func newTestMail(id string, subject string, body string) []byte {
+	var sb strings.Builder
+	sb.WriteString("From: asb@seishub.ru\r\n")
+	sb.WriteString("To: " + testRcpt + "\r\n")
+	sb.WriteString("Message-ID: <" + id + ">\r\n")
+	sb.WriteString("Subject: " + mime.BEncoding.Encode("utf-8", subject) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	enc := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(enc) > 76 {
+		sb.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	sb.WriteString(enc + "\r\n")
+
+	return []byte(sb.String())
+}
+
+func Test_StartWatch(t *testing.T) {
+	h := newTestHub(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch: %v", err)
+	}
+	addr := h.Addr().String()
+
+	//a mailbox which is not accepted
+	err = smtp.SendMail(addr, nil, "asb@seishub.ru", []string{"other@seismo.local"}, newTestMail("0@test", "Test", "Test"))
+	if err == nil {
+		t.Errorf("Test_StartWatch: an error is expected for a not accepted mailbox")
+	}
+
+	//a mail which is not a report is accepted and skipped
+	err = smtp.SendMail(addr, nil, "asb@seishub.ru", []string{testRcpt}, newTestMail("1@test", "Test", "Hello!"))
+	if err != nil {
+		t.Fatalf("Test_StartWatch: %v", err)
+	}
+
+	report := readFile(t, "testdata/asb2022cfjhkl.txt")
+	subject := "[Seismic-Report] ОПЕРАТИВНОЕ СООБЩЕНИЕ О СЕЙСМИЧЕСКОМ СОБЫТИИ (asb2022cfjhkl)"
+	sent := make(chan error, 1)
+	go func() {
+		sent <- smtp.SendMail(addr, nil, "asb@seishub.ru", []string{testRcpt}, newTestMail("2@test", subject, report))
+	}()
+
+	select {
+	case m := <-ch:
+		if m.EventId != "asb2022cfjhkl" || m.SourceId != "mail" || m.Link != "mid:2@test" {
+			t.Errorf("Test_StartWatch: unexpected message: %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Test_StartWatch: timeout")
+	}
+
+	//the mail is accepted after its messages are fetched
+	if err := <-sent; err != nil {
+		t.Errorf("Test_StartWatch: %v", err)
+	}
+
+	if _, err := h.StartWatch(ctx, time.Now()); err == nil {
+		t.Errorf("Test_StartWatch: an error is expected for a running hub")
+	}
+
+	cancel()
+	for range ch {
+	}
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Errorf("Test_StartWatch: want state: %s, res: %s", provider.Stopped, s)
+	}
+
+	if h.Addr() != nil {
+		t.Errorf("Test_StartWatch: a stopped hub cannot have an address")
+	}
+
+	//the mail which is not a report is the last error
+	if st := h.Stats(); st.Emitted != 1 || st.LastPoll.IsZero() || st.LastErr == nil {
+		t.Errorf("Test_StartWatch: unexpected stats: %+v", st)
+	}
+}
+
+func Test_StartWatch_From(t *testing.T) {
+	h := newTestHub(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := h.StartWatch(ctx, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_From: %v", err)
+	}
+
+	//the event of the report is before "from"
+	report := readFile(t, "testdata/asb2022cfjhkl.txt")
+	err = smtp.SendMail(h.Addr().String(), nil, "asb@seishub.ru", []string{testRcpt}, newTestMail("1@test", "Test", report))
+	if err != nil {
+		t.Fatalf("Test_StartWatch_From: %v", err)
+	}
+
+	select {
+	case m := <-ch:
+		t.Errorf("Test_StartWatch_From: unexpected message: %v", m)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func Test_acceptDelay(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want time.Duration
+	}{
+		{0, minAcceptDelay},
+		{minAcceptDelay, 2 * minAcceptDelay},
+		{maxAcceptDelay * 3 / 4, maxAcceptDelay},
+		{maxAcceptDelay, maxAcceptDelay},
+	}
+
+	for _, test := range tests {
+		if res := acceptDelay(test.d); res != test.want {
+			t.Errorf("Test_acceptDelay: d: %v want: %v res: %v", test.d, test.want, res)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package smtpd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const (
+	//maxMailSize defines the max size of a received mail in bytes
+	maxMailSize = 1024 * 1024
+
+	//domain defines the domain name the server introduces itself
+	domain = "seismo"
+)
+
+// session implements the server side of an SMTP session (RFC 5321)
+// sufficient to receive mails from mail transfer agents and clients.
+// STARTTLS and authentication are not supported.
+type session struct {
+	conn    net.Conn
+	tp      *textproto.Conn
+	timeout time.Duration
+
+	//rcpts specifies accepted mailboxes. If it is empty, any mailbox is accepted.
+	rcpts map[string]bool
+
+	//deliver is called for every received mail. If it returns an error,
+	//the mail is rejected with a temporary failure.
+	deliver func(data []byte) error
+
+	//transaction state
+	from  bool
+	to    int
+	greet bool
+}
+
+func newSession(conn net.Conn, timeout time.Duration, rcpts map[string]bool, deliver func(data []byte) error) *session {
+	return &session{conn: conn, tp: textproto.NewConn(conn), timeout: timeout, rcpts: rcpts, deliver: deliver}
+}
+
+// serve processes commands of the session until the client quits
+// or the connection is broken.
+func (s *session) serve() error {
+	defer s.tp.Close()
+
+	if err := s.reply(220, domain+" ESMTP ready"); err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	for {
+		s.conn.SetDeadline(time.Now().Add(s.timeout))
+		line, err := s.tp.ReadLine()
+		if err != nil {
+			return fmt.Errorf("serve: %w", err)
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		quit, err := s.handle(strings.ToUpper(verb), strings.TrimSpace(arg))
+		if err != nil {
+			return fmt.Errorf("serve: %w", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// handle processes a command and reports whether the session is over.
+func (s *session) handle(verb string, arg string) (quit bool, err error) {
+	switch verb {
+	case "HELO":
+		s.greet = true
+		s.reset()
+		return false, s.reply(250, domain)
+	case "EHLO":
+		s.greet = true
+		s.reset()
+		return false, s.reply(250, domain, fmt.Sprintf("SIZE %d", maxMailSize), "8BITMIME")
+	case "MAIL":
+		if !s.greet {
+			return false, s.reply(503, "Send HELO/EHLO first")
+		}
+		if _, ok := path(arg, "FROM:"); !ok {
+			return false, s.reply(501, "Syntax: MAIL FROM:<address>")
+		}
+		s.reset()
+		s.from = true
+		return false, s.reply(250, "OK")
+	case "RCPT":
+		if !s.from {
+			return false, s.reply(503, "Need MAIL command")
+		}
+		addr, ok := path(arg, "TO:")
+		if !ok || addr == "" {
+			return false, s.reply(501, "Syntax: RCPT TO:<address>")
+		}
+		if len(s.rcpts) > 0 && !s.rcpts[strings.ToLower(addr)] {
+			return false, s.reply(550, "No such mailbox "+addr)
+		}
+		s.to++
+		return false, s.reply(250, "OK")
+	case "DATA":
+		if s.to == 0 {
+			return false, s.reply(503, "Need RCPT command")
+		}
+		return false, s.data()
+	case "RSET":
+		s.reset()
+		return false, s.reply(250, "OK")
+	case "NOOP":
+		return false, s.reply(250, "OK")
+	case "VRFY":
+		return false, s.reply(252, "Cannot verify user")
+	case "QUIT":
+		return true, s.reply(221, domain+" closing connection")
+	default:
+		return false, s.reply(502, "Command not implemented")
+	}
+}
+
+// data receives the content of a mail and delivers it.
+func (s *session) data() error {
+	if err := s.reply(354, "End data with <CR><LF>.<CR><LF>"); err != nil {
+		return err
+	}
+
+	dr := s.tp.DotReader()
+	data, err := io.ReadAll(io.LimitReader(dr, maxMailSize+1))
+	if err != nil {
+		return err
+	}
+	defer s.reset()
+
+	if len(data) > maxMailSize {
+		//the rest of the data is skipped
+		if _, err := io.Copy(io.Discard, dr); err != nil {
+			return err
+		}
+		return s.reply(552, "Message size exceeds fixed limit")
+	}
+
+	if err := s.deliver(data); err != nil {
+		return s.reply(451, "Requested action aborted: "+err.Error())
+	}
+
+	return s.reply(250, "OK")
+}
+
+func (s *session) reset() {
+	s.from = false
+	s.to = 0
+}
+
+// reply sends a reply with the code and the lines of a text.
+func (s *session) reply(code int, lines ...string) error {
+	for i, l := range lines {
+		sep := " "
+		if i < len(lines)-1 {
+			sep = "-"
+		}
+		if err := s.tp.PrintfLine("%d%s%s", code, sep, l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// path returns an address of a MAIL or RCPT command argument like
+// "FROM:<user@domain> SIZE=100" and whether the argument is correct.
+// The null reverse path "<>" is correct.
+func path(arg string, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	p := strings.TrimSpace(arg[len(prefix):])
+	if i := strings.Index(p, ">"); strings.HasPrefix(p, "<") && i > 0 {
+		p = p[1:i]
+	} else {
+		return "", false
+	}
+
+	if p == "" {
+		return "", true
+	}
+
+	if _, err := mail.ParseAddress(p); err != nil {
+		return "", false
+	}
+
+	return p, true
+}
@@ -0,0 +1,114 @@
+// Package seismo/provider/smtpd provides tools for receiving seismic event reports
+// by e-mail through an embedded SMTP server, e.g. reports of the SEISHUB mailing list
+// or other agencies distributing reports by e-mail.
+//
+// The connection string of a watcher specifies the listening address and
+// the accepted mailboxes (recipients) in the following format:
+//
+//	smtp://<host>:<port>?rcpt=<mailbox>[&rcpt=<mailbox>...]
+//
+// E.g. "smtp://:2525?rcpt=reports@seismo.local". If no mailbox is specified,
+// mails for any recipient are accepted.
+//
+// A received mail is decoded (see seishub.ReadMail) and its body is passed to
+// parsers one by one until a parser succeeds. Mails which cannot be parsed
+// are accepted and skipped.
+package smtpd
+
+import (
+	"fmt"
+	"net/url"
+	"seismo/provider"
+	"seismo/provider/quakeml"
+	"seismo/provider/seishub"
+	"strings"
+)
+
+const (
+	//DefConnStr defines the default listening address
+	DefConnStr = "smtp://:2525"
+
+	//scheme defines the scheme of connection strings
+	scheme = "smtp"
+)
+
+// Parser returns seismic event messages parsed from a text of a mail body and an error.
+// If the returned error is not nil, the returned slice is nil.
+type Parser func(body string) ([]provider.Message, error)
+
+// DefParsers defines the parsers used by default in the order of applying.
+var DefParsers = []Parser{ParseSeishub, ParseQuakeML}
+
+// ParseSeishub parses a SEISHUB report (see seishub.ParseMsg).
+func ParseSeishub(body string) ([]provider.Message, error) {
+	m, err := seishub.ParseMsg(body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseSeishub: %w", err)
+	}
+
+	return []provider.Message{*m}, nil
+}
+
+// ParseQuakeML parses a QuakeML document (see quakeml.Unmarshal).
+func ParseQuakeML(body string) ([]provider.Message, error) {
+	msgs, err := quakeml.Unmarshal([]byte(body))
+	if err != nil {
+		return nil, fmt.Errorf("ParseQuakeML: %w", err)
+	}
+
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("ParseQuakeML: no events")
+	}
+
+	return msgs, nil
+}
+
+// ParseMail returns seismic event messages parsed from the body of the mail "m"
+// by the first succeeded parser of "parsers" and an error.
+// If the returned error is not nil, the returned slice is nil.
+//
+// The link of every message refers to the mail by its message id ("mid:" URL).
+// Messages without an update time get the date of the mail.
+func ParseMail(m *seishub.Mail, parsers []Parser) ([]provider.Message, error) {
+	errs := make([]string, 0, len(parsers))
+	for _, p := range parsers {
+		msgs, err := p(m.Body)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		for i := range msgs {
+			if m.MessageId != "" {
+				msgs[i].Link = "mid:" + url.PathEscape(m.MessageId)
+			}
+			if msgs[i].UpdateTime.IsZero() {
+				msgs[i].UpdateTime = m.Date
+			}
+		}
+
+		return msgs, nil
+	}
+
+	return nil, fmt.Errorf("ParseMail: message id %q subject %q: no suitable parser: %s",
+		m.MessageId, m.Subject, strings.Join(errs, "; "))
+}
+
+// ParseConnStr returns the listening address and accepted mailboxes
+// specified by the connection string "s" and an error.
+func ParseConnStr(s string) (addr string, rcpts []string, err error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("ParseConnStr: %w", err)
+	}
+
+	if u.Scheme != scheme {
+		return "", nil, fmt.Errorf("ParseConnStr: %q: unexpected scheme %q", s, u.Scheme)
+	}
+
+	for _, r := range u.Query()["rcpt"] {
+		rcpts = append(rcpts, strings.ToLower(r))
+	}
+
+	return u.Host, rcpts, nil
+}
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+// WatcherStats contains health information and statistics of a watcher.
+// Counters are accumulated during the lifetime of the watcher, i.e. they are
+// not reset by restarting.
+type WatcherStats struct {
+	// State specifies the current state of the watcher.
+	State WatcherStateInfo
+
+	// StateTime specifies the time of the last state transition.
+	StateTime time.Time
+
+	// LastErr specifies the last error of the watcher. It is nil if there were no errors.
+	LastErr error
+
+	// LastErrTime specifies the time of the last error.
+	LastErrTime time.Time
+
+	// Emitted specifies the number of messages sent into message channels.
+	Emitted uint64
+
+	// FetchErrors specifies the number of failed requests to the message source.
+	FetchErrors uint64
+
+	// Retries specifies the number of requests repeated after failures.
+	Retries uint64
+
+	// LastPoll specifies the time of the last successful request to the message source.
+	LastPoll time.Time
+
+	// LastMsg specifies the time the last message was sent.
+	LastMsg time.Time
+
+	// Backlog specifies the number of messages known to be available, but not got yet,
+	// e.g. while the watcher is catching up. It is 0 if the number is unknown.
+	Backlog uint64
+}
+
+// StatsRecorder keeps statistics of a watcher (except the state) safely
+// for concurrent use by watching go-routines and Stats calls.
+// The zero value is ready to use.
+type StatsRecorder struct {
+	mu    sync.Mutex
+	stats WatcherStats
+}
+
+// Transited records the time of a state transition.
+func (r *StatsRecorder) Transited() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.StateTime = time.Now().UTC()
+}
+
+// Emitted records sending a message.
+func (r *StatsRecorder) Emitted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Emitted++
+	r.stats.LastMsg = time.Now().UTC()
+}
+
+// Polled records a successful request to the message source.
+func (r *StatsRecorder) Polled() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.LastPoll = time.Now().UTC()
+}
+
+// FetchFailed records a failed request to the message source and its error.
+func (r *StatsRecorder) FetchFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.FetchErrors++
+	r.setErr(err)
+}
+
+// Retried records repeating a request after a failure.
+func (r *StatsRecorder) Retried() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Retries++
+}
+
+// SetBacklog records the number of messages available, but not got yet.
+func (r *StatsRecorder) SetBacklog(n uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Backlog = n
+}
+
+// SetErr records an error of the watcher, which is not an error of a request.
+func (r *StatsRecorder) SetErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setErr(err)
+}
+
+func (r *StatsRecorder) setErr(err error) {
+	r.stats.LastErr = err
+	r.stats.LastErrTime = time.Now().UTC()
+}
+
+// Stats returns the recorded statistics with the state "s".
+func (r *StatsRecorder) Stats(s WatcherStateInfo) WatcherStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.stats
+	st.State = s
+	return st
+}
+
+// LastErr returns the last recorded error.
+func (r *StatsRecorder) LastErr() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stats.LastErr
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func Test_WatcherStateInfo_Active(t *testing.T) {
+	for s, want := range map[WatcherStateInfo]bool{
+		Stopped: false, Failed: false, Run: true, Starting: true, CatchingUp: true, Backoff: true,
+	} {
+		if s.Active() != want {
+			t.Errorf("Test_WatcherStateInfo_Active: %s: want: %v", s, want)
+		}
+	}
+}
+
+func Test_StatsRecorder(t *testing.T) {
+	var r StatsRecorder
+	errFetch := errors.New("fetch")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Emitted()
+			r.FetchFailed(errFetch)
+			r.Retried()
+			r.Polled()
+		}()
+	}
+	wg.Wait()
+
+	st := r.Stats(Run)
+	if st.State != Run || st.Emitted != 10 || st.FetchErrors != 10 || st.Retries != 10 {
+		t.Errorf("Test_StatsRecorder: unexpected counters: %+v", st)
+	}
+	if st.LastErr != errFetch || st.LastErrTime.IsZero() || st.LastPoll.IsZero() || st.LastMsg.IsZero() {
+		t.Errorf("Test_StatsRecorder: unexpected times or error: %+v", st)
+	}
+}
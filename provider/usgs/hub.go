@@ -0,0 +1,386 @@
+package usgs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"seismo/provider"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hubState is implemented to provide a specific behavior within THE STATE PATTERN.
+type hubState interface {
+	startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error)
+	stateInfo() provider.WatcherStateInfo
+}
+
+// stoppedState implements a stopped Hub's behavior within THE STATE PATTERN.
+type stoppedState struct {
+	hub *Hub
+}
+
+func newStoppedState(h *Hub) *stoppedState {
+	return &stoppedState{hub: h}
+}
+
+// startWatch implements the behaivor of Hub.StartWatch in the "stopped" state,
+// i.e. starts polling the feed and returns a channel for fetching messages.
+// If the returned error is not nil, the returned channel is nil.
+func (s *stoppedState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("cannot start with canceled context")
+	}
+
+	from = from.UTC()
+	if from.After(time.Now().UTC()) {
+		return nil, fmt.Errorf(`watching cannot be started in the future (the "from" arg cannot be after the start time)`)
+	}
+
+	h := s.hub
+	h.setState(newRunState(h))
+	o := make(chan provider.Message)
+	go h.watch(ctx, o, from, time.Duration(h.config.CheckPeriod)*time.Second)
+
+	return o, nil
+}
+
+func (s *stoppedState) stateInfo() provider.WatcherStateInfo {
+	return provider.Stopped
+}
+
+// runState implements a running Hub's behavior within THE STATE PATTERN.
+type runState struct {
+	hub *Hub
+}
+
+func newRunState(h *Hub) *runState {
+	return &runState{hub: h}
+}
+
+func (r *runState) startWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	return nil, provider.AlreadyRunErr{}
+}
+
+func (r *runState) stateInfo() provider.WatcherStateInfo {
+	return provider.Run
+}
+
+// Hub implements the provider.Watcher interface for a USGS-style GeoJSON feed
+// addressed by the connection string of its configuration.
+//
+// Hub embeds an http.Client.
+type Hub struct {
+	config provider.WatcherConfig
+	http.Client
+
+	//options are decoded from the configuration options block
+	options Options
+
+	//state implements THE STATE PATTERN
+	state hubState
+
+	//mu guards the state, since the polling go-routine stops the Hub
+	//concurrently with StateInfo and StartWatch calls.
+	mu sync.Mutex
+
+	//lifecycle publishes lifecycle events of the Hub
+	lifecycle provider.Lifecycle
+
+	//stats keeps statistics of the Hub
+	stats provider.StatsRecorder
+
+	//updated maps identifiers of sent events to their "updated" timestamps.
+	//It is kept between watching sessions, so a restarted hub does not send
+	//unchanged events again.
+	updated map[string]int64
+
+	//generated specifies the "generated" timestamp of the last processed feed.
+	generated int64
+}
+
+// Options contains options of a usgs watcher (see provider.WatcherConfig.Options).
+// Events of a feed are filtered by the filter options after fetching.
+type Options struct {
+	provider.HTTPOptions
+	provider.FilterOptions
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		T: provider.Usgs,
+		Factory: func(conf provider.WatcherConfig) (provider.Watcher, error) {
+			h, err := NewHub(conf)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		Schema: provider.ConfigSchema{
+			Description: "Polls a USGS-style GeoJSON summary or detail feed.",
+			ConnStr:     "The address of a GeoJSON feed.",
+			DefConnStr:  DefConnStr,
+			Options:     Options{},
+		},
+		Rules: provider.RuleSet{provider.RequiredLink},
+	})
+}
+
+// NewHub returns a pointer to a new usgs.Hub in the stopped state
+// configured by "conf" values and an error.
+//
+// If the returned error is not nil, the returned pointer is nil.
+func NewHub(conf provider.WatcherConfig) (*Hub, error) {
+	if conf.CheckPeriod < 1 {
+		return nil, fmt.Errorf("NewHub: checkperiod cannot be less than 1 (second)")
+	}
+
+	if conf.Timeout < 1 {
+		return nil, fmt.Errorf("NewHub: timeout cannot be less than 1 (second)")
+	}
+
+	if conf.ConnStr == "" {
+		conf.ConnStr = DefConnStr
+	}
+
+	var opts Options
+	if err := provider.DecodeOptions(conf.Options, &opts); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("NewHub: %w", err)
+	}
+
+	h := &Hub{config: conf, options: opts,
+		Client:  http.Client{Timeout: time.Duration(conf.Timeout) * time.Second, Transport: opts.Transport(nil)},
+		updated: make(map[string]int64)}
+
+	h.setState(newStoppedState(h))
+
+	return h, nil
+}
+
+// GetConfig returns configuration of the Hub.
+func (h *Hub) GetConfig() provider.WatcherConfig {
+	return h.config
+}
+
+// setState sets the state of the Hub. The caller must hold h.mu,
+// except while the Hub is being created.
+func (h *Hub) setState(s hubState) {
+	prev := h.state
+	h.state = s
+	h.stats.Transited()
+
+	if prev != nil {
+		h.lifecycle.Transit(h.config.Id, prev.stateInfo(), s.stateInfo(), nil)
+	}
+}
+
+// Subscribe implements the provider.Observable interface.
+func (h *Hub) Subscribe(buf int) (<-chan provider.LifecycleEvent, func()) {
+	return h.lifecycle.Subscribe(buf)
+}
+
+// StateInfo reports a current state of the Hub.
+func (h *Hub) StateInfo() provider.WatcherStateInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.state.stateInfo()
+}
+
+// Stats returns the state and statistics of the Hub. Failed requests to the USGS feed
+// are counted as fetch errors, a request following a failed one is counted as a retry.
+func (h *Hub) Stats() provider.WatcherStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.Stats(h.state.stateInfo())
+}
+
+// StartWatch starts polling the feed every CheckPeriod.
+//
+// The method returns a channel for fetching messages. If the returned error is not nil, the returned
+// channel is nil.
+//
+// An event is sent if its origin time is after (or equal to) "from" and it has not been sent
+// yet, or if its "updated" timestamp has changed since it was sent (i.e. the event has been revised).
+// Sent events are remembered between watching sessions of the hub, so watching restarted
+// (e.g. by collector.RestartWatchers) resumes without duplicates.
+// Watching can't be started in the future. Returns an error in such case.
+func (h *Hub) StartWatch(ctx context.Context, from time.Time) (<-chan provider.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	o, err := h.state.startWatch(ctx, from)
+	return o, err
+}
+
+// watch polls the feed with a frequency of "checkPeriod" and sends
+// new and revised messages into the "o" channel.
+func (h *Hub) watch(ctx context.Context, o chan<- provider.Message, from time.Time, checkPeriod time.Duration) {
+	defer func() {
+		h.mu.Lock()
+		h.setState(newStoppedState(h))
+		h.mu.Unlock()
+		close(o)
+	}()
+
+	wt := time.NewTicker(checkPeriod)
+	defer wt.Stop()
+
+	failed := false
+	for {
+		if failed {
+			h.stats.Retried()
+		}
+
+		p, err := h.poll(ctx, from)
+		if err != nil {
+			log.Printf("watch: %v", err)
+			h.stats.FetchFailed(err)
+			h.lifecycle.Failed(h.config.Id, provider.Run, err)
+		} else {
+			h.stats.Polled()
+		}
+		failed = err != nil
+
+		for _, m := range p.msgs {
+			select {
+			case o <- *m:
+				h.updated[m.EventId] = p.updated[m.EventId]
+				h.stats.Emitted()
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err == nil {
+			h.generated = p.generated
+		}
+
+		select {
+		case <-wt.C:
+		case <-ctx.Done():
+			log.Println("watch: Canceled")
+			return
+		}
+	}
+}
+
+// Backfill implements the provider.Backfiller interface. It fetches the feed
+// and sends the messages of its events with the FocusTime in the [from, to) range
+// in ascending order of the FocusTime. Events are available for the period
+// of the feed only (e.g. the last month for "all_month.geojson").
+//
+// Backfilling can be performed while the Hub is watching,
+// it does not affect the events remembered by watching.
+func (h *Hub) Backfill(ctx context.Context, from time.Time, to time.Time) (<-chan provider.Message, error) {
+	if err := provider.CheckRange(from, to); err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	o := make(chan provider.Message)
+	go func() {
+		defer close(o)
+
+		f, err := GetFeed(ctx, h.config.ConnStr, &h.Client)
+		if err != nil {
+			log.Printf("Backfill: %v", err)
+			return
+		}
+
+		events := f.Events()
+		msgs := make([]provider.Message, 0, len(events))
+		for _, e := range events {
+			m, err := e.Message(h.config.ConnStr)
+			if err != nil {
+				log.Printf("Backfill: %v", err)
+				continue
+			}
+
+			if !provider.InRange(m.FocusTime, from, to) || !h.options.Match(*m) {
+				continue
+			}
+
+			m.SourceId = h.config.Id
+			msgs = append(msgs, *m)
+		}
+		sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].FocusTime.Before(msgs[j].FocusTime) })
+
+		provider.SendAll(ctx, o, msgs)
+	}()
+
+	return o, nil
+}
+
+// polled keeps new and revised messages of a feed along with
+// the "updated" timestamps of their events.
+type polled struct {
+	msgs      []*provider.Message
+	updated   map[string]int64
+	generated int64
+}
+
+// poll fetches the feed and returns new and revised messages and an error.
+// If the feed has not been regenerated since the previous poll, no messages are returned.
+//
+// Events which are absent in a summary feed (i.e. are out of its period) are forgotten.
+func (h *Hub) poll(ctx context.Context, from time.Time) (polled, error) {
+	f, err := GetFeed(ctx, h.config.ConnStr, &h.Client)
+	if err != nil {
+		return polled{}, fmt.Errorf("poll: %w", err)
+	}
+
+	res := polled{generated: f.Metadata.Generated}
+	if f.Metadata.Generated != 0 && f.Metadata.Generated == h.generated {
+		return res, nil
+	}
+
+	events := f.Events()
+	res.updated = make(map[string]int64, len(events))
+	present := make(map[string]bool, len(events))
+	for _, e := range events {
+		present[e.Id] = true
+
+		prev, sent := h.updated[e.Id]
+		if sent && prev >= e.Properties.Updated {
+			continue
+		}
+
+		m, err := e.Message(h.config.ConnStr)
+		if err != nil {
+			log.Printf("poll: %v", err)
+			continue
+		}
+
+		//revisions of sent events are sent even if they do not match the options
+		//any more, so consumers learn about the changes
+		if !sent && (m.FocusTime.Before(from) || !h.options.Match(*m)) {
+			continue
+		}
+
+		if m.Action == "" {
+			m.Action = provider.Create
+			if sent {
+				m.Action = provider.Update
+			}
+		}
+
+		m.SourceId = h.config.Id
+		res.msgs = append(res.msgs, m)
+		res.updated[m.EventId] = e.Properties.Updated
+	}
+
+	if f.Type == "FeatureCollection" {
+		for id := range h.updated {
+			if !present[id] {
+				delete(h.updated, id)
+			}
+		}
+	}
+
+	return res, nil
+}
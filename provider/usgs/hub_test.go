@@ -0,0 +1,94 @@
+package usgs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"seismo/provider"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// receive reads "n" messages from "ch" and returns their event identifiers
+// followed by their actions, e.g. "us7000jk3l create".
+func receive(t *testing.T, ctx context.Context, ch <-chan provider.Message, n int) []string {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		select {
+		case m := <-ch:
+			ids = append(ids, m.EventId+" "+string(m.Action))
+		case <-ctx.Done():
+			t.Fatalf("receive: timeout waiting for message %d", i)
+		}
+	}
+	return ids
+}
+
+func Test_StartWatch_Restart(t *testing.T) {
+	var feed atomic.Value
+	feed.Store("testdata/all_hour.geojson")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := os.ReadFile(feed.Load().(string))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	conf := provider.WatcherConfig{Id: "usgs", T: provider.Usgs, ConnStr: srv.URL, Timeout: 10, CheckPeriod: 1}
+	h, err := NewHub(conf)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Restart: NewHub: %v", err)
+	}
+	from := time.Date(2023, 3, 1, 6, 0, 0, 0, time.UTC)
+
+	//the first session: events after "from"
+	ctx1, cancel1 := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel1()
+	ch, err := h.StartWatch(ctx1, from)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Restart: %v", err)
+	}
+
+	res := receive(t, ctx1, ch, 2)
+	if res[0] != "uu60521187 create" || res[1] != "us7000jk3l create" {
+		t.Errorf("Test_StartWatch_Restart: first session: unexpected events %v", res)
+	}
+
+	cancel1()
+	for range ch {
+	}
+	if s := h.StateInfo(); s != provider.Stopped {
+		t.Fatalf("Test_StartWatch_Restart: want state: %s, res: %s", provider.Stopped, s)
+	}
+
+	//the restarted session: a revised and a new event only
+	feed.Store("testdata/all_hour_update.geojson")
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel2()
+	ch, err = h.StartWatch(ctx2, from)
+	if err != nil {
+		t.Fatalf("Test_StartWatch_Restart: %v", err)
+	}
+
+	res = receive(t, ctx2, ch, 2)
+	if res[0] != "nc73858141 create" || res[1] != "uu60521187 update" {
+		t.Errorf("Test_StartWatch_Restart: restarted session: unexpected events %v", res)
+	}
+
+	//no duplicates on the next polls of the same feed
+	select {
+	case m := <-ch:
+		t.Errorf("Test_StartWatch_Restart: unexpected message %v", m)
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	if st := h.Stats(); st.State != provider.Run || st.Emitted != 4 || st.LastPoll.IsZero() || st.FetchErrors != 0 {
+		t.Errorf("Test_StartWatch_Restart: unexpected stats: %+v", st)
+	}
+}
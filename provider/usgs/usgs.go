@@ -0,0 +1,245 @@
+// Package seismo/provider/usgs provides tools for getting information about
+// seismic activity from USGS-style GeoJSON feeds, i.e. summary feeds
+// (e.g. "https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_hour.geojson"),
+// containing a collection of events updated for a period, and detail feeds
+// (e.g. "https://earthquake.usgs.gov/earthquakes/feed/v1.0/detail/us7000jk3l.geojson"),
+// containing a single event.
+//
+// Every event (feature) of a feed has the "updated" timestamp, which changes
+// when the event is revised. The timestamps are used to recognize new
+// and revised events. Events with the "deleted" status are reported
+// as retractions.
+package usgs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"seismo/provider"
+	"seismo/provider/fetch"
+	"seismo/provider/quakeml"
+	"strings"
+	"time"
+)
+
+const (
+	//DefConnStr defines the default feed address (all events for the past hour)
+	DefConnStr = "https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_hour.geojson"
+
+	//deletedStatus is the review status of deleted events
+	deletedStatus = "deleted"
+)
+
+// defClient is a package-level default http client, that can be
+// used by package functions, having no specified client(s).
+// The Timeout value is 60 sec, failed requests are retried by the shared fetch layer.
+var defClient = http.Client{Timeout: 60 * time.Second, Transport: &fetch.Transport{Retries: fetch.DefRetries}}
+
+// Feed represents a summary feed (a feature collection) or
+// a detail feed (a single feature).
+type Feed struct {
+	// Type specifies the GeoJSON type: "FeatureCollection" or "Feature".
+	Type string `json:"type"`
+
+	// Metadata specifies the metadata of a summary feed.
+	Metadata Metadata `json:"metadata"`
+
+	// Features specifies the events of a summary feed.
+	Features []Feature `json:"features"`
+
+	// Feature specifies the event of a detail feed.
+	Feature
+}
+
+// Metadata represents the metadata of a summary feed.
+type Metadata struct {
+	// Generated specifies the time the feed was generated in milliseconds since the epoch.
+	Generated int64 `json:"generated"`
+
+	// Title specifies the title of the feed.
+	Title string `json:"title"`
+
+	// Count specifies the number of events in the feed.
+	Count int `json:"count"`
+}
+
+// Feature represents an event of a feed.
+type Feature struct {
+	Id         string     `json:"id"`
+	Properties Properties `json:"properties"`
+	Geometry   Geometry   `json:"geometry"`
+}
+
+// Properties represents the properties of an event.
+type Properties struct {
+	Mag     *float64 `json:"mag"`
+	MagType string   `json:"magType"`
+	Place   string   `json:"place"`
+
+	// Time specifies the origin time in milliseconds since the epoch.
+	Time int64 `json:"time"`
+
+	// Updated specifies the time the event was updated in milliseconds since the epoch.
+	Updated int64 `json:"updated"`
+
+	// Url specifies the address of the event page.
+	Url string `json:"url"`
+
+	// Detail specifies the address of the detail feed of the event.
+	// It is empty in detail feeds.
+	Detail string `json:"detail"`
+
+	// Status specifies the review status: "automatic", "reviewed" or "deleted".
+	Status string `json:"status"`
+
+	// Type specifies the type of the event, e.g. "earthquake", "quarry blast".
+	Type string `json:"type"`
+
+	// Net specifies the id of the network which is the preferred source
+	// of the event information.
+	Net string `json:"net"`
+
+	// Nst specifies the number of stations used to locate the event.
+	Nst *int `json:"nst"`
+
+	// Gap specifies the largest azimuthal gap between stations (degrees).
+	Gap *float64 `json:"gap"`
+}
+
+// Geometry represents the location of an event.
+type Geometry struct {
+	// Coordinates specifies longitude, latitude and depth (km).
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// Events returns the features of the feed. A detail feed has one feature.
+func (f *Feed) Events() []Feature {
+	if f.Type == "Feature" {
+		return []Feature{f.Feature}
+	}
+
+	return f.Features
+}
+
+// UpdatedTime returns the time the event was updated.
+func (f *Feature) UpdatedTime() time.Time {
+	return time.UnixMilli(f.Properties.Updated).UTC()
+}
+
+// Message returns a message built from the event and an error.
+// If the returned error is not nil, the returned message is nil.
+//
+// The "feedLink" parameter specifies the address of the feed containing the event.
+// It is used as the message link if the event has no detail address, i.e. for detail feeds.
+func (f *Feature) Message(feedLink string) (*provider.Message, error) {
+	if f.Id == "" {
+		return nil, fmt.Errorf("Message: empty event id")
+	}
+
+	if len(f.Geometry.Coordinates) < 2 {
+		return nil, fmt.Errorf("Message: event %q: no coordinates", f.Id)
+	}
+
+	m := provider.Message{
+		EventId:   f.Id,
+		FocusTime: time.UnixMilli(f.Properties.Time).UTC(),
+		Longitude: f.Geometry.Coordinates[0],
+		Latitude:  f.Geometry.Coordinates[1],
+		Type:      quakeml.EventType(f.Properties.Type),
+		Quality:   defineEventQuality(f.Properties.Status),
+		Link:      f.Properties.Detail,
+
+		MagType:      f.Properties.MagType,
+		StationCount: f.Properties.Nst,
+		AzimuthalGap: f.Properties.Gap,
+		Agency:       f.Properties.Net,
+	}
+
+	if f.Properties.Mag != nil {
+		m.Magnitude = *f.Properties.Mag
+	}
+
+	if len(f.Geometry.Coordinates) > 2 {
+		d := f.Geometry.Coordinates[2]
+		m.Depth = &d
+	}
+
+	if f.Properties.Place != "" {
+		m.Extra = map[string]string{"region": f.Properties.Place}
+	}
+
+	if f.Properties.Updated != 0 {
+		m.UpdateTime = f.UpdatedTime()
+	}
+
+	if strings.ToLower(f.Properties.Status) == deletedStatus {
+		m.Action = provider.Retract
+	}
+
+	if m.Link == "" {
+		m.Link = feedLink
+	}
+
+	return &m, nil
+}
+
+// GetFeed returns a feed addressed by "link" and an error.
+// If the returned error is not nil, the returned feed is nil.
+//
+// If the "cl" parameter is nil, the function uses the default package-level http client.
+func GetFeed(ctx context.Context, link string, cl *http.Client) (*Feed, error) {
+	if cl == nil {
+		cl = &defClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetFeed: link: %q error: %w", link, err)
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GetFeed: link: %q error: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GetFeed: link: %q unexpected status: %s", link, resp.Status)
+	}
+
+	f, err := ParseFeed(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("GetFeed: link: %q error: %w", link, err)
+	}
+
+	return f, nil
+}
+
+// ParseFeed returns a feed read from "r" and an error.
+// If the returned error is not nil, the returned feed is nil.
+func ParseFeed(r io.Reader) (*Feed, error) {
+	var f Feed
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("ParseFeed: %w", err)
+	}
+
+	if f.Type != "FeatureCollection" && f.Type != "Feature" {
+		return nil, fmt.Errorf("ParseFeed: unexpected GeoJSON type %q", f.Type)
+	}
+
+	return &f, nil
+}
+
+// defineEventQuality converts a passed review status to the corresponding EventQuality value.
+func defineEventQuality(s string) provider.EventQuality {
+	switch strings.ToLower(s) {
+	case "reviewed":
+		return provider.Excellent
+	case "automatic":
+		return provider.Preliminary
+	default:
+		return provider.UnknownQuality
+	}
+}
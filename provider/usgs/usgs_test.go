@@ -0,0 +1,99 @@
+package usgs
+
+import (
+	"os"
+	"seismo/provider"
+	"testing"
+	"time"
+)
+
+func Test_ParseFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantType string
+		wantLen  int
+	}{
+		{"testdata/all_hour.geojson", "FeatureCollection", 3},
+		{"testdata/all_hour_update.geojson", "FeatureCollection", 4},
+		{"testdata/us7000jk3l.geojson", "Feature", 1},
+	}
+
+	for _, test := range tests {
+		f, err := os.Open(test.name)
+		if err != nil {
+			t.Fatalf("Test_ParseFeed: %v", err)
+		}
+
+		feed, err := ParseFeed(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Test_ParseFeed: name: %s error: %v", test.name, err)
+		}
+
+		if feed.Type != test.wantType || len(feed.Events()) != test.wantLen {
+			t.Errorf("Test_ParseFeed: name: %s want: %s(%d) res: %s(%d)",
+				test.name, test.wantType, test.wantLen, feed.Type, len(feed.Events()))
+		}
+	}
+}
+
+func Test_Message(t *testing.T) {
+	f, err := os.Open("testdata/us7000jk3l.geojson")
+	if err != nil {
+		t.Fatalf("Test_Message: %v", err)
+	}
+	defer f.Close()
+
+	feed, err := ParseFeed(f)
+	if err != nil {
+		t.Fatalf("Test_Message: %v", err)
+	}
+
+	feedLink := "https://earthquake.usgs.gov/earthquakes/feed/v1.0/detail/us7000jk3l.geojson"
+	res, err := feed.Events()[0].Message(feedLink)
+	if err != nil {
+		t.Fatalf("Test_Message: %v", err)
+	}
+
+	want := provider.Message{
+		EventId:   "us7000jk3l",
+		FocusTime: time.Date(2023, 3, 1, 7, 21, 19, 458000000, time.UTC),
+		Latitude:  2.4506,
+		Longitude: 127.3547,
+		Magnitude: 4.7,
+		Type:      provider.EarthQuake,
+		Quality:   provider.Excellent,
+		Link:      feedLink,
+		Depth:     provider.Float(35),
+		MagType:   "mb",
+		Agency:    "us",
+		Extra:     map[string]string{"region": "test place"},
+
+		UpdateTime: time.Date(2023, 3, 1, 7, 40, 10, 40000000, time.UTC),
+	}
+
+	if !res.Equal(want) {
+		t.Errorf("Test_Message: \n\twant: %v\n\tres: %v", want, *res)
+	}
+}
+
+func Test_Message_NoCoordinates(t *testing.T) {
+	f := Feature{Id: "us7000jk3l"}
+	if _, err := f.Message(""); err == nil {
+		t.Errorf("Test_Message_NoCoordinates: an error is expected for an event without coordinates")
+	}
+}
+
+func Test_Message_Deleted(t *testing.T) {
+	f := Feature{Id: "us7000jk3l", Geometry: Geometry{Coordinates: []float64{127.3547, 2.4506}}}
+	f.Properties.Status = "deleted"
+
+	res, err := f.Message("")
+	if err != nil {
+		t.Fatalf("Test_Message_Deleted: %v", err)
+	}
+
+	if res.Action != provider.Retract {
+		t.Errorf("Test_Message_Deleted: want action: %q, res: %q", provider.Retract, res.Action)
+	}
+}
@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	//plausible bounds of values
+
+	MinMagnitude float64 = -3
+	MaxMagnitude float64 = 10
+	MinDepth     float64 = -10
+	MaxDepth     float64 = 800
+
+	// MaxFutureSkew specifies how far the FocusTime of a message can be
+	// in the future, that covers clock differences of sources.
+	MaxFutureSkew = 5 * time.Minute
+)
+
+// FieldError describes a violation of a validation rule by a message field.
+type FieldError struct {
+	// Field specifies the name of the field as it is named in JSON, e.g. "latitude".
+	Field string
+
+	// Reason specifies the violated rule.
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationErr indicates that a message violates validation rules.
+// It lists every violated field.
+type ValidationErr struct {
+	SourceId string
+	EventId  string
+	Fields   []FieldError
+}
+
+func (e ValidationErr) Error() string {
+	reasons := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		reasons = append(reasons, f.Error())
+	}
+
+	return fmt.Sprintf("invalid message: source %q event %q: %s", e.SourceId, e.EventId, strings.Join(reasons, "; "))
+}
+
+// FieldNames returns the names of violated fields.
+func (e ValidationErr) FieldNames() []string {
+	names := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		names = append(names, f.Field)
+	}
+
+	return names
+}
+
+// Rule checks a message and returns the violations found, or nil
+// if the message satisfies the rule.
+type Rule func(m Message) []FieldError
+
+// RuleSet is a set of rules, a message is valid if it satisfies all of them.
+type RuleSet []Rule
+
+// Validate checks the message "m" with all rules of the set and returns
+// a ValidationErr listing every violated field, or nil if the message is valid.
+func (rs RuleSet) Validate(m Message) error {
+	var fields []FieldError
+	for _, r := range rs {
+		fields = append(fields, r(m)...)
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return ValidationErr{SourceId: m.SourceId, EventId: m.EventId, Fields: fields}
+}
+
+// Validate checks the message with the common rules (DefRules).
+// The returned error is a ValidationErr or nil.
+func (m Message) Validate() error {
+	return DefRules.Validate(m)
+}
+
+// DefRules contains the rules every message must satisfy: non-empty
+// SourceId and EventId, coordinates in their ranges, plausible magnitude
+// and depth, the FocusTime not in the future and non-negative
+// optional quality parameters.
+var DefRules = RuleSet{
+	requiredIds,
+	coordinates,
+	magnitude,
+	focusTime,
+	depth,
+	quality,
+}
+
+// RulesFor returns DefRules followed by the source specific rules
+// of the provider type "t" registered with the type (see Registration.Rules).
+// If the type is not registered, it returns DefRules only.
+func RulesFor(t ProviderType) RuleSet {
+	r, _ := Lookup(t)
+	rs := make(RuleSet, 0, len(DefRules)+len(r.Rules))
+	rs = append(rs, DefRules...)
+	return append(rs, r.Rules...)
+}
+
+func requiredIds(m Message) []FieldError {
+	var fe []FieldError
+	if strings.TrimSpace(m.SourceId) == "" {
+		fe = append(fe, FieldError{"source_id", "is empty"})
+	}
+	if strings.TrimSpace(m.EventId) == "" {
+		fe = append(fe, FieldError{"event_id", "is empty"})
+	}
+	return fe
+}
+
+func coordinates(m Message) []FieldError {
+	var fe []FieldError
+	if !inRange(m.Latitude, -90, 90) {
+		fe = append(fe, FieldError{"latitude", fmt.Sprintf("%v is out of range [-90, 90]", m.Latitude)})
+	}
+	if !inRange(m.Longitude, -180, 180) {
+		fe = append(fe, FieldError{"longitude", fmt.Sprintf("%v is out of range [-180, 180]", m.Longitude)})
+	}
+	return fe
+}
+
+func magnitude(m Message) []FieldError {
+	if !inRange(m.Magnitude, MinMagnitude, MaxMagnitude) {
+		return []FieldError{{"magnitude", fmt.Sprintf("%v is out of range [%v, %v]", m.Magnitude, MinMagnitude, MaxMagnitude)}}
+	}
+	return nil
+}
+
+func focusTime(m Message) []FieldError {
+	if m.FocusTime.IsZero() {
+		return []FieldError{{"focus_time", "is zero"}}
+	}
+	if m.FocusTime.After(time.Now().Add(MaxFutureSkew)) {
+		return []FieldError{{"focus_time", fmt.Sprintf("%v is in the future", m.FocusTime)}}
+	}
+	return nil
+}
+
+func depth(m Message) []FieldError {
+	var fe []FieldError
+	if m.Depth != nil && !inRange(*m.Depth, MinDepth, MaxDepth) {
+		fe = append(fe, FieldError{"depth", fmt.Sprintf("%v is out of range [%v, %v]", *m.Depth, MinDepth, MaxDepth)})
+	}
+	if m.DepthUncertainty != nil && !inRange(*m.DepthUncertainty, 0, math.MaxFloat64) {
+		fe = append(fe, FieldError{"depth_uncertainty", fmt.Sprintf("%v is negative", *m.DepthUncertainty)})
+	}
+	return fe
+}
+
+func quality(m Message) []FieldError {
+	var fe []FieldError
+	for _, u := range []struct {
+		field string
+		v     *float64
+	}{
+		{"time_uncertainty", m.TimeUncertainty},
+		{"latitude_uncertainty", m.LatitudeUncertainty},
+		{"longitude_uncertainty", m.LongitudeUncertainty},
+	} {
+		if u.v != nil && !inRange(*u.v, 0, math.MaxFloat64) {
+			fe = append(fe, FieldError{u.field, fmt.Sprintf("%v is negative", *u.v)})
+		}
+	}
+
+	if m.StationCount != nil && *m.StationCount < 0 {
+		fe = append(fe, FieldError{"station_count", fmt.Sprintf("%d is negative", *m.StationCount)})
+	}
+	if m.PhaseCount != nil && *m.PhaseCount < 0 {
+		fe = append(fe, FieldError{"phase_count", fmt.Sprintf("%d is negative", *m.PhaseCount)})
+	}
+	if m.AzimuthalGap != nil && !inRange(*m.AzimuthalGap, 0, 360) {
+		fe = append(fe, FieldError{"azimuthal_gap", fmt.Sprintf("%v is out of range [0, 360]", *m.AzimuthalGap)})
+	}
+	return fe
+}
+
+// RequiredLink is a source specific rule requiring a non-empty Link,
+// e.g. for sources publishing a page of every event.
+func RequiredLink(m Message) []FieldError {
+	if strings.TrimSpace(m.Link) == "" {
+		return []FieldError{{"link", "is empty"}}
+	}
+	return nil
+}
+
+// inRange reports whether v is in [min, max]. It is false for NaN.
+func inRange(v, min, max float64) bool {
+	return v >= min && v <= max
+}
@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func validMessage() Message {
+	return Message{
+		SourceId:  "seishub",
+		FocusTime: time.Date(2022, 2, 1, 5, 55, 14, 445000000, time.UTC),
+		Latitude:  54.38,
+		Longitude: 86.13,
+		Magnitude: 2.4,
+		EventId:   "asb2022cfjhkl",
+		Link:      "http://seishub.ru/pipermail/seismic-report/2022-February/017538.html",
+		Depth:     Float(10),
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Message)
+		want   []string
+	}{
+		{"valid", func(m *Message) {}, nil},
+		{"empty ids", func(m *Message) { m.SourceId, m.EventId = "", " " }, []string{"source_id", "event_id"}},
+		{"coordinates", func(m *Message) { m.Latitude, m.Longitude = 90.5, -180.1 }, []string{"latitude", "longitude"}},
+		{"nan", func(m *Message) { m.Latitude = math.NaN() }, []string{"latitude"}},
+		{"magnitude", func(m *Message) { m.Magnitude = 12 }, []string{"magnitude"}},
+		{"zero time", func(m *Message) { m.FocusTime = time.Time{} }, []string{"focus_time"}},
+		{"future", func(m *Message) { m.FocusTime = time.Now().Add(time.Hour) }, []string{"focus_time"}},
+		{"near future", func(m *Message) { m.FocusTime = time.Now().Add(time.Minute) }, nil},
+		{"depth", func(m *Message) { m.Depth = Float(1000) }, []string{"depth"}},
+		{"quality", func(m *Message) {
+			m.StationCount, m.AzimuthalGap, m.TimeUncertainty = Int(-1), Float(361), Float(-0.1)
+		}, []string{"time_uncertainty", "station_count", "azimuthal_gap"}},
+		{"several", func(m *Message) { m.EventId, m.Magnitude, m.Longitude = "", -4, 200 },
+			[]string{"event_id", "longitude", "magnitude"}},
+	}
+
+	for _, test := range tests {
+		m := validMessage()
+		test.change(&m)
+
+		err := m.Validate()
+		if test.want == nil {
+			if err != nil {
+				t.Errorf("Test_Validate: %s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+
+		var ve ValidationErr
+		if !errors.As(err, &ve) {
+			t.Errorf("Test_Validate: %s: ValidationErr is expected, result: %v", test.name, err)
+			continue
+		}
+
+		if !cmp.Equal(ve.FieldNames(), test.want) {
+			t.Errorf("Test_Validate: %s: want fields: %v, result: %v (%v)", test.name, test.want, ve.FieldNames(), err)
+		}
+	}
+}
+
+func Test_RulesFor(t *testing.T) {
+	const testType ProviderType = "rules_test"
+
+	Register(Registration{
+		T:       testType,
+		Factory: func(conf WatcherConfig) (Watcher, error) { return &testWatcher{conf: conf}, nil },
+		Rules:   RuleSet{RequiredLink},
+	})
+
+	m := validMessage()
+	m.Link = ""
+
+	var ve ValidationErr
+	if err := RulesFor(testType).Validate(m); !errors.As(err, &ve) || !cmp.Equal(ve.FieldNames(), []string{"link"}) {
+		t.Errorf("Test_RulesFor: registered type: unexpected result: %v", err)
+	}
+
+	if err := RulesFor("no_such_type").Validate(m); err != nil {
+		t.Errorf("Test_RulesFor: unregistered type: unexpected error: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -13,8 +14,29 @@ const (
 
 	Stopped WatcherStateInfo = "Stopped"
 	Run     WatcherStateInfo = "Run"
+
+	// Starting means that a watcher has been started,
+	// but it is still preparing to get messages.
+	Starting WatcherStateInfo = "Starting"
+
+	// CatchingUp means that a watcher gets messages appeared before
+	// the current moment (e.g. after starting from a past time).
+	CatchingUp WatcherStateInfo = "CatchingUp"
+
+	// Backoff means that a watcher waits before retrying a failed request.
+	Backoff WatcherStateInfo = "Backoff"
+
+	// Failed means that a watcher has stopped because of an error
+	// (see WatcherStats.LastErr). A failed watcher can be started again.
+	Failed WatcherStateInfo = "Failed"
 )
 
+// Active reports whether the state is a state of a running watcher,
+// i.e. the watcher cannot be started.
+func (s WatcherStateInfo) Active() bool {
+	return s != Stopped && s != Failed
+}
+
 // Watcher represents a type which can start watching seismic activity (StartWatch method),
 // i.e. waiting and getting seismic event messages, report its state information (StateInfo method),
 // and return its configuration (GetConfig methods).
@@ -38,6 +60,54 @@ type Watcher interface {
 
 	//GetConfig returns the current configuration of the watcher.
 	GetConfig() WatcherConfig
+
+	// Stats returns health information and statistics of the watcher.
+	Stats() WatcherStats
+}
+
+// Backfiller represents a watcher which can get messages of past events
+// for a time range (e.g. to fill a gap in the database after an outage).
+//
+// The interface is optional, use a type assertion to check whether a watcher implements it.
+type Backfiller interface {
+	// Backfill gets messages of events, the FocusTime of which is in the [from, to) range,
+	// and sends them into the returned channel. The channel is closed when all messages
+	// have been sent or backfilling is cancelled through the context. Errors of getting
+	// separate messages are logged and do not stop backfilling.
+	//
+	// If the returned error is not nil (e.g. the range is empty), the returned channel is nil.
+	//
+	// Backfilling does not depend on the state of the watcher, i.e. it can be
+	// performed while the watcher is watching.
+	Backfill(ctx context.Context, from time.Time, to time.Time) (<-chan Message, error)
+}
+
+// CheckRange returns an error if the [from, to) time range is empty.
+func CheckRange(from time.Time, to time.Time) error {
+	if !from.Before(to) {
+		return fmt.Errorf("CheckRange: the range [%v, %v) is empty", from, to)
+	}
+
+	return nil
+}
+
+// InRange reports whether the time "t" is in the [from, to) range.
+func InRange(t time.Time, from time.Time, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+// SendAll sends "msgs" into the "o" channel in order until the context is canceled.
+// It reports whether all messages have been sent.
+func SendAll(ctx context.Context, o chan<- Message, msgs []Message) bool {
+	for _, m := range msgs {
+		select {
+		case o <- m:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	return true
 }
 
 // AlreadyRunErr indicates that a watcher is already running (watching)
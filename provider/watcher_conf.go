@@ -1,11 +1,18 @@
 package provider
 
+import "encoding/json"
+
 // ProviderType represents types (implementations) of message sources.
 type ProviderType string
 
 const (
 	Pseudo  ProviderType = "pseudo"
 	Seishub ProviderType = "seishub"
+	Fdsn    ProviderType = "fdsn"
+	Usgs    ProviderType = "usgs"
+	Emsc    ProviderType = "emsc"
+	Smtp    ProviderType = "smtp"
+	Replay  ProviderType = "replay"
 
 	//default values
 
@@ -33,6 +40,11 @@ type WatcherConfig struct {
 
 	// CheckPeriod specifies a period of checking the appearance of new messages.
 	CheckPeriod uint `json:"check_period"`
+
+	// Options specifies provider specific options, e.g. a minimal magnitude
+	// or a user agent. Every provider decodes the block into its own options struct
+	// (see DecodeOptions) and rejects unknown fields. Optional.
+	Options json.RawMessage `json:"options,omitempty"`
 }
 
 // DefaultWatcherConfig returns a watcher configuration with default values.